@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Crop detection samples cropSampleFrames frames at up to cropSamplePoints screenshot positions.
+// cropdetect treats a limit below 1 as a fraction of the range, so it works for any bit depth.
+const (
+	cropSamplePoints = 3
+	cropSampleFrames = 5
+	cropDetectFilter = "cropdetect=limit=0.094:round=2:reset=0"
+)
+
+// cropPattern matches the crop filter cropdetect suggests
+var cropPattern = regexp.MustCompile(`crop=(\d+):(\d+):(\d+):(\d+)`)
+
+// namedAspectRatios are display aspect ratios shown as a ratio of whole numbers; others are
+// shown as a decimal ratio to 1, e.g. 2.40:1
+var namedAspectRatios = []struct {
+	name  string
+	ratio float64
+}{
+	{"1:1", 1},
+	{"5:4", 5.0 / 4},
+	{"4:3", 4.0 / 3},
+	{"3:2", 3.0 / 2},
+	{"16:10", 16.0 / 10},
+	{"16:9", 16.0 / 9},
+}
+
+// parseAspectRatio parses ffprobe aspect ratios such as 64:45, returning 0 if they are unset
+func parseAspectRatio(ratio string) float64 {
+	return parseRational(strings.Replace(ratio, ":", "/", 1))
+}
+
+// aspectRatioLabel formats a display aspect ratio, e.g. 16:9 or 2.40:1
+func aspectRatioLabel(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	ratio := float64(width) / float64(height)
+	for _, named := range namedAspectRatios {
+		if math.Abs(ratio-named.ratio)/named.ratio < 0.01 {
+			return named.name
+		}
+	}
+	return fmt.Sprintf("%.2f:1", ratio)
+}
+
+// displaySize returns the size the stream is meant to be shown at. Anamorphic video has
+// non-square pixels and is stretched horizontally by its sample aspect ratio, e.g. a 720x576
+// PAL DVD with a SAR of 64:45 shows as 1024x576.
+func (s ffprobeStream) displaySize() (int, int) {
+	if sar := parseAspectRatio(s.SampleAspectRatio); sar > 0 && math.Abs(sar-1) > 0.001 && s.Width > 0 {
+		return evenRound(float64(s.Width) * sar), s.Height
+	}
+	if dar := parseAspectRatio(s.DisplayAspectRatio); dar > 0 && s.Height > 0 {
+		return evenRound(float64(s.Height) * dar), s.Height
+	}
+	return s.Width, s.Height
+}
+
+// evenRound rounds to the nearest even number, as most encoders need even dimensions
+func evenRound(value float64) int {
+	return int(math.Round(value/2)) * 2
+}
+
+// screenshotFilter returns the ffmpeg filter chain for the screenshots of the movie: the
+// detected crop, stretching anamorphic video to its display aspect ratio and, if asked for,
+// tonemapping. It is empty if the frame is used as is.
+func (s *SpoilerService) screenshotFilter(movie Movie, tonemap bool) string {
+	var filters []string
+	if s.settings.AutoCrop && movie.Media.Crop != "" {
+		filters = append(filters, movie.Media.Crop)
+	}
+	if sar := parseAspectRatio(movie.Params["%Video@sample_aspect_ratio%"]); sar > 0 && math.Abs(sar-1) > 0.001 {
+		filters = append(filters, fmt.Sprintf("scale=trunc(iw*%.6f/2)*2:ih,setsar=1", sar))
+	}
+	if algorithm := s.tonemapping(movie); tonemap && algorithm != "" {
+		filters = append(filters, tonemapFilter(movie, algorithm))
+	}
+	return strings.Join(filters, ",")
+}
+
+// detectCrop finds the letterbox and pillarbox bars of the movie with cropdetect at a few of the
+// screenshot positions and returns the crop filter that keeps the picture of all of them. It is
+// empty if the movie has no bars or detection fails.
+func (s *SpoilerService) detectCrop(movie Movie, timestamps []float64) string {
+	positions := timestamps
+	if len(positions) > cropSamplePoints {
+		// Spread the samples over the movie
+		positions = make([]float64, cropSamplePoints)
+		for i := range positions {
+			positions[i] = timestamps[i*(len(timestamps)-1)/(cropSamplePoints-1)]
+		}
+	}
+
+	left, top, right, bottom := math.MaxInt, math.MaxInt, 0, 0
+	for _, position := range positions {
+		w, h, x, y, err := s.sampleCrop(movie.FilePath, position)
+		if err != nil {
+			log.Printf("Crop detection failed for %s at %.2f: %v", movie.FileName, position, err)
+			return ""
+		}
+		left, top = min(left, x), min(top, y)
+		right, bottom = max(right, x+w), max(bottom, y+h)
+	}
+	if right <= left || bottom <= top {
+		return ""
+	}
+
+	width, _ := strconv.Atoi(movie.Width)
+	height, _ := strconv.Atoi(movie.Height)
+	if left == 0 && top == 0 && right >= width && bottom >= height {
+		return ""
+	}
+	return fmt.Sprintf("crop=%d:%d:%d:%d", right-left, bottom-top, left, top)
+}
+
+// sampleCrop runs cropdetect on a few frames from the position and returns its last suggestion
+func (s *SpoilerService) sampleCrop(videoPath string, position float64) (w, h, x, y int, err error) {
+	cmd := exec.CommandContext(s.cancelCtx, "ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-ss", fmt.Sprintf("%.2f", position),
+		"-i", videoPath,
+		"-an", "-sn",
+		"-frames:v", strconv.Itoa(cropSampleFrames),
+		"-vf", cropDetectFilter,
+		"-f", "null",
+		"-",
+	)
+	hideWindow(cmd)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("ffmpeg command failed: %v", err)
+	}
+
+	matches := cropPattern.FindAllStringSubmatch(stderr.String(), -1)
+	if len(matches) == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("cropdetect found no picture")
+	}
+	match := matches[len(matches)-1]
+	w, _ = strconv.Atoi(match[1])
+	h, _ = strconv.Atoi(match[2])
+	x, _ = strconv.Atoi(match[3])
+	y, _ = strconv.Atoi(match[4])
+	return w, h, x, y, nil
+}
@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// batchPlaceholders describes the aggregate placeholders computed over the completed movies
+var batchPlaceholders = []PlaceholderInfo{
+	{Name: "EPISODE_COUNT", Category: categoryBatch, Description: "Number of completed movies in the post"},
+	{Name: "TOTAL_SIZE", Category: categoryBatch, Description: "Combined file size of the completed movies"},
+	{Name: "TOTAL_DURATION", Category: categoryBatch, Description: "Combined duration of the completed movies"},
+	{Name: "COMMON_VIDEO_CODEC", Category: categoryBatch, Description: "Video codec shared by the completed movies, \"mixed\" if they differ"},
+	{Name: "COMMON_AUDIO_CODEC", Category: categoryBatch, Description: "Audio codec shared by the completed movies, \"mixed\" if they differ"},
+	{Name: "COMMON_RESOLUTION", Category: categoryBatch, Description: "Resolution shared by the completed movies, \"mixed\" if they differ"},
+}
+
+// batchContext holds the aggregate values of a post. Every section can read them,
+// e.g. {{.EpisodeCount}} or {{range .Movies}}{{.FileName}}{{end}}.
+type batchContext struct {
+	Movies               []Movie // Completed movies in queue order
+	EpisodeCount         int
+	TotalSizeBytes       int64
+	TotalSize            string
+	TotalDurationSeconds float64
+	TotalDuration        string
+	CommonVideoCodec     string // The value every movie has, mixedValue if they differ
+	CommonAudioCodec     string
+	CommonResolution     string
+}
+
+// newBatchContext aggregates the movies, which must all be completed
+func newBatchContext(movies []Movie) batchContext {
+	batch := batchContext{Movies: movies, EpisodeCount: len(movies)}
+
+	var videoCodecs, audioCodecs, resolutions []string
+	for _, movie := range movies {
+		batch.TotalSizeBytes += movie.FileSizeBytes
+		batch.TotalDurationSeconds += movie.Duration
+		videoCodecs = appendDistinct(videoCodecs, movie.VideoCodec)
+		audioCodecs = appendDistinct(audioCodecs, movie.AudioCodec)
+		resolution := ""
+		if movie.Width != "" && movie.Height != "" {
+			resolution = movie.Width + "x" + movie.Height
+		}
+		resolutions = appendDistinct(resolutions, resolution)
+	}
+
+	batch.TotalSize = FormatFileSize(batch.TotalSizeBytes)
+	batch.TotalDuration = FormatDuration(time.Duration(batch.TotalDurationSeconds * float64(time.Second)))
+	batch.CommonVideoCodec = commonValue(videoCodecs)
+	batch.CommonAudioCodec = commonValue(audioCodecs)
+	batch.CommonResolution = commonValue(resolutions)
+	return batch
+}
+
+// appendDistinct appends values that are not in the slice yet. An empty value is kept too,
+// so a movie without the value does not share it.
+func appendDistinct(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// mixedValue stands in for a common value when the movies do not share one
+const mixedValue = "mixed"
+
+// commonValue returns the only distinct value, mixedValue if there are several. A missing
+// value makes the result mixed unless every movie lacks it.
+func commonValue(values []string) string {
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0]
+	default:
+		return mixedValue
+	}
+}
+
+// placeholders returns the values of the aggregate %PLACEHOLDER% tokens by name
+func (b batchContext) placeholders() map[string]string {
+	return map[string]string{
+		"EPISODE_COUNT":      strconv.Itoa(b.EpisodeCount),
+		"TOTAL_SIZE":         b.TotalSize,
+		"TOTAL_DURATION":     b.TotalDuration,
+		"COMMON_VIDEO_CODEC": b.CommonVideoCodec,
+		"COMMON_AUDIO_CODEC": b.CommonAudioCodec,
+		"COMMON_RESOLUTION":  b.CommonResolution,
+	}
+}
+
+// completedMovies returns the movies that are part of the generated result
+func completedMovies(movies []Movie) []Movie {
+	var completed []Movie
+	for _, movie := range movies {
+		if movie.FileName != "" && movie.ProcessingState == StateCompleted {
+			completed = append(completed, movie)
+		}
+	}
+	return completed
+}
+
+// templateSections are the parsed sections of a preset. Header and footer are nil when empty.
+type templateSections struct {
+	preset TemplatePreset
+	header *template.Template
+	movie  *template.Template
+	footer *template.Template
+}
+
+// parseTemplateSections parses the header, per-movie template and footer of the preset.
+// Errors in the header or footer name the section.
+func parseTemplateSections(preset TemplatePreset) (templateSections, error) {
+	sections := templateSections{preset: preset}
+
+	var err error
+	if sections.movie, err = parseSpoilerTemplate(preset.Template); err != nil {
+		return sections, err
+	}
+	if sections.header, err = parseSectionTemplate("header", preset.Header); err != nil {
+		return sections, err
+	}
+	if sections.footer, err = parseSectionTemplate("footer", preset.Footer); err != nil {
+		return sections, err
+	}
+	return sections, nil
+}
+
+// parseSectionTemplate parses a header or footer; blank sections give a nil template
+func parseSectionTemplate(name, source string) (*template.Template, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := parseSpoilerTemplate(source)
+	if err != nil {
+		templateErr := err.(*TemplateError)
+		templateErr.Message = fmt.Sprintf("%s: %s", name, templateErr.Message)
+		return nil, templateErr
+	}
+	return tmpl, nil
+}
+
+// render writes the header, one block per movie and the footer, each followed by a newline
+func (t templateSections) render(movies []Movie) string {
+	batch := newBatchContext(movies)
+	var result strings.Builder
+
+	if t.header != nil {
+		result.WriteString(renderSection(t.header, t.preset.Header, newBatchTemplateContext(batch)))
+		result.WriteString("\n")
+	}
+	markup := newMarkupRenderer(t.preset.Markup)
+	for _, movie := range movies {
+		result.WriteString(renderSection(t.movie, t.preset.Template, newTemplateContext(movie, batch, markup)))
+		result.WriteString("\n")
+	}
+	if t.footer != nil {
+		result.WriteString(renderSection(t.footer, t.preset.Footer, newBatchTemplateContext(batch)))
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// renderSection executes a section, showing the error in its place if it fails
+func renderSection(tmpl *template.Template, source string, ctx templateContext) string {
+	result, err := executeSpoilerTemplate(tmpl, source, ctx)
+	if err != nil {
+		name := ctx.FileName
+		if name == "" {
+			name = "header or footer"
+		}
+		log.Printf("Failed to render template for %s: %v", name, err)
+		return templateErrorResult(err)
+	}
+	return result
+}
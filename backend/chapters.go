@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxScreenshotCount caps the screenshots of a movie, as the settings cap the screenshot count
+const maxScreenshotCount = 20
+
+// ffprobeChapter is a chapter as printed by ffprobe -show_chapters
+type ffprobeChapter struct {
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+func (c ffprobeChapter) chapter() Chapter {
+	start, _ := strconv.ParseFloat(c.StartTime, 64)
+	end, _ := strconv.ParseFloat(c.EndTime, 64)
+	return Chapter{Start: start, End: end, Title: c.Tags["title"]}
+}
+
+// Timestamp returns the chapter start, e.g. 1:02:15
+func (c Chapter) Timestamp() string {
+	return FormatDuration(time.Duration(c.Start * float64(time.Second)))
+}
+
+// chapterList formats one "1. 0:00 Opening" line per chapter; untitled chapters are numbered
+func chapterList(chapters []Chapter) string {
+	lines := make([]string, len(chapters))
+	for i, chapter := range chapters {
+		title := chapter.Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		lines[i] = fmt.Sprintf("%d. %s %s", i+1, chapter.Timestamp(), title)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// screenshotTimestamps returns the positions in seconds of the screenshots of a movie. In order
+// of precedence: the timestamps the movie overrides, evenly spaced points for an overridden
+// count, the middle of every chapter if screenshots per chapter are enabled and the movie has
+// chapters, and ScreenshotCount evenly spaced points. Points and chapters stay inside the
+// overridden range.
+func (s *SpoilerService) screenshotTimestamps(movie Movie) []float64 {
+	if timestamps, err := movie.Overrides.timestamps(); err == nil && len(timestamps) > 0 {
+		return timestamps
+	}
+
+	start, end := movie.Overrides.span(movie.Duration)
+	count := s.settings.ScreenshotCount
+	if movie.Overrides.ScreenshotCount > 0 {
+		count = movie.Overrides.ScreenshotCount
+	} else if s.settings.ScreenshotPerChapter {
+		if timestamps := chapterTimestamps(movie.Chapters, start, end); len(timestamps) > 0 {
+			return timestamps
+		}
+	}
+
+	timestamps := make([]float64, count)
+	interval := (end - start) / float64(count+1)
+	for i := range timestamps {
+		timestamps[i] = start + interval*float64(i+1)
+	}
+	return timestamps
+}
+
+// chapterTimestamps returns the middle of every chapter that has a length and lies in the range
+func chapterTimestamps(chapters []Chapter, start, end float64) []float64 {
+	var timestamps []float64
+	for _, chapter := range chapters {
+		middle := (chapter.Start + chapter.End) / 2
+		if chapter.End > chapter.Start && middle >= start && middle <= end && len(timestamps) < maxScreenshotCount {
+			timestamps = append(timestamps, middle)
+		}
+	}
+	return timestamps
+}
@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"spoilr/backend/img_uploaders"
+	"strings"
+	"sync"
+)
+
+// CLI exit codes
+const (
+	exitOK     = 0
+	exitFailed = 1 // At least one movie failed or completed with errors
+	exitUsage  = 2
+)
+
+// IsCLICommand reports whether the argument selects a command line mode instead of the window
+func IsCLICommand(arg string) bool {
+	return arg == "generate"
+}
+
+// RunCLI runs a command line command without starting the application window and returns
+// the process exit code. Progress goes to stderr, the result to stdout unless --out is given.
+func RunCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !IsCLICommand(args[0]) {
+		fmt.Fprintln(stderr, "Usage: spoilr generate <paths...> [--preset NAME] [--hosts fp,ham] [--out FILE]")
+		return exitUsage
+	}
+
+	return runGenerate(args[1:], stdout, stderr)
+}
+
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	preset := flags.String("preset", "", "template preset name or ID (default: current preset)")
+	hosts := flags.String("hosts", "", "comma-separated image host codes, e.g. fp,ham (default: every host in the template)")
+	out := flags.String("out", "", "write the result to this file instead of stdout")
+	verbose := flags.Bool("verbose", false, "print detailed logs")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: spoilr generate <paths...> [--preset NAME] [--hosts fp,ham] [--out FILE]")
+		flags.PrintDefaults()
+	}
+
+	paths, err := parseInterspersedFlags(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(paths) == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	sink := &cliEventSink{w: stderr, states: make(map[string]ProcessingState)}
+	s := newSpoilerService(WithEventSink(sink), WithDialogProvider(noDialogs{}))
+
+	if *preset != "" {
+		selected, err := s.findPreset(*preset)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		s.preset = &selected
+	}
+	if _, err := parseTemplateSections(s.currentPreset()); err != nil {
+		fmt.Fprintf(stderr, "Error: template %v\n", err)
+		return exitUsage
+	}
+
+	if *hosts != "" {
+		codes, err := parseHostCodes(*hosts)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		s.hostFilter = codes
+	}
+
+	if err := s.AddMovies(paths); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailed
+	}
+	defer s.ClearMovies() // Removes the media cache of this run
+
+	if len(s.GetState().Movies) == 0 {
+		fmt.Fprintln(stderr, "Error: no video files found")
+		return exitFailed
+	}
+
+	if err := s.beginProcessing(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitFailed
+	}
+
+	// Cancel processing on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(stderr, "Cancelling...")
+			s.CancelProcessing()
+		case <-done:
+		}
+	}()
+	s.runProcessing()
+	close(done)
+	stop()
+
+	result := s.GenerateResult()
+
+	if *out != "" {
+		if err := os.WriteFile(*out, []byte(result), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error: failed to write %s: %v\n", *out, err)
+			return exitFailed
+		}
+	} else {
+		fmt.Fprint(stdout, result)
+	}
+
+	return reportCLIResult(s.GetState().Movies, stderr)
+}
+
+// parseInterspersedFlags parses flags that may appear before, between or after the paths
+func parseInterspersedFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseHostCodes converts a comma-separated host list into registered host codes
+func parseHostCodes(list string) ([]string, error) {
+	var codes []string
+	for _, code := range strings.Split(list, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+		if _, ok := img_uploaders.Lookup(code); !ok {
+			var known []string
+			for _, host := range img_uploaders.Hosts() {
+				known = append(known, strings.ToLower(host.Code))
+			}
+			return nil, fmt.Errorf("unknown host %q (available: %s)", strings.ToLower(code), strings.Join(known, ", "))
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// findPreset returns the preset with the given name or ID
+func (s *SpoilerService) findPreset(nameOrID string) (TemplatePreset, error) {
+	for _, preset := range s.GetTemplatePresets() {
+		if preset.ID == nameOrID || strings.EqualFold(preset.Name, nameOrID) {
+			return preset, nil
+		}
+	}
+	return TemplatePreset{}, fmt.Errorf("template preset %q not found", nameOrID)
+}
+
+// reportCLIResult prints a summary with the errors of every movie and returns the exit code
+func reportCLIResult(movies []Movie, stderr io.Writer) int {
+	completed, failed := 0, 0
+	for _, movie := range movies {
+		if movie.ProcessingState == StateCompleted && len(movie.Errors) == 0 {
+			completed++
+			continue
+		}
+
+		failed++
+		if movie.ProcessingState == StateCompleted {
+			fmt.Fprintf(stderr, "%s: completed with errors\n", movie.FileName)
+		} else if movie.ProcessingError != "" {
+			fmt.Fprintf(stderr, "%s: %s\n", movie.FileName, movie.ProcessingError)
+		} else {
+			fmt.Fprintf(stderr, "%s: not processed\n", movie.FileName)
+		}
+		for _, err := range movie.Errors {
+			fmt.Fprintf(stderr, "  - %s\n", err)
+		}
+	}
+
+	fmt.Fprintf(stderr, "Done: %d completed, %d failed\n", completed, failed)
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// cliEventSink prints movie state changes and errors as they happen
+type cliEventSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	states map[string]ProcessingState
+}
+
+func (p *cliEventSink) State(state AppState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, movie := range state.Movies {
+		if p.states[movie.ID] == movie.ProcessingState {
+			continue
+		}
+		p.states[movie.ID] = movie.ProcessingState
+
+		line := fmt.Sprintf("[%d/%d] %s: %s", i+1, len(state.Movies), movie.FileName,
+			strings.ReplaceAll(string(movie.ProcessingState), "_", " "))
+		if movie.ProcessingError != "" {
+			line += " (" + movie.ProcessingError + ")"
+		}
+		fmt.Fprintln(p.w, line)
+	}
+}
+
+func (p *cliEventSink) Error(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "Error: %s\n", message)
+}
+
+// Progress is not printed; state changes are detailed enough for a terminal
+func (p *cliEventSink) Progress(Progress) {}
@@ -3,8 +3,12 @@ package backend
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"spoilr/backend/img_uploaders"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/knadh/koanf/parsers/yaml"
@@ -28,6 +32,68 @@ type SpoilerConfig struct {
 	HamsterPassword string `json:"hamsterPassword" koanf:"hamster_password"`
 	// Save media settings
 	SaveMediaDirectory string `json:"saveMediaDirectory" koanf:"save_media_directory"` // Empty = disabled
+	// Upload retry settings
+	UploadRetries          int `json:"uploadRetries" koanf:"upload_retries"`
+	UploadRetryBaseDelayMs int `json:"uploadRetryBaseDelayMs" koanf:"upload_retry_base_delay_ms"`
+	UploadRetryMaxDelayMs  int `json:"uploadRetryMaxDelayMs" koanf:"upload_retry_max_delay_ms"`
+	// Network settings
+	Proxy string `json:"proxy" koanf:"proxy"` // Used by every host without its own proxy
+	// Upload cache settings
+	VerifyCachedUploads bool `json:"verifyCachedUploads" koanf:"verify_cached_uploads"`
+	// Screenshot placement
+	ScreenshotPerChapter bool `json:"screenshotPerChapter" koanf:"screenshot_per_chapter"`
+	// Smart frame selection settings
+	SmartFrameSelection     bool `json:"smartFrameSelection" koanf:"smart_frame_selection"`
+	SmartFrameCandidates    int  `json:"smartFrameCandidates" koanf:"smart_frame_candidates"`
+	SmartFrameWindowSeconds int  `json:"smartFrameWindowSeconds" koanf:"smart_frame_window_seconds"`
+	// Screenshot output format settings
+	ScreenshotFormat string `json:"screenshotFormat" koanf:"screenshot_format"`
+	WebPQuality      int    `json:"webpQuality" koanf:"webp_quality"`
+	AVIFQuality      int    `json:"avifQuality" koanf:"avif_quality"`
+	// HDR settings
+	TonemapHDR       bool   `json:"tonemapHdr" koanf:"tonemap_hdr"`
+	TonemapAlgorithm string `json:"tonemapAlgorithm" koanf:"tonemap_algorithm"`
+	KeepUntonemapped bool   `json:"keepUntonemapped" koanf:"keep_untonemapped"`
+	// Cropping settings
+	AutoCrop bool `json:"autoCrop" koanf:"auto_crop"`
+	// Connection overrides keyed by host code, e.g. FP for a fastpic mirror
+	Hosts map[string]HostConfig `json:"hosts" koanf:"hosts"`
+}
+
+// directProxy as a host proxy bypasses the global proxy for that host
+const directProxy = "direct"
+
+// HostConfig overrides how an image host is reached. Empty fields keep the built-in values.
+type HostConfig struct {
+	BaseURL        string `json:"baseUrl" koanf:"base_url"`
+	UserAgent      string `json:"userAgent" koanf:"user_agent"`
+	TimeoutSeconds int    `json:"timeoutSeconds" koanf:"timeout_seconds"`
+	Proxy          string `json:"proxy" koanf:"proxy"` // "direct" bypasses the global proxy
+	// Largest image the host accepts; bigger ones are re-encoded to fit. 0 = no limit.
+	MaxSizeMB float64 `json:"maxSizeMb" koanf:"max_size_mb"`
+}
+
+// validate checks that the base URL is an absolute http(s) URL and the timeout and size limit
+// are not negative
+func (h HostConfig) validate() error {
+	if h.BaseURL != "" {
+		u, err := url.Parse(h.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("base URL %q must be an absolute http or https URL", h.BaseURL)
+		}
+	}
+	if h.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if h.MaxSizeMB < 0 {
+		return fmt.Errorf("max size must not be negative")
+	}
+	if h.Proxy != "" && h.Proxy != directProxy {
+		if err := img_uploaders.ValidateProxyURL(h.Proxy); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 var SpoilerAppConfig SpoilerConfig
@@ -85,6 +151,78 @@ var DefaultSpoilerConfig = SpoilerConfig{
 	HamsterEmail:             "",
 	HamsterPassword:          "",
 	SaveMediaDirectory:       "",
+	UploadRetries:            3,
+	UploadRetryBaseDelayMs:   1000,
+	UploadRetryMaxDelayMs:    15000,
+	SmartFrameCandidates:     5,
+	SmartFrameWindowSeconds:  10,
+	ScreenshotFormat:         formatJPG,
+	WebPQuality:              90,
+	AVIFQuality:              28,
+	TonemapHDR:               true,
+	TonemapAlgorithm:         tonemapHable,
+}
+
+// appSettingsFromConfig extracts the application settings from the config
+func appSettingsFromConfig(config SpoilerConfig) AppSettings {
+	return AppSettings{
+		ScreenshotCount:          config.ScreenshotCount,
+		FastpicSID:               config.FastpicSID,
+		ScreenshotQuality:        config.ScreenshotQuality,
+		MaxConcurrentScreenshots: config.MaxConcurrentScreenshots,
+		MaxConcurrentUploads:     config.MaxConcurrentUploads,
+		MtnArgs:                  config.MtnArgs,
+		ImageMiniatureSize:       config.ImageMiniatureSize,
+		HamsterEmail:             config.HamsterEmail,
+		HamsterPassword:          config.HamsterPassword,
+		SaveMediaDirectory:       config.SaveMediaDirectory,
+		UploadRetries:            config.UploadRetries,
+		UploadRetryBaseDelayMs:   config.UploadRetryBaseDelayMs,
+		UploadRetryMaxDelayMs:    config.UploadRetryMaxDelayMs,
+		Proxy:                    config.Proxy,
+		VerifyCachedUploads:      config.VerifyCachedUploads,
+		ScreenshotPerChapter:     config.ScreenshotPerChapter,
+		SmartFrameSelection:      config.SmartFrameSelection,
+		SmartFrameCandidates:     config.SmartFrameCandidates,
+		SmartFrameWindowSeconds:  config.SmartFrameWindowSeconds,
+		ScreenshotFormat:         config.ScreenshotFormat,
+		WebPQuality:              config.WebPQuality,
+		AVIFQuality:              config.AVIFQuality,
+		TonemapHDR:               config.TonemapHDR,
+		TonemapAlgorithm:         config.TonemapAlgorithm,
+		KeepUntonemapped:         config.KeepUntonemapped,
+		AutoCrop:                 config.AutoCrop,
+	}
+}
+
+// applySettingsToConfig copies the application settings into the config
+func applySettingsToConfig(config *SpoilerConfig, settings AppSettings) {
+	config.ScreenshotCount = settings.ScreenshotCount
+	config.FastpicSID = settings.FastpicSID
+	config.ScreenshotQuality = settings.ScreenshotQuality
+	config.MaxConcurrentScreenshots = settings.MaxConcurrentScreenshots
+	config.MaxConcurrentUploads = settings.MaxConcurrentUploads
+	config.MtnArgs = settings.MtnArgs
+	config.ImageMiniatureSize = settings.ImageMiniatureSize
+	config.HamsterEmail = settings.HamsterEmail
+	config.HamsterPassword = settings.HamsterPassword
+	config.SaveMediaDirectory = settings.SaveMediaDirectory
+	config.UploadRetries = settings.UploadRetries
+	config.UploadRetryBaseDelayMs = settings.UploadRetryBaseDelayMs
+	config.UploadRetryMaxDelayMs = settings.UploadRetryMaxDelayMs
+	config.Proxy = settings.Proxy
+	config.VerifyCachedUploads = settings.VerifyCachedUploads
+	config.ScreenshotPerChapter = settings.ScreenshotPerChapter
+	config.SmartFrameSelection = settings.SmartFrameSelection
+	config.SmartFrameCandidates = settings.SmartFrameCandidates
+	config.SmartFrameWindowSeconds = settings.SmartFrameWindowSeconds
+	config.ScreenshotFormat = settings.ScreenshotFormat
+	config.WebPQuality = settings.WebPQuality
+	config.AVIFQuality = settings.AVIFQuality
+	config.TonemapHDR = settings.TonemapHDR
+	config.TonemapAlgorithm = settings.TonemapAlgorithm
+	config.KeepUntonemapped = settings.KeepUntonemapped
+	config.AutoCrop = settings.AutoCrop
 }
 
 type ConfigService struct{}
@@ -96,14 +234,14 @@ func NewConfigService() *ConfigService {
 func (g *ConfigService) GetConfig() SpoilerConfig {
 	initSpoilerConfigPath()
 	if _, err := os.Stat(ConfigPath); os.IsNotExist(err) {
-		fmt.Println("Created a new spoiler settings config")
+		log.Println("Created a new spoiler settings config")
 		SpoilerAppConfig = DefaultSpoilerConfig
 		saveSpoilerAppConfig()
 	}
 
 	file, _ := os.ReadFile(ConfigPath)
 	if len(file) == 0 {
-		fmt.Println("config file is empty")
+		log.Println("config file is empty")
 		SpoilerAppConfig = DefaultSpoilerConfig
 	} else {
 		SpoilerAppConfig = loadSpoilerAppConfig()
@@ -117,10 +255,25 @@ func (g *ConfigService) GetConfig() SpoilerConfig {
 	if redacted.FastpicSID != "" {
 		redacted.FastpicSID = redacted.FastpicSID[:4] + "***"
 	}
+	redacted.Proxy = redactProxyURL(redacted.Proxy)
+	redacted.Hosts = make(map[string]HostConfig, len(SpoilerAppConfig.Hosts))
+	for code, host := range SpoilerAppConfig.Hosts {
+		host.Proxy = redactProxyURL(host.Proxy)
+		redacted.Hosts[code] = host
+	}
 	log.Println("Spoiler Config", redacted)
 	return SpoilerAppConfig
 }
 
+// redactProxyURL hides the password of a proxy URL for logging
+func redactProxyURL(proxyURL string) string {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	return u.Redacted()
+}
+
 func (g *ConfigService) UpdateConfig(config SpoilerConfig) error {
 	// Validate some values
 	if config.ScreenshotCount < 0 || config.ScreenshotCount > 20 {
@@ -138,6 +291,40 @@ func (g *ConfigService) UpdateConfig(config SpoilerConfig) error {
 	if config.ImageMiniatureSize < 100 || config.ImageMiniatureSize > 800 {
 		return fmt.Errorf("image miniature size must be between 100 and 800")
 	}
+	if config.UploadRetries < 0 || config.UploadRetries > 10 {
+		return fmt.Errorf("upload retries must be between 0 and 10")
+	}
+	if config.UploadRetryBaseDelayMs < 0 || config.UploadRetryMaxDelayMs < config.UploadRetryBaseDelayMs {
+		return fmt.Errorf("upload retry max delay must not be lower than the base delay")
+	}
+	if config.SmartFrameCandidates < 2 || config.SmartFrameCandidates > 20 {
+		return fmt.Errorf("smart frame candidates must be between 2 and 20")
+	}
+	if config.SmartFrameWindowSeconds < 1 || config.SmartFrameWindowSeconds > 60 {
+		return fmt.Errorf("smart frame window must be between 1 and 60 seconds")
+	}
+	if !slices.Contains(screenshotFormats, config.ScreenshotFormat) {
+		return fmt.Errorf("screenshot format must be one of %s", strings.Join(screenshotFormats, ", "))
+	}
+	if config.WebPQuality < 1 || config.WebPQuality > 100 {
+		return fmt.Errorf("WebP quality must be between 1 and 100")
+	}
+	if config.AVIFQuality < 0 || config.AVIFQuality > 63 {
+		return fmt.Errorf("AVIF quality must be between 0 and 63")
+	}
+	if !slices.Contains(tonemapAlgorithms, config.TonemapAlgorithm) {
+		return fmt.Errorf("tonemap algorithm must be one of %s", strings.Join(tonemapAlgorithms, ", "))
+	}
+	if config.Proxy != "" {
+		if err := img_uploaders.ValidateProxyURL(config.Proxy); err != nil {
+			return err
+		}
+	}
+	for code, host := range config.Hosts {
+		if err := host.validate(); err != nil {
+			return fmt.Errorf("host %s: %v", code, err)
+		}
+	}
 
 	// Ensure we always have at least one preset
 	if len(config.TemplatePresets) == 0 {
@@ -223,22 +410,27 @@ func (g *ConfigService) SetCurrentPreset(presetID string) error {
 }
 
 func (g *ConfigService) GetCurrentTemplate() string {
+	return g.GetCurrentPreset().Template
+}
+
+// GetCurrentPreset returns the selected preset with its header and footer
+func (g *ConfigService) GetCurrentPreset() TemplatePreset {
 	config := g.GetConfig()
 
 	// Find current preset
 	for _, preset := range config.TemplatePresets {
 		if preset.ID == config.CurrentPresetID {
-			return preset.Template
+			return preset
 		}
 	}
 
 	// Fallback to first preset if current preset not found
 	if len(config.TemplatePresets) > 0 {
-		return config.TemplatePresets[0].Template
+		return config.TemplatePresets[0]
 	}
 
 	// Ultimate fallback
-	return getDefaultTemplate()
+	return TemplatePreset{Template: getDefaultTemplate()}
 }
 
 func initSpoilerConfigPath() {
@@ -273,7 +465,7 @@ func saveSpoilerAppConfig() error {
 
 	err := k.Load(structs.Provider(SpoilerAppConfig, "koanf"), nil)
 	if err != nil {
-		fmt.Println(err)
+		log.Println(err)
 		return err
 	}
 
@@ -284,13 +476,13 @@ func saveSpoilerAppConfig() error {
 
 	b, err := k.Marshal(yaml.Parser())
 	if err != nil {
-		fmt.Println(err)
+		log.Println(err)
 		return err
 	}
 
 	err = os.WriteFile(ConfigPath, b, 0600)
 	if err != nil {
-		fmt.Println(err)
+		log.Println(err)
 		return err
 	}
 
@@ -300,6 +492,11 @@ func saveSpoilerAppConfig() error {
 func loadSpoilerAppConfig() SpoilerConfig {
 	var c SpoilerConfig
 	var k = koanf.New(".")
+	// Start from the defaults so settings added in newer versions get sane values
+	if err := k.Load(structs.Provider(DefaultSpoilerConfig, "koanf"), nil); err != nil {
+		log.Printf("error loading default spoiler app config: %v", err)
+		return DefaultSpoilerConfig
+	}
 	if err := k.Load(file.Provider(ConfigPath), yaml.Parser()); err != nil {
 		log.Printf("error parsing spoiler app config: %v", err)
 		return DefaultSpoilerConfig
@@ -329,6 +526,46 @@ func loadSpoilerAppConfig() SpoilerConfig {
 	if c.MtnArgs == "" {
 		c.MtnArgs = DefaultSpoilerConfig.MtnArgs
 	}
+	if c.UploadRetries < 0 || c.UploadRetries > 10 {
+		c.UploadRetries = DefaultSpoilerConfig.UploadRetries
+	}
+	if c.UploadRetryBaseDelayMs < 0 || c.UploadRetryMaxDelayMs < c.UploadRetryBaseDelayMs {
+		c.UploadRetryBaseDelayMs = DefaultSpoilerConfig.UploadRetryBaseDelayMs
+		c.UploadRetryMaxDelayMs = DefaultSpoilerConfig.UploadRetryMaxDelayMs
+	}
+	if c.SmartFrameCandidates < 2 || c.SmartFrameCandidates > 20 {
+		c.SmartFrameCandidates = DefaultSpoilerConfig.SmartFrameCandidates
+	}
+	if c.SmartFrameWindowSeconds < 1 || c.SmartFrameWindowSeconds > 60 {
+		c.SmartFrameWindowSeconds = DefaultSpoilerConfig.SmartFrameWindowSeconds
+	}
+	if !slices.Contains(screenshotFormats, c.ScreenshotFormat) {
+		c.ScreenshotFormat = DefaultSpoilerConfig.ScreenshotFormat
+	}
+	if c.WebPQuality < 1 || c.WebPQuality > 100 {
+		c.WebPQuality = DefaultSpoilerConfig.WebPQuality
+	}
+	if c.AVIFQuality < 0 || c.AVIFQuality > 63 {
+		c.AVIFQuality = DefaultSpoilerConfig.AVIFQuality
+	}
+	if !slices.Contains(tonemapAlgorithms, c.TonemapAlgorithm) {
+		c.TonemapAlgorithm = DefaultSpoilerConfig.TonemapAlgorithm
+	}
+	if c.Proxy != "" {
+		if err := img_uploaders.ValidateProxyURL(c.Proxy); err != nil {
+			log.Printf("Ignoring proxy setting: %v", err)
+			c.Proxy = ""
+		}
+	}
+	hosts := make(map[string]HostConfig, len(c.Hosts))
+	for code, host := range c.Hosts {
+		if err := host.validate(); err != nil {
+			log.Printf("Ignoring settings of host %s: %v", code, err)
+			continue
+		}
+		hosts[strings.ToUpper(code)] = host
+	}
+	c.Hosts = hosts
 
 	// Ensure we have presets and current preset ID
 	if len(c.TemplatePresets) == 0 {
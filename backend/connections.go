@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"spoilr/backend/img_uploaders"
+	"sync"
+	"time"
+)
+
+// connectionTestTimeout bounds the connection test of a single host
+const connectionTestTimeout = 15 * time.Second
+
+// TestConnections checks with the current proxy and host settings whether every image host
+// can be reached. The hosts are tested in parallel and reported in registration order.
+func (s *SpoilerService) TestConnections() []ConnectionStatus {
+	s.loadHostConfigs()
+	hosts := img_uploaders.Hosts()
+	statuses := make([]ConnectionStatus, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = s.testConnection(host)
+		}()
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+func (s *SpoilerService) testConnection(host img_uploaders.Host) ConnectionStatus {
+	status := ConnectionStatus{Code: host.Code, Name: host.Name}
+
+	uploader, err := host.New(s.uploaderOptions(host.Code))
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err = uploader.TestConnection(ctx)
+	status.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		log.Printf("%s connection test failed: %v", host.Name, err)
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Reachable = true
+	return status
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package backend
+
+// AttachConsole is a no-op outside Windows, where the process always has its terminal
+func AttachConsole() {}
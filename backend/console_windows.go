@@ -0,0 +1,32 @@
+//go:build windows
+
+package backend
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+const attachParentProcess = ^uintptr(0) // ATTACH_PARENT_PROCESS
+
+var attachConsoleProc = windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+
+// AttachConsole connects a GUI-subsystem build to the console it was started from, so the
+// CLI can print to it. Redirected stdout and stderr are left untouched.
+func AttachConsole() {
+	if result, _, _ := attachConsoleProc.Call(attachParentProcess); result == 0 {
+		return
+	}
+
+	console, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		os.Stdout = console
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		os.Stderr = console
+	}
+}
@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+// EventSink receives the events SpoilerService reports while it works
+type EventSink interface {
+	State(state AppState)       // Full state after every change
+	Error(message string)       // Problems the user should see, e.g. a failed login
+	Progress(progress Progress) // Step-by-step progress of a single movie
+}
+
+// DialogProvider shows native dialogs
+type DialogProvider interface {
+	// SelectDirectory asks the user for a directory and returns "" if they cancelled
+	SelectDirectory(title string) (string, error)
+}
+
+// Progress reports how many steps of the current processing stage a movie has finished
+type Progress struct {
+	MovieID string          `json:"movieId"`
+	State   ProcessingState `json:"state"`
+	Done    int             `json:"done"`
+	Total   int             `json:"total"`
+}
+
+// Option configures a SpoilerService
+type Option func(*SpoilerService)
+
+// WithEventSink sends service events to the sink
+func WithEventSink(sink EventSink) Option {
+	return func(s *SpoilerService) {
+		s.events = sink
+	}
+}
+
+// WithDialogProvider shows dialogs through the provider
+func WithDialogProvider(dialogs DialogProvider) Option {
+	return func(s *SpoilerService) {
+		s.dialogs = dialogs
+	}
+}
+
+// WithTemplate uses the template instead of the current preset template, without header and footer
+func WithTemplate(template string) Option {
+	return func(s *SpoilerService) {
+		s.preset = &TemplatePreset{Template: template}
+	}
+}
+
+// WithPreset uses the preset sections instead of the current preset
+func WithPreset(preset TemplatePreset) Option {
+	return func(s *SpoilerService) {
+		s.preset = &preset
+	}
+}
+
+// WithHostBaseURLs points the uploaders of the given host codes at other site roots
+func WithHostBaseURLs(baseURLs map[string]string) Option {
+	return func(s *SpoilerService) {
+		s.hostBaseURLs = baseURLs
+	}
+}
+
+// discardEvents drops all events; used when no sink is configured
+type discardEvents struct{}
+
+func (discardEvents) State(AppState)    {}
+func (discardEvents) Error(string)      {}
+func (discardEvents) Progress(Progress) {}
+
+var errNoDialogs = errors.New("dialogs are not available")
+
+// noDialogs fails every dialog; used when no provider is configured and by the CLI
+type noDialogs struct{}
+
+func (noDialogs) SelectDirectory(string) (string, error) {
+	return "", errNoDialogs
+}
+
+// MemoryEventSink records events in memory, e.g. for tests
+type MemoryEventSink struct {
+	mu       sync.Mutex
+	states   []AppState
+	errors   []string
+	progress []Progress
+}
+
+func NewMemoryEventSink() *MemoryEventSink {
+	return &MemoryEventSink{}
+}
+
+func (m *MemoryEventSink) State(state AppState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states = append(m.states, state)
+}
+
+func (m *MemoryEventSink) Error(message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, message)
+}
+
+func (m *MemoryEventSink) Progress(progress Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress = append(m.progress, progress)
+}
+
+// States returns all recorded states in emission order
+func (m *MemoryEventSink) States() []AppState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.states)
+}
+
+// Errors returns all recorded error messages
+func (m *MemoryEventSink) Errors() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.errors)
+}
+
+// ProgressEvents returns all recorded progress events
+func (m *MemoryEventSink) ProgressEvents() []Progress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.progress)
+}
+
+// MemoryDialogProvider answers dialogs with preset values, e.g. for tests
+type MemoryDialogProvider struct {
+	Directory string
+	Err       error
+}
+
+func (m MemoryDialogProvider) SelectDirectory(string) (string, error) {
+	return m.Directory, m.Err
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// Weights of the frame score. Black and transition frames are penalised harder than
+// low contrast or blur are rewarded, so they lose even against dull frames.
+const (
+	frameContrastWeight   = 1.0
+	frameSharpnessWeight  = 1.0
+	frameBlackWeight      = 2.0
+	frameTransitionWeight = 1.5
+)
+
+// Frame header and lavfi.key=value lines the metadata filter logs
+var (
+	frameHeaderPattern = regexp.MustCompile(`frame:\d+\s+pts:\S+\s+pts_time:(\S+)`)
+	frameValuePattern  = regexp.MustCompile(`(lavfi\.[\w.]+)=(\S+)`)
+)
+
+// frameCandidate is a frame near a screenshot position with the filter values it was scored on
+type frameCandidate struct {
+	offset float64           // Seconds from the start of the sampled window
+	values map[string]string // lavfi.* metadata by key
+}
+
+// score rates the frame from its filter values: high contrast and sharp frames score high,
+// mostly black frames and frames in the middle of a cut or fade score low
+func (c frameCandidate) score() float64 {
+	value := func(key string) float64 {
+		v, _ := strconv.ParseFloat(c.values[key], 64)
+		return v
+	}
+
+	// blackframe: share of pixels below the black threshold, in percent
+	black := value("lavfi.blackframe.pblack") / 100
+	// scdet: difference to the previous frame, 0-100
+	transition := value("lavfi.scd.score") / 100
+	// signalstats: spread between the 10th and 90th luma percentile
+	contrast := (value("lavfi.signalstats.YHIGH") - value("lavfi.signalstats.YLOW")) / 255
+	// blurdetect: estimated blur width, 0 for a perfectly sharp frame
+	sharpness := 1 / (1 + value("lavfi.blur"))
+
+	return frameContrastWeight*contrast + frameSharpnessWeight*sharpness -
+		frameBlackWeight*black - frameTransitionWeight*transition
+}
+
+// selectFrame samples SmartFrameCandidates frames in a window of SmartFrameWindowSeconds around
+// the target and returns the timestamp of the best one. The target is kept if sampling fails.
+func (s *SpoilerService) selectFrame(movie Movie, target float64) float64 {
+	count := s.settings.SmartFrameCandidates
+	window := float64(s.settings.SmartFrameWindowSeconds)
+	if count < 2 || window <= 0 || movie.Duration <= 0 {
+		return target
+	}
+
+	// Keep the window inside the file
+	window = min(window, movie.Duration)
+	start := min(max(target-window/2, 0), movie.Duration-window)
+
+	candidates, err := s.sampleFrames(movie.FilePath, start, window, count)
+	if err != nil {
+		log.Printf("Smart frame selection failed for %s at %.2f: %v", movie.FileName, target, err)
+		return target
+	}
+	if len(candidates) == 0 {
+		return target
+	}
+
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.score() > best.score() {
+			best = candidate
+		}
+	}
+	return start + best.offset
+}
+
+// sampleFrames decodes the window once, scoring every frame for transitions at full rate and
+// keeping count evenly spaced frames for the other measurements, which are run on a small copy
+func (s *SpoilerService) sampleFrames(videoPath string, start, window float64, count int) ([]frameCandidate, error) {
+	filters := fmt.Sprintf(
+		"scale=320:-2,format=yuv420p,scdet=threshold=100,fps=%d/%.3f,blackframe=amount=0,signalstats,blurdetect,metadata=mode=print",
+		count, window,
+	)
+	cmd := exec.CommandContext(s.cancelCtx, "ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-ss", fmt.Sprintf("%.2f", start),
+		"-t", fmt.Sprintf("%.2f", window),
+		"-i", videoPath,
+		"-an", "-sn",
+		"-vf", filters,
+		"-f", "null",
+		"-",
+	)
+	hideWindow(cmd)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg command failed: %v", err)
+	}
+	return parseFrameMetadata(stderr.Bytes()), nil
+}
+
+// parseFrameMetadata reads the frames logged by the metadata filter
+func parseFrameMetadata(output []byte) []frameCandidate {
+	var candidates []frameCandidate
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := frameHeaderPattern.FindStringSubmatch(line); match != nil {
+			offset, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, frameCandidate{offset: offset, values: make(map[string]string)})
+			continue
+		}
+		if match := frameValuePattern.FindStringSubmatch(line); match != nil && len(candidates) > 0 {
+			candidates[len(candidates)-1].values[match[1]] = match[2]
+		}
+	}
+	return candidates
+}
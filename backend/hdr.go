@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Transfer characteristics of HDR video as ffprobe names them
+const (
+	transferPQ  = "smpte2084"
+	transferHLG = "arib-std-b67"
+)
+
+// Side data types ffprobe prints for HDR metadata
+const (
+	sideDataDOVIConfig       = "DOVI configuration record"
+	sideDataMasteringDisplay = "Mastering display metadata"
+	sideDataContentLight     = "Content light level metadata"
+)
+
+// ffprobeSideData is a stream or frame side data entry. Only the fields used for HDR
+// detection are decoded; chromaticities and luminances are rationals such as "50/10000".
+type ffprobeSideData struct {
+	Type                      string `json:"side_data_type"`
+	DVProfile                 int    `json:"dv_profile"`
+	DVBLSignalCompatibilityID int    `json:"dv_bl_signal_compatibility_id"`
+	GreenX                    string `json:"green_x"`
+	GreenY                    string `json:"green_y"`
+	MinLuminance              string `json:"min_luminance"`
+	MaxLuminance              string `json:"max_luminance"`
+	MaxContent                int    `json:"max_content"`
+	MaxAverage                int    `json:"max_average"`
+}
+
+// pixFmtDepthPattern matches the bit depth suffix of pixel formats such as yuv420p10le
+var pixFmtDepthPattern = regexp.MustCompile(`p(\d+)(?:le|be)$`)
+
+// masteringPrimaries identifies mastering display primaries by their green chromaticity,
+// which differs the most between the common gamuts
+var masteringPrimaries = []struct {
+	name string
+	x, y float64
+}{
+	{"BT.2020", 0.170, 0.797},
+	{"Display P3", 0.265, 0.690},
+	{"BT.709", 0.300, 0.600},
+}
+
+// probeFrameSideData reads the side data of the first frame of a stream. HDR10+ dynamic
+// metadata, and mastering display data in MP4 files, are only carried in frames.
+func probeFrameSideData(filePath string, streamIndex int) []ffprobeSideData {
+	cmd := exec.Command("ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-select_streams", strconv.Itoa(streamIndex),
+		"-read_intervals", "%+#1",
+		"-show_frames",
+		"-show_entries", "frame=side_data_list",
+		filePath,
+	)
+	hideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var result struct {
+		Frames []struct {
+			SideDataList []ffprobeSideData `json:"side_data_list"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil
+	}
+
+	var sideData []ffprobeSideData
+	for _, frame := range result.Frames {
+		sideData = append(sideData, frame.SideDataList...)
+	}
+	return sideData
+}
+
+// mayBeHDR reports whether the stream signals HDR, so its frames are worth probing
+func (s ffprobeStream) mayBeHDR() bool {
+	return s.ColorTransfer == transferPQ || s.ColorTransfer == transferHLG || s.sideData(sideDataDOVIConfig) != nil
+}
+
+// sideData returns the first side data entry of the type, or nil
+func (s ffprobeStream) sideData(sideDataType string) *ffprobeSideData {
+	for i := range s.SideDataList {
+		if s.SideDataList[i].Type == sideDataType {
+			return &s.SideDataList[i]
+		}
+	}
+	return nil
+}
+
+// bitDepth returns the bits per sample, from the decoder if it reports them or else from the pixel format
+func (s ffprobeStream) bitDepth() int {
+	if depth, err := strconv.Atoi(s.BitsPerRawSample); err == nil && depth > 0 {
+		return depth
+	}
+	if match := pixFmtDepthPattern.FindStringSubmatch(s.PixFmt); match != nil {
+		depth, _ := strconv.Atoi(match[1])
+		return depth
+	}
+	if s.PixFmt != "" {
+		return 8
+	}
+	return 0
+}
+
+// hdrFormat labels the dynamic range of the stream, e.g. "Dolby Vision Profile 8.1, HDR10+",
+// "HDR10", "HLG" or "SDR". Dolby Vision is listed with the format it falls back to.
+func (s ffprobeStream) hdrFormat() string {
+	var formats []string
+
+	if dovi := s.sideData(sideDataDOVIConfig); dovi != nil {
+		format := "Dolby Vision"
+		if dovi.DVProfile > 0 {
+			format += fmt.Sprintf(" Profile %d", dovi.DVProfile)
+			if dovi.DVBLSignalCompatibilityID > 0 {
+				format += fmt.Sprintf(".%d", dovi.DVBLSignalCompatibilityID)
+			}
+		}
+		formats = append(formats, format)
+	}
+
+	switch s.ColorTransfer {
+	case transferPQ:
+		if s.hasHDR10Plus() {
+			formats = append(formats, "HDR10+")
+		} else {
+			formats = append(formats, "HDR10")
+		}
+	case transferHLG:
+		formats = append(formats, "HLG")
+	}
+
+	if len(formats) == 0 {
+		return "SDR"
+	}
+	return strings.Join(formats, ", ")
+}
+
+// hasHDR10Plus looks for SMPTE 2094-40 dynamic metadata
+func (s ffprobeStream) hasHDR10Plus() bool {
+	for _, sideData := range s.SideDataList {
+		if strings.Contains(sideData.Type, "SMPTE2094-40") {
+			return true
+		}
+	}
+	return false
+}
+
+// colorInfo returns the Video section values describing colour and HDR metadata
+func (s ffprobeStream) colorInfo() map[string]string {
+	info := map[string]string{
+		"pix_fmt":         s.PixFmt,
+		"color_range":     s.ColorRange,
+		"color_space":     s.ColorSpace,
+		"color_transfer":  s.ColorTransfer,
+		"color_primaries": s.ColorPrimaries,
+		"hdr_format":      s.hdrFormat(),
+	}
+	if depth := s.bitDepth(); depth > 0 {
+		info["bit_depth"] = strconv.Itoa(depth)
+	}
+	if mastering := s.sideData(sideDataMasteringDisplay); mastering != nil {
+		info["mastering_display"] = mastering.masteringDisplay()
+	}
+	if light := s.sideData(sideDataContentLight); light != nil {
+		if light.MaxContent > 0 {
+			info["max_cll"] = fmt.Sprintf("%d cd/m²", light.MaxContent)
+		}
+		if light.MaxAverage > 0 {
+			info["max_fall"] = fmt.Sprintf("%d cd/m²", light.MaxAverage)
+		}
+	}
+
+	for key, value := range info {
+		if value == "" || value == "unknown" {
+			delete(info, key)
+		}
+	}
+	return info
+}
+
+// masteringDisplay describes mastering display metadata, e.g. "Display P3, min 0.0050 cd/m², max 1000 cd/m²"
+func (d ffprobeSideData) masteringDisplay() string {
+	var parts []string
+
+	greenX, greenY := parseRational(d.GreenX), parseRational(d.GreenY)
+	for _, primaries := range masteringPrimaries {
+		if math.Abs(greenX-primaries.x) < 0.005 && math.Abs(greenY-primaries.y) < 0.005 {
+			parts = append(parts, primaries.name)
+			break
+		}
+	}
+	if d.MinLuminance != "" {
+		parts = append(parts, fmt.Sprintf("min %.4f cd/m²", parseRational(d.MinLuminance)))
+	}
+	if d.MaxLuminance != "" {
+		parts = append(parts, fmt.Sprintf("max %.0f cd/m²", parseRational(d.MaxLuminance)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Screenshot output formats, named by their file extension
+const (
+	formatJPG  = "jpg"
+	formatPNG  = "png"
+	formatWebP = "webp"
+	formatAVIF = "avif"
+)
+
+var screenshotFormats = []string{formatJPG, formatPNG, formatWebP, formatAVIF}
+
+// Re-encoding an image over a host size limit first lowers the quality fitQualitySteps times,
+// then shrinks it by fitScaleStep per attempt. Lossless PNG is only shrunk.
+const (
+	fitQualitySteps = 3
+	fitScaleStep    = 0.8
+	maxFitAttempts  = 10
+)
+
+// imageFormat returns the format of an image from its file extension
+func imageFormat(path string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format == "jpeg" {
+		return formatJPG
+	}
+	return format
+}
+
+// screenshotFormat returns the configured screenshot format, JPEG if it is unknown
+func (s *SpoilerService) screenshotFormat() string {
+	if slices.Contains(screenshotFormats, s.settings.ScreenshotFormat) {
+		return s.settings.ScreenshotFormat
+	}
+	return formatJPG
+}
+
+// encoderArgs returns the ffmpeg output options for a still image in the format. Every
+// qualityStep lowers the configured quality of lossy formats by a notch.
+func (s *SpoilerService) encoderArgs(format string, qualityStep int) []string {
+	switch format {
+	case formatPNG:
+		// 10-bit sources would otherwise be stored as 48-bit RGB, twice the size
+		return []string{"-c:v", "png", "-pix_fmt", "rgb24"}
+	case formatWebP:
+		quality := max(s.settings.WebPQuality-qualityStep*15, 10)
+		return []string{"-c:v", "libwebp", "-quality", fmt.Sprint(quality)}
+	case formatAVIF:
+		crf := min(s.settings.AVIFQuality+qualityStep*8, 63)
+		return []string{"-c:v", "libaom-av1", "-still-picture", "1", "-crf", fmt.Sprint(crf), "-pix_fmt", "yuv420p"}
+	default:
+		quality := min(s.settings.ScreenshotQuality+qualityStep*5, 31)
+		return []string{"-q:v", fmt.Sprint(quality)}
+	}
+}
+
+// maxUploadSize returns the largest image the host accepts in bytes, 0 if it has no limit
+func (s *SpoilerService) maxUploadSize(code string) int64 {
+	return int64(s.hostConfig(code).MaxSizeMB * 1024 * 1024)
+}
+
+// fitImage returns the image if it is at most maxBytes, otherwise a copy re-encoded at a lower
+// quality and then a lower resolution until it fits. The copy is written next to the image
+// under the host code, and the caller removes it.
+func (s *SpoilerService) fitImage(path, hostCode string, maxBytes int64) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if maxBytes <= 0 || info.Size() <= maxBytes {
+		return path, nil
+	}
+
+	format := imageFormat(path)
+	ext := filepath.Ext(path)
+	fittedPath := fmt.Sprintf("%s_%s%s", strings.TrimSuffix(path, ext), strings.ToLower(hostCode), ext)
+
+	qualitySteps := fitQualitySteps
+	if format == formatPNG {
+		qualitySteps = 0
+	}
+	for attempt := 1; attempt <= maxFitAttempts; attempt++ {
+		qualityStep := min(attempt, qualitySteps)
+		scale := math.Pow(fitScaleStep, float64(attempt-qualityStep))
+
+		if err := s.reencodeImage(path, fittedPath, format, qualityStep, scale); err != nil {
+			os.Remove(fittedPath)
+			return "", err
+		}
+		fitted, err := os.Stat(fittedPath)
+		if err != nil {
+			return "", err
+		}
+		if fitted.Size() <= maxBytes {
+			log.Printf("Re-encoded %s from %d to %d bytes for %s (quality step %d, scale %.2f)",
+				filepath.Base(path), info.Size(), fitted.Size(), hostCode, qualityStep, scale)
+			return fittedPath, nil
+		}
+	}
+
+	os.Remove(fittedPath)
+	return "", fmt.Errorf("%s is %s and does not fit in %s", filepath.Base(path), FormatFileSize(info.Size()), FormatFileSize(maxBytes))
+}
+
+// reencodeImage writes the image in the format at a lower quality step and scale
+func (s *SpoilerService) reencodeImage(inputPath, outputPath, format string, qualityStep int, scale float64) error {
+	args := []string{"-i", inputPath}
+	if scale < 1 {
+		args = append(args, "-vf", fmt.Sprintf("scale=trunc(iw*%.4f/2)*2:-2", scale))
+	}
+	args = append(args, s.encoderArgs(format, qualityStep)...)
+	args = append(args, "-y", outputPath)
+
+	cmd := exec.CommandContext(s.cancelCtx, "ffmpeg", args...)
+	hideWindow(cmd)
+	if err := cmd.Run(); err != nil {
+		if s.cancelCtx.Err() != nil {
+			return fmt.Errorf("re-encoding cancelled: %v", s.cancelCtx.Err())
+		}
+		return fmt.Errorf("ffmpeg command failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package img_uploaders
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// checkUserAgent is sent when checking image links of any host
+const checkUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"
+
+// CheckURL reports whether an uploaded image is still served. The request uses the proxy,
+// user agent and timeout of the host options.
+func CheckURL(ctx context.Context, opts Options, imageURL string) error {
+	config := newServiceConfig("", checkUserAgent, opts.serviceOptions())
+	client := config.newHTTPClient()
+
+	status, err := requestStatus(ctx, client, http.MethodHead, imageURL, config.userAgent)
+	// Some image servers don't answer HEAD requests
+	if err == nil && status == http.StatusMethodNotAllowed {
+		status, err = requestStatus(ctx, client, http.MethodGet, imageURL, config.userAgent)
+	}
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return newStatusError(status, "%s returned status code %d", imageURL, status)
+	}
+	return nil
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method, url, userAgent string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
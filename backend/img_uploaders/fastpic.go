@@ -13,39 +13,62 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	"golang.org/x/net/html"
 )
 
+const (
+	fastpicBaseURL   = "https://new.fastpic.org"
+	fastpicUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+)
+
 type FastpicService struct {
+	mu                 sync.Mutex // Protects sid and uploadID
 	sid                string
 	uploadID           string
 	imageMiniatureSize int
+	baseURL            string
+	userAgent          string
+	client             *http.Client
 }
 
-type FastpicUploadResult struct {
-	AlbumLink string `json:"albumLink"`
-	Direct    string `json:"direct"`
-	BBThumb   string `json:"bbThumb"`
-	BBBig     string `json:"bbBig"`
+func init() {
+	Register(Host{
+		Code: "FP",
+		Name: "Fastpic",
+		New: func(opts Options) (ImageUploader, error) {
+			return NewFastpicService(opts.SessionID, opts.ImageMiniatureSize, opts.serviceOptions()...), nil
+		},
+	})
 }
 
-func NewFastpicService(sid string, imageMiniatureSize int) *FastpicService {
+func NewFastpicService(sid string, imageMiniatureSize int, opts ...ServiceOption) *FastpicService {
+	config := newServiceConfig(fastpicBaseURL, fastpicUserAgent, opts)
 	return &FastpicService{
 		sid:                sid,
 		imageMiniatureSize: imageMiniatureSize,
+		baseURL:            config.baseURL,
+		userAgent:          config.userAgent,
+		client:             config.newHTTPClient(),
 	}
 }
 
-func (f *FastpicService) GetFastpicUploadID(ctx context.Context) error {
-	client := &http.Client{Timeout: 30 * time.Second}
+// Init obtains a fresh upload ID for this session
+func (f *FastpicService) Init(ctx context.Context) error {
+	return f.getUploadID(ctx)
+}
+
+func (f *FastpicService) getUploadID(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://new.fastpic.org/", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", f.baseURL+"/", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
+	req.Header.Set("User-Agent", f.userAgent)
 
 	// Add fp_sid if available
 	if f.sid != "" {
@@ -53,18 +76,18 @@ func (f *FastpicService) GetFastpicUploadID(ctx context.Context) error {
 		log.Printf("Using existing fastpic SID for authentication")
 	}
 
-	resp, err := client.Do(req)
+	resp, err := f.client.Do(req)
 	if err != nil {
 		// Check if error is due to context cancellation
 		if ctx.Err() != nil {
 			return fmt.Errorf("request cancelled: %v", ctx.Err())
 		}
-		return fmt.Errorf("request failed: %v", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("fastpic returned status code %d", resp.StatusCode)
+		return newStatusError(resp.StatusCode, "fastpic returned status code %d", resp.StatusCode)
 	}
 
 	// If no SID was set, try to parse it from Set-Cookie
@@ -115,10 +138,38 @@ func (f *FastpicService) GetFastpicUploadID(ctx context.Context) error {
 	return nil
 }
 
-// uploadToFastpic uploads image to fastpic
-func (f *FastpicService) UploadToFastpic(ctx context.Context, filePath, fileName string) (*FastpicUploadResult, error) {
+// TestConnection loads the upload page without changing the session
+func (f *FastpicService) TestConnection(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", f.baseURL+"/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return newStatusError(resp.StatusCode, "fastpic returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Upload uploads an image to fastpic
+func (f *FastpicService) Upload(ctx context.Context, filePath, fileName string) (*UploadResult, error) {
 	log.Printf("Starting upload of %s to fastpic...", fileName)
 
+	sid, uploadID := f.session()
+	if uploadID == "" {
+		if err := f.getUploadID(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get upload ID: %w", err)
+		}
+		sid, uploadID = f.session()
+	}
+
 	if _, err := os.Stat(filePath); err != nil {
 		return nil, fmt.Errorf("failed to stat file: %v", err)
 	}
@@ -135,7 +186,7 @@ func (f *FastpicService) UploadToFastpic(ctx context.Context, filePath, fileName
 	fields := map[string]string{
 		"uploading":                 "1",
 		"fp":                        "not-loaded",
-		"upload_id":                 f.uploadID,
+		"upload_id":                 uploadID,
 		"check_thumb":               "size",
 		"thumb_text":                "",
 		"thumb_size":                strconv.Itoa(f.imageMiniatureSize),
@@ -154,7 +205,7 @@ func (f *FastpicService) UploadToFastpic(ctx context.Context, filePath, fileName
 		}
 	}
 
-	part, err := writer.CreateFormFile("file1", fileName)
+	part, err := createImagePart(writer, "file1", fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %v", err)
 	}
@@ -165,32 +216,35 @@ func (f *FastpicService) UploadToFastpic(ctx context.Context, filePath, fileName
 
 	writer.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://new.fastpic.org/v2upload/", &buffer)
+	req, err := http.NewRequestWithContext(ctx, "POST", f.baseURL+"/v2upload/", &buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("User-Agent", f.userAgent)
 
-	if f.sid != "" {
-		req.AddCookie(&http.Cookie{Name: "fp_sid", Value: f.sid})
+	if sid != "" {
+		req.AddCookie(&http.Cookie{Name: "fp_sid", Value: sid})
 		req.AddCookie(&http.Cookie{Name: "pp", Value: "1"})
 	}
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := f.client.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
 			return nil, fmt.Errorf("upload cancelled: %v", ctx.Err())
 		}
-		return nil, fmt.Errorf("upload request failed: %v", err)
+		return nil, fmt.Errorf("upload request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, newStatusError(resp.StatusCode, "upload failed: status %d - %s", resp.StatusCode, string(body))
 	}
 
 	log.Printf("Fastpic response: %s", string(body))
@@ -205,32 +259,62 @@ func (f *FastpicService) UploadToFastpic(ctx context.Context, filePath, fileName
 		return nil, fmt.Errorf("failed to parse JSON: %v", err)
 	}
 
-	result := &FastpicUploadResult{
-		AlbumLink: "https://new.fastpic.org" + respJSON.AlbumLink,
-		Direct:    extractDirectLink(respJSON.Codes),
+	// Fastpic answers with an empty result when the upload ID is no longer valid
+	if respJSON.Codes == "" {
+		return nil, newUploadError(ErrorSessionExpired, "fastpic returned no image codes, upload ID may have expired")
+	}
+
+	result := &UploadResult{
+		AlbumLink: f.absoluteURL(respJSON.AlbumLink),
+		DirectURL: extractDirectLink(respJSON.Codes),
+		ThumbURL:  f.absoluteURL(respJSON.ThumbLink),
+		ViewerURL: f.absoluteURL(respJSON.ViewLink),
 	}
 
-	// Extract BBCode values
-	result.BBThumb, result.BBBig = extractBBCodes(respJSON.Codes)
+	// The thumbnail BBCode has the thumbnail image and the public viewer page
+	if viewer, thumb := extractThumbLinks(respJSON.Codes); thumb != "" {
+		result.ViewerURL, result.ThumbURL = viewer, thumb
+	}
 
-	log.Printf("Upload completed. Direct: %s, BBThumb: %s, BBBig: %s",
-		result.Direct, result.BBThumb, result.BBBig)
+	log.Printf("Upload completed. Direct: %s, Thumb: %s, Viewer: %s",
+		result.DirectURL, result.ThumbURL, result.ViewerURL)
 	return result, nil
 }
 
+// session returns the current session cookie and upload ID
+func (f *FastpicService) session() (string, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sid, f.uploadID
+}
+
+// absoluteURL prefixes site-relative links returned by fastpic with the host
+func (f *FastpicService) absoluteURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return f.baseURL + link
+	}
+	return link
+}
+
+// directLinkPattern captures the plain image URL input of the codes HTML. Fastpic keeps the
+// extension of the uploaded file, so any image format can show up.
+var directLinkPattern = regexp.MustCompile(`<input[^>]*value="(https://[^"]*\.(?:jpe?g|png|webp|avif|gif))"[^>]*>`)
+
 // extractDirectLink extracts the direct image URL from the codes HTML
 func extractDirectLink(codesHTML string) string {
-	// Use regex to find the direct link input value
-	re := regexp.MustCompile(`<input[^>]*value="(https://[^"]*\.jpg)"[^>]*>`)
-	matches := re.FindStringSubmatch(codesHTML)
+	matches := directLinkPattern.FindStringSubmatch(codesHTML)
 	if len(matches) > 1 {
 		return matches[1]
 	}
 	return ""
 }
 
-// extractBBCodes extracts both BBCode formats from the codes HTML
-func extractBBCodes(codesHTML string) (bbThumb, bbBig string) {
+// bbCodeLinkPattern captures the link and image of a [URL=...][IMG]...[/IMG][/URL] code
+var bbCodeLinkPattern = regexp.MustCompile(`^\[URL=([^\]]+)\]\[IMG\]([^\[]+)\[/IMG\]`)
+
+// extractThumbLinks returns the viewer page and thumbnail image of the thumbnail BBCode
+// in the codes HTML
+func extractThumbLinks(codesHTML string) (viewer, thumb string) {
 	doc, err := html.Parse(strings.NewReader(codesHTML))
 	if err != nil {
 		log.Printf("Failed to parse HTML codes: %v", err)
@@ -248,14 +332,10 @@ func extractBBCodes(codesHTML string) (bbThumb, bbBig string) {
 				}
 			}
 
-			// Check if it's a BBCode format
-			if strings.HasPrefix(value, "[URL=") && strings.Contains(value, "[IMG]") {
-				// Distinguish between thumbnail and big image BBCode
-				if strings.Contains(value, "/thumb/") && strings.Contains(value, ".jpeg") {
-					bbThumb = value
-				} else if strings.Contains(value, "/big/") && strings.Contains(value, ".jpg") {
-					bbBig = value
-				}
+			// The thumbnail BBCode embeds an image from /thumb/, the big one from /big/
+			if match := bbCodeLinkPattern.FindStringSubmatch(value); match != nil &&
+				strings.Contains(match[2], "/thumb/") {
+				viewer, thumb = match[1], match[2]
 			}
 		}
 
@@ -265,5 +345,5 @@ func extractBBCodes(codesHTML string) (bbThumb, bbBig string) {
 	}
 
 	parseNode(doc)
-	return bbThumb, bbBig
+	return viewer, thumb
 }
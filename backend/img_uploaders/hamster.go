@@ -12,21 +12,28 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	http "github.com/bogdanfinn/fhttp"
 	tls_client "github.com/bogdanfinn/tls-client"
-	"github.com/bogdanfinn/tls-client/profiles"
+)
+
+const (
+	hamsterBaseURL   = "https://hamster.is"
+	hamsterUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"
 )
 
 type HamsterService struct {
+	mu        sync.Mutex // Protects authToken and loggedIn, serializes logins
 	email     string
 	password  string
 	authToken string
 	loggedIn  bool
 	client    tls_client.HttpClient
+	baseURL   string
+	userAgent string
 }
 
 type HamsterUploadResult struct {
@@ -34,8 +41,6 @@ type HamsterUploadResult struct {
 	URL          string `json:"url"`
 	ViewerURL    string `json:"viewer_url"`
 	ThumbnailURL string `json:"thumbnail_url"`
-	BBThumb      string `json:"bbThumb"`
-	BBBig        string `json:"bbBig"`
 }
 
 // HamsterResponse represents the JSON response structure from hamster.is
@@ -49,24 +54,34 @@ type HamsterResponse struct {
 	} `json:"image"`
 }
 
-func NewHamsterService(email, password string) *HamsterService {
-	jar := tls_client.NewCookieJar()
-	options := []tls_client.HttpClientOption{
-		tls_client.WithTimeoutSeconds(60),
-		tls_client.WithClientProfile(profiles.Chrome_120),
-		tls_client.WithCookieJar(jar),
-	}
+func init() {
+	Register(Host{
+		Code: "HAM",
+		Name: "Hamster",
+		New: func(opts Options) (ImageUploader, error) {
+			service := NewHamsterService(opts.Email, opts.Password, opts.serviceOptions()...)
+			if service == nil {
+				return nil, fmt.Errorf("failed to create hamster client")
+			}
+			return service, nil
+		},
+	})
+}
 
-	client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
+func NewHamsterService(email, password string, opts ...ServiceOption) *HamsterService {
+	config := newServiceConfig(hamsterBaseURL, hamsterUserAgent, opts)
+	client, err := config.newTLSClient()
 	if err != nil {
 		log.Printf("Failed to create TLS client: %v", err)
 		return nil
 	}
 
 	return &HamsterService{
-		email:    email,
-		password: password,
-		client:   client,
+		email:     email,
+		password:  password,
+		client:    client,
+		baseURL:   config.baseURL,
+		userAgent: config.userAgent,
 	}
 }
 
@@ -82,14 +97,26 @@ func (h *HamsterService) extractAuthToken(htmlContent string) (string, error) {
 	return matches[1], nil
 }
 
-// login performs authentication with hamster.is
+// Init logs in to hamster.is, replacing any previous session
+func (h *HamsterService) Init(ctx context.Context) error {
+	h.mu.Lock()
+	h.loggedIn = false
+	h.mu.Unlock()
+
+	return h.Login(ctx)
+}
+
+// Login performs authentication with hamster.is
 func (h *HamsterService) Login(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if h.loggedIn {
 		return nil
 	}
 
 	// Step 1: Get the homepage to extract auth_token
-	req, err := http.NewRequest(http.MethodGet, "https://hamster.is/", nil)
+	req, err := http.NewRequest(http.MethodGet, h.baseURL+"/", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
@@ -98,7 +125,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 		"accept":          {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"},
 		"accept-encoding": {"gzip, deflate, br, zstd"},
 		"connection":      {"keep-alive"},
-		"user-agent":      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"},
+		"user-agent":      {h.userAgent},
 		http.HeaderOrderKey: {
 			"accept",
 			"accept-encoding",
@@ -112,12 +139,12 @@ func (h *HamsterService) Login(ctx context.Context) error {
 		if ctx.Err() != nil {
 			return fmt.Errorf("request cancelled: %v", ctx.Err())
 		}
-		return fmt.Errorf("failed to load hamster.is homepage: %v", err)
+		return fmt.Errorf("failed to load hamster.is homepage: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("hamster.is returned status code %d", resp.StatusCode)
+		return newStatusError(resp.StatusCode, "hamster.is returned status code %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -128,7 +155,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 	// Extract auth token from JavaScript
 	authToken, err := h.extractAuthToken(string(body))
 	if err != nil {
-		return fmt.Errorf("failed to extract auth token: %v", err)
+		return newUploadError(ErrorFatal, "failed to extract auth token: %v", err)
 	}
 
 	h.authToken = authToken
@@ -140,7 +167,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 	formData.Set("password", h.password)
 	formData.Set("auth_token", h.authToken)
 
-	loginReq, err := http.NewRequest(http.MethodPost, "https://hamster.is/login", bytes.NewBufferString(formData.Encode()))
+	loginReq, err := http.NewRequest(http.MethodPost, h.baseURL+"/login", bytes.NewBufferString(formData.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create login request: %v", err)
 	}
@@ -150,9 +177,9 @@ func (h *HamsterService) Login(ctx context.Context) error {
 		"accept-encoding": {"gzip, deflate, br, zstd"},
 		"content-type":    {"application/x-www-form-urlencoded"},
 		"connection":      {"keep-alive"},
-		"origin":          {"https://hamster.is"},
-		"referer":         {"https://hamster.is/"},
-		"user-agent":      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"},
+		"origin":          {h.baseURL},
+		"referer":         {h.baseURL + "/"},
+		"user-agent":      {h.userAgent},
 		http.HeaderOrderKey: {
 			"accept",
 			"accept-encoding",
@@ -170,7 +197,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 		if ctx.Err() != nil {
 			return fmt.Errorf("login request cancelled: %v", ctx.Err())
 		}
-		return fmt.Errorf("login request failed: %v", err)
+		return fmt.Errorf("login request failed: %w", err)
 	}
 	defer loginResp.Body.Close()
 
@@ -188,8 +215,8 @@ func (h *HamsterService) Login(ctx context.Context) error {
 
 			// Copy headers from original request (except method-specific ones)
 			redirectReq.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8")
-			redirectReq.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
-			redirectReq.Header.Set("referer", "https://hamster.is/login")
+			redirectReq.Header.Set("user-agent", h.userAgent)
+			redirectReq.Header.Set("referer", h.baseURL+"/login")
 
 			loginResp.Body.Close() // Close the redirect response
 			loginResp, err = h.client.Do(redirectReq)
@@ -205,7 +232,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 
 	// Check for KEEP_LOGIN cookie in the client's cookie jar (not just the response)
 	// The cookie gets stored in the session during redirects
-	hamsterURL, _ := url.Parse("https://hamster.is/")
+	hamsterURL, _ := url.Parse(h.baseURL + "/")
 	cookies := h.client.GetCookieJar().Cookies(hamsterURL)
 
 	log.Printf("Cookies in session for hamster.is:")
@@ -220,7 +247,7 @@ func (h *HamsterService) Login(ctx context.Context) error {
 
 	if !keepLoginFound {
 		loginBody, _ := io.ReadAll(loginResp.Body)
-		return fmt.Errorf("login failed: status %d - KEEP_LOGIN cookie not found in session. Response: %s",
+		return newUploadError(ErrorFatal, "login failed: status %d - KEEP_LOGIN cookie not found in session. Response: %s",
 			loginResp.StatusCode, string(loginBody))
 	}
 
@@ -229,6 +256,13 @@ func (h *HamsterService) Login(ctx context.Context) error {
 	return nil
 }
 
+// currentAuthToken returns the auth token of the current session
+func (h *HamsterService) currentAuthToken() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.authToken
+}
+
 // getTimestamp returns current timestamp in milliseconds
 func (h *HamsterService) getTimestamp() string {
 	return strconv.FormatInt(time.Now().UnixMilli(), 10)
@@ -238,11 +272,11 @@ func (h *HamsterService) getTimestamp() string {
 func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName string) (*HamsterUploadResult, error) {
 	log.Printf("Starting upload of %s to hamster.is...", fileName)
 
-	if !h.loggedIn {
-		if err := h.Login(ctx); err != nil {
-			return nil, fmt.Errorf("failed to login: %v", err)
-		}
+	// Login is a no-op while the session is valid
+	if err := h.Login(ctx); err != nil {
+		return nil, fmt.Errorf("failed to login: %w", err)
 	}
+	authToken := h.currentAuthToken()
 
 	if _, err := os.Stat(filePath); err != nil {
 		return nil, fmt.Errorf("failed to stat file: %v", err)
@@ -254,21 +288,7 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 	}
 	defer file.Close()
 
-	// Determine content type
-	contentType := "application/octet-stream"
-	ext := filepath.Ext(fileName)
-	switch ext {
-	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
-	case ".webp":
-		contentType = "image/webp"
-	case ".bmp":
-		contentType = "image/bmp"
-	}
+	contentType := imageContentType(fileName)
 
 	timestamp := h.getTimestamp()
 
@@ -276,7 +296,7 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 	writer := multipart.NewWriter(&buffer)
 
 	// Add the image file first
-	part, err := writer.CreateFormFile("source", fileName)
+	part, err := createImagePart(writer, "source", fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %v", err)
 	}
@@ -290,7 +310,7 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 		"type":       "file",
 		"action":     "upload",
 		"timestamp":  timestamp,
-		"auth_token": h.authToken,
+		"auth_token": authToken,
 		"nsfw":       "1",
 		"mimetype":   contentType,
 		"checksum":   "",
@@ -304,7 +324,7 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 
 	writer.Close()
 
-	req, err := http.NewRequest(http.MethodPost, "https://hamster.is/json", &buffer)
+	req, err := http.NewRequest(http.MethodPost, h.baseURL+"/json", &buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create upload request: %v", err)
 	}
@@ -312,12 +332,12 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 	req.Header = http.Header{
 		"accept":         {"application/json"},
 		"content-type":   {writer.FormDataContentType()},
-		"origin":         {"https://hamster.is"},
-		"referer":        {"https://hamster.is/"},
+		"origin":         {h.baseURL},
+		"referer":        {h.baseURL + "/"},
 		"sec-fetch-dest": {"empty"},
 		"sec-fetch-mode": {"cors"},
 		"sec-fetch-site": {"same-origin"},
-		"user-agent":     {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"},
+		"user-agent":     {h.userAgent},
 		http.HeaderOrderKey: {
 			"accept",
 			"content-type",
@@ -333,20 +353,20 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 	log.Printf("Uploading: %s", fileName)
 	log.Printf("Content-Type: %s", contentType)
 	log.Printf("Timestamp: %s", timestamp)
-	log.Printf("Auth Token: %s", h.authToken[:10]+"...")
+	log.Printf("Auth Token: %s", authToken[:10]+"...")
 
 	resp, err := h.client.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
 			return nil, fmt.Errorf("upload cancelled: %v", ctx.Err())
 		}
-		return nil, fmt.Errorf("upload request failed: %v", err)
+		return nil, fmt.Errorf("upload request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upload failed: status %d - %s", resp.StatusCode, string(respBody))
+		return nil, newStatusError(resp.StatusCode, "upload failed: status %d - %s", resp.StatusCode, string(respBody))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -367,10 +387,6 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 		ThumbnailURL: respJSON.Image.Thumb.URL,
 	}
 
-	// Generate BBCode formats
-	result.BBThumb = fmt.Sprintf("[URL=%s][IMG]%s[/IMG][/URL]", result.ViewerURL, result.ThumbnailURL)
-	result.BBBig = fmt.Sprintf("[URL=%s][IMG]%s[/IMG][/URL]", result.ViewerURL, result.URL)
-
 	log.Printf("Upload completed. URL: %s, Viewer: %s, Thumbnail: %s",
 		result.URL, result.ViewerURL, result.ThumbnailURL)
 
@@ -379,26 +395,50 @@ func (h *HamsterService) uploadToHamster(ctx context.Context, filePath, fileName
 
 // UploadImage is the main public method to upload an image
 func (h *HamsterService) UploadImage(ctx context.Context, filePath string) (*HamsterUploadResult, error) {
-	fileName := filepath.Base(filePath)
+	if err := validateImageFile(filePath); err != nil {
+		return nil, err
+	}
+
+	return h.uploadToHamster(ctx, filePath, filepath.Base(filePath))
+}
+
+// TestConnection loads the homepage without changing the session
+func (h *HamsterService) TestConnection(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+"/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("user-agent", h.userAgent)
 
-	// Validate file exists and get basic info
-	fileInfo, err := os.Stat(filePath)
+	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("file not found: %v", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
+	resp.Body.Close()
 
-	if fileInfo.Size() == 0 {
-		return nil, fmt.Errorf("file is empty")
+	if resp.StatusCode >= 400 {
+		return newStatusError(resp.StatusCode, "hamster.is returned status code %d", resp.StatusCode)
 	}
+	return nil
+}
 
-	// Check if file extension suggests it's an image
-	ext := filepath.Ext(fileName)
-	validExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp"}
-	isValidExt := slices.Contains(validExts, ext)
+// Upload uploads an image and returns the host-independent result
+func (h *HamsterService) Upload(ctx context.Context, filePath, fileName string) (*UploadResult, error) {
+	if err := validateImageFile(filePath); err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		fileName = filepath.Base(filePath)
+	}
 
-	if !isValidExt {
-		log.Printf("Warning: file %s may not be a valid image format", fileName)
+	result, err := h.uploadToHamster(ctx, filePath, fileName)
+	if err != nil {
+		return nil, err
 	}
 
-	return h.uploadToHamster(ctx, filePath, fileName)
+	return &UploadResult{
+		DirectURL: result.URL,
+		ThumbURL:  result.ThumbnailURL,
+		ViewerURL: result.ViewerURL,
+	}, nil
 }
@@ -10,21 +10,28 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"slices"
 	"strconv"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	http "github.com/bogdanfinn/fhttp"
 	tls_client "github.com/bogdanfinn/tls-client"
-	"github.com/bogdanfinn/tls-client/profiles"
+)
+
+const (
+	imgboxBaseURL   = "https://imgbox.com"
+	imgboxUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"
 )
 
 type ImgboxService struct {
+	mu                 sync.Mutex // Protects the tokens
 	imageMiniatureSize int
 	csrfToken          string
 	tokenID            string
 	tokenSecret        string
 	client             tls_client.HttpClient
+	baseURL            string
+	userAgent          string
 }
 
 type ImgboxUploadResult struct {
@@ -36,24 +43,29 @@ type ImgboxUploadResult struct {
 	ThumbnailURL string `json:"thumbnail_url"`
 	SquareURL    string `json:"square_url"`
 	CreatedAt    string `json:"created_at"`
-	BBThumb      string `json:"bbThumb"`
-	BBBig        string `json:"bbBig"`
 }
 
 type ImgboxResponse struct {
 	Files []ImgboxUploadResult `json:"files"`
 }
 
-func NewImgboxService(imageMiniatureSize int) *ImgboxService {
-	jar := tls_client.NewCookieJar()
-	options := []tls_client.HttpClientOption{
-		tls_client.WithTimeoutSeconds(60),
-		tls_client.WithClientProfile(profiles.Chrome_120),
-		tls_client.WithNotFollowRedirects(),
-		tls_client.WithCookieJar(jar),
-	}
+func init() {
+	Register(Host{
+		Code: "IB",
+		Name: "Imgbox",
+		New: func(opts Options) (ImageUploader, error) {
+			service := NewImgboxService(opts.ImageMiniatureSize, opts.serviceOptions()...)
+			if service == nil {
+				return nil, fmt.Errorf("failed to create imgbox client")
+			}
+			return service, nil
+		},
+	})
+}
 
-	client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
+func NewImgboxService(imageMiniatureSize int, opts ...ServiceOption) *ImgboxService {
+	config := newServiceConfig(imgboxBaseURL, imgboxUserAgent, opts)
+	client, err := config.newTLSClient(tls_client.WithNotFollowRedirects())
 	if err != nil {
 		log.Printf("Failed to create TLS client: %v", err)
 		return nil
@@ -62,13 +74,22 @@ func NewImgboxService(imageMiniatureSize int) *ImgboxService {
 	return &ImgboxService{
 		imageMiniatureSize: imageMiniatureSize,
 		client:             client,
+		baseURL:            config.baseURL,
+		userAgent:          config.userAgent,
 	}
 }
 
-// Replace the tokenResponse struct and unmarshaling logic in the initializeTokens method
+// Init obtains the CSRF and upload tokens
+func (i *ImgboxService) Init(ctx context.Context) error {
+	return i.initializeTokens(ctx)
+}
+
 func (i *ImgboxService) initializeTokens(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	// Step 1: Get CSRF token from homepage
-	req, err := http.NewRequest(http.MethodGet, "https://imgbox.com/", nil)
+	req, err := http.NewRequest(http.MethodGet, i.baseURL+"/", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
@@ -77,7 +98,7 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 		"accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
 		"accept-language":           {"en-US,en;q=0.9"},
 		"connection":                {"keep-alive"},
-		"host":                      {"imgbox.com"},
+		"host":                      {hostOf(i.baseURL)},
 		"sec-ch-ua":                 {`"Chromium";v="136", "Google Chrome";v="136", "Not.A/Brand";v="99"`},
 		"sec-ch-ua-mobile":          {"?0"},
 		"sec-ch-ua-platform":        {`"Windows"`},
@@ -86,7 +107,7 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 		"sec-fetch-site":            {"none"},
 		"sec-fetch-user":            {"?1"},
 		"upgrade-insecure-requests": {"1"},
-		"user-agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"},
+		"user-agent":                {i.userAgent},
 		http.HeaderOrderKey: {
 			"accept",
 			"accept-language",
@@ -109,12 +130,12 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 		if ctx.Err() != nil {
 			return fmt.Errorf("request cancelled: %v", ctx.Err())
 		}
-		return fmt.Errorf("request failed: %v", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("imgbox returned status code %d", resp.StatusCode)
+		return newStatusError(resp.StatusCode, "imgbox returned status code %d", resp.StatusCode)
 	}
 
 	// Parse HTML to extract CSRF token
@@ -133,7 +154,7 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 	log.Printf("Successfully obtained CSRF token: %s", csrfToken[:10]+"...")
 
 	// Step 2: Generate upload tokens
-	req, err = http.NewRequest(http.MethodPost, "https://imgbox.com/ajax/token/generate", nil)
+	req, err = http.NewRequest(http.MethodPost, i.baseURL+"/ajax/token/generate", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create token request: %v", err)
 	}
@@ -141,10 +162,10 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 	req.Header = http.Header{
 		"x-csrf-token": {i.csrfToken},
 		"content-type": {"application/x-www-form-urlencoded"},
-		"user-agent":   {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"},
+		"user-agent":   {i.userAgent},
 		"accept":       {"*/*"},
-		"origin":       {"https://imgbox.com"},
-		"referer":      {"https://imgbox.com/"},
+		"origin":       {i.baseURL},
+		"referer":      {i.baseURL + "/"},
 		http.HeaderOrderKey: {
 			"accept",
 			"content-type",
@@ -160,12 +181,12 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 		if ctx.Err() != nil {
 			return fmt.Errorf("token request cancelled: %v", ctx.Err())
 		}
-		return fmt.Errorf("token request failed: %v", err)
+		return fmt.Errorf("token request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("token generation failed with status code %d", resp.StatusCode)
+		return newStatusError(resp.StatusCode, "token generation failed with status code %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -202,14 +223,23 @@ func (i *ImgboxService) initializeTokens(ctx context.Context) error {
 	return nil
 }
 
+// tokens returns the current upload token ID and secret
+func (i *ImgboxService) tokens() (string, string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.tokenID, i.tokenSecret
+}
+
 func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName string) (*ImgboxUploadResult, error) {
 	log.Printf("Starting upload of %s to imgbox...", fileName)
 
 	// Initialize tokens if not already done
-	if i.csrfToken == "" || i.tokenID == "" || i.tokenSecret == "" {
+	tokenID, tokenSecret := i.tokens()
+	if tokenID == "" || tokenSecret == "" {
 		if err := i.initializeTokens(ctx); err != nil {
-			return nil, fmt.Errorf("failed to initialize tokens: %v", err)
+			return nil, fmt.Errorf("failed to initialize tokens: %w", err)
 		}
+		tokenID, tokenSecret = i.tokens()
 	}
 
 	if _, err := os.Stat(filePath); err != nil {
@@ -227,8 +257,8 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 
 	// Add all form fields
 	fields := map[string]string{
-		"token_id":         i.tokenID,
-		"token_secret":     i.tokenSecret,
+		"token_id":         tokenID,
+		"token_secret":     tokenSecret,
 		"content_type":     "2",
 		"thumbnail_size":   strconv.Itoa(i.imageMiniatureSize) + "r",
 		"gallery_id":       "null",
@@ -243,7 +273,7 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 	}
 
 	// Add the file
-	part, err := writer.CreateFormFile("files[]", fileName)
+	part, err := createImagePart(writer, "files[]", fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %v", err)
 	}
@@ -254,17 +284,17 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 
 	writer.Close()
 
-	req, err := http.NewRequest(http.MethodPost, "https://imgbox.com/upload/process", &buffer)
+	req, err := http.NewRequest(http.MethodPost, i.baseURL+"/upload/process", &buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create upload request: %v", err)
 	}
 
 	req.Header = http.Header{
 		"content-type": {writer.FormDataContentType()},
-		"user-agent":   {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"},
+		"user-agent":   {i.userAgent},
 		"accept":       {"*/*"},
-		"origin":       {"https://imgbox.com"},
-		"referer":      {"https://imgbox.com/"},
+		"origin":       {i.baseURL},
+		"referer":      {i.baseURL + "/"},
 		http.HeaderOrderKey: {
 			"accept",
 			"content-type",
@@ -279,13 +309,17 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 		if ctx.Err() != nil {
 			return nil, fmt.Errorf("upload cancelled: %v", ctx.Err())
 		}
-		return nil, fmt.Errorf("upload request failed: %v", err)
+		return nil, fmt.Errorf("upload request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read upload response: %v", err)
+		return nil, fmt.Errorf("failed to read upload response: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, newStatusError(resp.StatusCode, "upload failed: status %d - %s", resp.StatusCode, string(body))
 	}
 
 	log.Printf("Imgbox response: %s", string(body))
@@ -301,9 +335,6 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 
 	result := &respJSON.Files[0]
 
-	result.BBThumb = fmt.Sprintf("[URL=%s][IMG]%s[/IMG][/URL]", result.URL, result.ThumbnailURL)
-	result.BBBig = fmt.Sprintf("[URL=%s][IMG]%s[/IMG][/URL]", result.URL, result.OriginalURL)
-
 	log.Printf("Upload completed. URL: %s, Original: %s, Thumbnail: %s",
 		result.URL, result.OriginalURL, result.ThumbnailURL)
 
@@ -312,26 +343,50 @@ func (i *ImgboxService) uploadToImgbox(ctx context.Context, filePath, fileName s
 
 // UploadImage is the main public method to upload an image
 func (i *ImgboxService) UploadImage(ctx context.Context, filePath string) (*ImgboxUploadResult, error) {
-	fileName := filepath.Base(filePath)
+	if err := validateImageFile(filePath); err != nil {
+		return nil, err
+	}
+
+	return i.uploadToImgbox(ctx, filePath, filepath.Base(filePath))
+}
 
-	// Validate file exists and get basic info
-	fileInfo, err := os.Stat(filePath)
+// TestConnection loads the homepage without changing the session
+func (i *ImgboxService) TestConnection(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.baseURL+"/", nil)
 	if err != nil {
-		return nil, fmt.Errorf("file not found: %v", err)
+		return fmt.Errorf("failed to create request: %v", err)
 	}
+	req.Header.Set("user-agent", i.userAgent)
 
-	if fileInfo.Size() == 0 {
-		return nil, fmt.Errorf("file is empty")
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
 	}
+	resp.Body.Close()
 
-	// Check if file extension suggests it's an image
-	ext := filepath.Ext(fileName)
-	validExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp"}
-	isValidExt := slices.Contains(validExts, ext)
+	if resp.StatusCode >= 400 {
+		return newStatusError(resp.StatusCode, "imgbox returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Upload uploads an image and returns the host-independent result
+func (i *ImgboxService) Upload(ctx context.Context, filePath, fileName string) (*UploadResult, error) {
+	if err := validateImageFile(filePath); err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		fileName = filepath.Base(filePath)
+	}
 
-	if !isValidExt {
-		log.Printf("Warning: file %s may not be a valid image format", fileName)
+	result, err := i.uploadToImgbox(ctx, filePath, fileName)
+	if err != nil {
+		return nil, err
 	}
 
-	return i.uploadToImgbox(ctx, filePath, fileName)
+	return &UploadResult{
+		DirectURL: result.OriginalURL,
+		ThumbURL:  result.ThumbnailURL,
+		ViewerURL: result.URL,
+	}, nil
 }
@@ -0,0 +1,199 @@
+package img_uploaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrorKind tells the retry logic how to react to a failed request
+type ErrorKind int
+
+const (
+	ErrorRetryable      ErrorKind = iota // Transient failure, retry after a delay
+	ErrorFatal                           // Retrying will not help
+	ErrorSessionExpired                  // Session must be re-established before retrying
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorFatal:
+		return "fatal"
+	case ErrorSessionExpired:
+		return "session expired"
+	default:
+		return "retryable"
+	}
+}
+
+// UploadError is a classified uploader error
+type UploadError struct {
+	Kind       ErrorKind
+	StatusCode int // HTTP status code, 0 if the request did not complete
+	Err        error
+}
+
+func (e *UploadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// newUploadError wraps a formatted error with the given kind
+func newUploadError(kind ErrorKind, format string, args ...any) error {
+	return &UploadError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// newStatusError builds an error for an unexpected HTTP status code
+func newStatusError(statusCode int, format string, args ...any) error {
+	return &UploadError{
+		Kind:       kindForStatus(statusCode),
+		StatusCode: statusCode,
+		Err:        fmt.Errorf(format, args...),
+	}
+}
+
+// kindForStatus classifies an HTTP status code
+func kindForStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == 401:
+		return ErrorFatal // Credentials rejected
+	case statusCode == 403, statusCode == 419, statusCode == 440:
+		return ErrorSessionExpired
+	case statusCode == 408, statusCode == 425, statusCode == 429, statusCode >= 500:
+		return ErrorRetryable
+	case statusCode >= 400:
+		return ErrorFatal // Includes 413 file too large
+	default:
+		return ErrorRetryable
+	}
+}
+
+// Classify returns the kind of an uploader error. Network failures (timeouts, connection
+// resets, truncated responses) and errors not classified by an uploader are retryable.
+// A client timeout can wrap context.DeadlineExceeded, so whether the upload was cancelled
+// is up to the caller's context, not the error.
+func Classify(err error) ErrorKind {
+	var uploadErr *UploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.Kind
+	}
+
+	return ErrorRetryable
+}
+
+// RetryPolicy configures retries of failed uploads
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt
+	BaseDelay  time.Duration // Delay before the first retry, doubled on every retry
+	MaxDelay   time.Duration // Upper bound of a single delay
+}
+
+// RetryStats describes the retries made for a single upload
+type RetryStats struct {
+	Retries int
+	Delay   time.Duration // Total time spent waiting between attempts
+}
+
+// backoff returns the jittered delay before the given retry (starting at 1)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Equal jitter: keep half of the delay, randomize the other half
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Session wraps an uploader shared by concurrent uploads and makes sure an expired
+// session is re-established only once, no matter how many uploads notice it
+type Session struct {
+	Uploader ImageUploader
+
+	mu         sync.Mutex
+	generation uint64
+}
+
+func NewSession(uploader ImageUploader) *Session {
+	return &Session{Uploader: uploader}
+}
+
+// Init establishes the session
+func (s *Session) Init(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	return s.Uploader.Init(ctx)
+}
+
+// refresh re-establishes the session unless another upload already did so after seen
+func (s *Session) refresh(ctx context.Context, seen uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != seen {
+		return nil
+	}
+	s.generation++
+	return s.Uploader.Init(ctx)
+}
+
+func (s *Session) currentGeneration() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// Upload uploads an image, retrying transient failures according to the policy
+func (s *Session) Upload(ctx context.Context, policy RetryPolicy, filePath, fileName string) (*UploadResult, RetryStats, error) {
+	var stats RetryStats
+
+	for {
+		generation := s.currentGeneration()
+		result, err := s.Uploader.Upload(ctx, filePath, fileName)
+		if err == nil {
+			return result, stats, nil
+		}
+
+		kind := Classify(err)
+		if kind == ErrorFatal || stats.Retries >= policy.MaxRetries || ctx.Err() != nil {
+			return nil, stats, err
+		}
+
+		stats.Retries++
+		delay := policy.backoff(stats.Retries)
+		log.Printf("Upload of %s failed (%s), retry %d/%d in %v: %v",
+			fileName, kind, stats.Retries, policy.MaxRetries, delay.Round(time.Millisecond), err)
+
+		select {
+		case <-time.After(delay):
+			stats.Delay += delay
+		case <-ctx.Done():
+			return nil, stats, err
+		}
+
+		if kind == ErrorSessionExpired {
+			if refreshErr := s.refresh(ctx, generation); refreshErr != nil {
+				if Classify(refreshErr) == ErrorFatal {
+					return nil, stats, fmt.Errorf("failed to renew session: %w", refreshErr)
+				}
+				log.Printf("Failed to renew session before retrying %s: %v", fileName, refreshErr)
+			}
+		}
+	}
+}
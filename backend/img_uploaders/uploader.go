@@ -0,0 +1,158 @@
+package img_uploaders
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UploadResult is the host-independent result of a single image upload
+type UploadResult struct {
+	DirectURL string `json:"directUrl"` // Full-size image
+	ThumbURL  string `json:"thumbUrl"`  // Thumbnail image
+	ViewerURL string `json:"viewerUrl"` // Host page showing the image
+	AlbumLink string `json:"albumLink"` // Album page, empty if the host has none
+}
+
+// ImageUploader is implemented by every image host
+type ImageUploader interface {
+	// Init prepares a session with the host (login, upload ID, tokens)
+	Init(ctx context.Context) error
+	// Upload uploads a single image file under the given file name
+	Upload(ctx context.Context, filePath, fileName string) (*UploadResult, error)
+	// TestConnection checks that the host can be reached without starting a session
+	TestConnection(ctx context.Context) error
+}
+
+// Options holds the user settings passed to an uploader when it is created
+type Options struct {
+	ImageMiniatureSize int
+	SessionID          string // Fastpic fp_sid cookie
+	Email              string
+	Password           string
+	BaseURL            string        // Site root, empty for the public site
+	UserAgent          string        // Empty for the built-in browser user agent
+	Timeout            time.Duration // Per request, zero for the default
+	Proxy              string        // Proxy URL, empty for a direct connection
+}
+
+// serviceOptions converts registry options into service options
+func (o Options) serviceOptions() []ServiceOption {
+	var opts []ServiceOption
+	if o.BaseURL != "" {
+		opts = append(opts, WithBaseURL(o.BaseURL))
+	}
+	if o.UserAgent != "" {
+		opts = append(opts, WithUserAgent(o.UserAgent))
+	}
+	if o.Timeout > 0 {
+		opts = append(opts, WithTimeout(o.Timeout))
+	}
+	if o.Proxy != "" {
+		opts = append(opts, WithProxy(o.Proxy))
+	}
+	return opts
+}
+
+// Host describes a registered image host
+type Host struct {
+	Code string // Template suffix, e.g. "FP" in %SCREENSHOTS_FP%
+	Name string
+	New  func(opts Options) (ImageUploader, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Host
+)
+
+// Register adds an image host to the registry. It panics if the code is already taken.
+func Register(host Host) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, h := range registry {
+		if h.Code == host.Code {
+			panic(fmt.Sprintf("img_uploaders: host %s registered twice", host.Code))
+		}
+	}
+	registry = append(registry, host)
+}
+
+// Lookup returns the host registered under the given code
+func Lookup(code string) (Host, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, h := range registry {
+		if h.Code == code {
+			return h, true
+		}
+	}
+	return Host{}, false
+}
+
+// Hosts returns all registered hosts in registration order
+func Hosts() []Host {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Clone(registry)
+}
+
+// validateImageFile checks that the file exists, is not empty and looks like an image
+func validateImageFile(filePath string) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return newUploadError(ErrorFatal, "file not found: %v", err)
+	}
+
+	if fileInfo.Size() == 0 {
+		return newUploadError(ErrorFatal, "file is empty")
+	}
+
+	if imageContentType(filePath) == "application/octet-stream" {
+		log.Printf("Warning: file %s may not be a valid image format", filepath.Base(filePath))
+	}
+
+	return nil
+}
+
+// imageContentTypes maps image file extensions to their MIME types
+var imageContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".avif": "image/avif",
+}
+
+// imageContentType returns the MIME type of an image from its file name
+func imageContentType(fileName string) string {
+	if contentType, ok := imageContentTypes[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// createImagePart starts a multipart file field with the content type of the image, where
+// CreateFormFile would send every file as application/octet-stream
+func createImagePart(writer *multipart.Writer, fieldName, fileName string) (io.Writer, error) {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(fieldName), quoteEscaper.Replace(fileName)))
+	header.Set("Content-Type", imageContentType(fileName))
+	return writer.CreatePart(header)
+}
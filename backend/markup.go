@@ -0,0 +1,196 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Markup formats a preset can render image placeholders in
+const (
+	MarkupBBCode   = "bbcode"
+	MarkupMarkdown = "markdown"
+	MarkupHTML     = "html"
+	MarkupJSON     = "json"
+)
+
+// markupRenderer emits uploaded images in one markup format
+type markupRenderer interface {
+	// image embeds the src image as a link to the page
+	image(page, src string) string
+	// list joins rendered images, on one line if spaced
+	list(images []string, spaced bool) string
+	// empty is rendered for an image that was not uploaded
+	empty() string
+}
+
+var markupRenderers = map[string]markupRenderer{
+	MarkupBBCode:   bbCodeMarkup{},
+	MarkupMarkdown: markdownMarkup{},
+	MarkupHTML:     htmlMarkup{},
+	MarkupJSON:     jsonMarkup{},
+}
+
+// newMarkupRenderer returns the renderer of the markup, BBCode if it is empty or unknown
+func newMarkupRenderer(markup string) markupRenderer {
+	if renderer, ok := markupRenderers[markup]; ok {
+		return renderer
+	}
+	return bbCodeMarkup{}
+}
+
+// validateMarkup accepts the known markup names and the empty default
+func validateMarkup(markup string) error {
+	if _, ok := markupRenderers[markup]; !ok && markup != "" {
+		return fmt.Errorf("unknown markup %q", markup)
+	}
+	return nil
+}
+
+// renderImage renders the thumbnail, or the full-size image if big, linking to the viewer page.
+// Hosts without a viewer page or thumbnail fall back to the direct link.
+func renderImage(r markupRenderer, img UploadedImage, big bool) string {
+	if !img.uploaded() {
+		return r.empty()
+	}
+
+	page := img.Viewer
+	if page == "" {
+		page = img.Direct
+	}
+	src := img.Thumb
+	if big || src == "" {
+		src = img.Direct
+	}
+	return r.image(page, src)
+}
+
+// renderImages renders every uploaded image of the list
+func renderImages(r markupRenderer, images []UploadedImage, big, spaced bool) string {
+	var rendered []string
+	for _, img := range images {
+		if img.uploaded() {
+			rendered = append(rendered, renderImage(r, img, big))
+		}
+	}
+	return r.list(rendered, spaced)
+}
+
+// joinImages puts images on one line or one per line
+func joinImages(images []string, spaced bool) string {
+	if spaced {
+		return strings.Join(images, " ")
+	}
+	return strings.Join(images, "\n")
+}
+
+type bbCodeMarkup struct{}
+
+func (bbCodeMarkup) image(page, src string) string {
+	return fmt.Sprintf("[URL=%s][IMG]%s[/IMG][/URL]", page, src)
+}
+
+func (bbCodeMarkup) list(images []string, spaced bool) string { return joinImages(images, spaced) }
+func (bbCodeMarkup) empty() string                            { return "" }
+
+type markdownMarkup struct{}
+
+func (markdownMarkup) image(page, src string) string {
+	return fmt.Sprintf("[![](%s)](%s)", src, page)
+}
+
+func (markdownMarkup) list(images []string, spaced bool) string { return joinImages(images, spaced) }
+func (markdownMarkup) empty() string                            { return "" }
+
+type htmlMarkup struct{}
+
+func (htmlMarkup) image(page, src string) string {
+	return fmt.Sprintf(`<a href="%s"><img src="%s"></a>`, html.EscapeString(page), html.EscapeString(src))
+}
+
+func (htmlMarkup) list(images []string, spaced bool) string {
+	if spaced {
+		return strings.Join(images, " ")
+	}
+	return strings.Join(images, "<br>\n")
+}
+
+func (htmlMarkup) empty() string { return "" }
+
+// jsonMarkup renders images as {"link": ..., "image": ...} objects and lists as arrays,
+// so placeholders can be used as values in a JSON template
+type jsonMarkup struct{}
+
+func (jsonMarkup) image(page, src string) string {
+	data, _ := json.Marshal(struct {
+		Link  string `json:"link"`
+		Image string `json:"image"`
+	}{page, src})
+	return string(data)
+}
+
+func (jsonMarkup) list(images []string, spaced bool) string {
+	if spaced || len(images) == 0 {
+		return "[" + strings.Join(images, ",") + "]"
+	}
+	return "[\n" + strings.Join(images, ",\n") + "\n]"
+}
+
+func (jsonMarkup) empty() string { return "null" }
+
+// templateJSON encodes a value for JSON templates: {{json .FileName}}
+func templateJSON(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// renderedImage is an uploaded image with embed codes in the preset markup
+type renderedImage struct {
+	Thumb    string // Thumbnail linking to the viewer
+	Big      string // Full-size image linking to the viewer
+	ThumbURL string // Raw thumbnail image URL
+	Direct   string // Raw full-size image URL
+	Viewer   string // Host page showing the image
+}
+
+// renderedUploadSet is the UploadSet of a host as templates see it
+type renderedUploadSet struct {
+	ContactSheet renderedImage
+	Screenshots  []renderedImage
+	Album        string
+}
+
+// renderUploads renders the uploads of every host in the markup
+func renderUploads(r markupRenderer, uploads map[string]UploadSet) map[string]renderedUploadSet {
+	rendered := make(map[string]renderedUploadSet, len(uploads))
+	for code, set := range uploads {
+		renderedSet := renderedUploadSet{
+			ContactSheet: newRenderedImage(r, set.ContactSheet),
+			Album:        set.Album,
+		}
+		for _, screenshot := range set.Screenshots {
+			if screenshot.uploaded() {
+				renderedSet.Screenshots = append(renderedSet.Screenshots, newRenderedImage(r, screenshot))
+			}
+		}
+		rendered[code] = renderedSet
+	}
+	return rendered
+}
+
+func newRenderedImage(r markupRenderer, img UploadedImage) renderedImage {
+	if !img.uploaded() {
+		return renderedImage{}
+	}
+	return renderedImage{
+		Thumb:    renderImage(r, img, false),
+		Big:      renderImage(r, img, true),
+		ThumbURL: img.Thumb,
+		Direct:   img.Direct,
+		Viewer:   img.Viewer,
+	}
+}
@@ -1,10 +1,19 @@
 package backend
 
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
 // TemplatePreset represents a saved template configuration
 type TemplatePreset struct {
 	ID       string `json:"id" koanf:"id"`
 	Name     string `json:"name" koanf:"name"`
-	Template string `json:"template" koanf:"template"`
+	Header   string `json:"header" koanf:"header"`     // Rendered once before the movies
+	Template string `json:"template" koanf:"template"` // Rendered for every completed movie
+	Footer   string `json:"footer" koanf:"footer"`     // Rendered once after the movies
+	Markup   string `json:"markup" koanf:"markup"`     // Image markup: bbcode (default), markdown, html or json
 }
 
 // Movie represents a media file with its metadata
@@ -12,6 +21,7 @@ type Movie struct {
 	ID                string  `json:"id"`
 	FileName          string  `json:"fileName"`
 	FilePath          string  `json:"filePath"`
+	FileMissing       bool    `json:"fileMissing,omitempty"` // Source file disappeared since the session was saved
 	FileSize          string  `json:"fileSize"`
 	FileSizeBytes     int64   `json:"fileSizeBytes"`
 	DurationFormatted string  `json:"duration"`
@@ -24,29 +34,85 @@ type Movie struct {
 	VideoCodec        string  `json:"videoCodec"`
 	AudioCodec        string  `json:"audioCodec"`
 
-	// Fastpic URLs
-	ContactSheetURL    string   `json:"contactSheetUrl"`    // MTN-generated contact sheet (small)
-	ContactSheetBigURL string   `json:"contactSheetBigUrl"` // MTN-generated contact sheet (big)
-	ScreenshotURLs     []string `json:"screenshotUrls"`     // Individual screenshots (small)
-	ScreenshotBigURLs  []string `json:"screenshotBigUrls"`  // Individual screenshots (big)
-	ScreenshotAlbum    string   `json:"screenshotAlbum"`    // Album link
-
-	// Imgbox Results - Renamed from Thumbnail* to ContactSheet*
-	ContactSheetURLIB    string   `json:"contactSheetUrlIb"`    // MTN-generated contact sheet (small)
-	ContactSheetBigURLIB string   `json:"contactSheetBigUrlIb"` // MTN-generated contact sheet (big)
-	ScreenshotURLsIB     []string `json:"screenshotUrlsIb"`     // Individual screenshots (small)
-	ScreenshotBigURLsIB  []string `json:"screenshotBigUrlsIb"`  // Individual screenshots (big)
-
-	// Hamster Results
-	ContactSheetURLHam    string   `json:"contactSheetUrlHam"`    // MTN-generated contact sheet (small)
-	ContactSheetBigURLHam string   `json:"contactSheetBigUrlHam"` // MTN-generated contact sheet (big)
-	ScreenshotURLsHam     []string `json:"screenshotUrlsHam"`     // Individual screenshots (small)
-	ScreenshotBigURLsHam  []string `json:"screenshotBigUrlsHam"`  // Individual screenshots (big)
+	// Every video, audio and subtitle stream in file order
+	Streams []MediaStream `json:"streams,omitempty"`
+	// Chapters in file order
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// Processing overrides; the global settings apply to everything left unset
+	Overrides MovieOverrides `json:"overrides"`
+
+	// Upload results keyed by host code (FP, IB, HAM)
+	Uploads map[string]UploadSet `json:"uploads"`
+	// Generated media kept in the cache directory, reused by later runs
+	Media GeneratedMedia `json:"media"`
 
 	Params          map[string]string `json:"params"`
 	ProcessingState ProcessingState   `json:"processingState"`           // State constants defined below
 	ProcessingError string            `json:"processingError,omitempty"` // Error details if processing fails
 	Errors          []string          `json:"errors,omitempty"`          // Individual errors that occurred during processing
+
+	UploadRetries      int   `json:"uploadRetries,omitempty"`      // Upload retries made for this movie
+	UploadRetryDelayMs int64 `json:"uploadRetryDelayMs,omitempty"` // Total backoff delay spent on retries
+}
+
+// UploadedImage holds the links of a single image uploaded to a host
+// Embed codes are rendered from these in the markup of the preset.
+type UploadedImage struct {
+	Thumb  string `json:"thumb"`  // Thumbnail image URL
+	Direct string `json:"direct"` // Direct image URL
+	Viewer string `json:"viewer"` // Host page showing the image
+}
+
+// UploadSet holds everything uploaded for a movie to a single host
+type UploadSet struct {
+	ContactSheet UploadedImage   `json:"contactSheet"`    // MTN-generated contact sheet
+	Screenshots  []UploadedImage `json:"screenshots"`     // Individual screenshots, indexed by screenshot number
+	Album        string          `json:"album,omitempty"` // Album link, if the host groups uploads
+}
+
+// GeneratedMedia holds the paths of the media files generated for a movie
+type GeneratedMedia struct {
+	ContactSheet string   `json:"contactSheet,omitempty"`
+	Screenshots  []string `json:"screenshots,omitempty"` // Indexed by screenshot number, empty if generation failed
+	// Positions in seconds the screenshots were planned at
+	ScreenshotTimestamps []float64 `json:"screenshotTimestamps,omitempty"`
+	// Positions the screenshots were actually taken at, which differ with smart frame selection
+	FrameTimestamps []float64 `json:"frameTimestamps,omitempty"`
+	SmartFrames     bool      `json:"smartFrames,omitempty"`  // Taken with smart frame selection
+	Tonemap         string    `json:"tonemap,omitempty"`      // Algorithm the screenshots were tonemapped with, empty if they were not
+	Untonemapped    bool      `json:"untonemapped,omitempty"` // An untonemapped copy is kept next to each screenshot
+	AutoCrop        bool      `json:"autoCrop,omitempty"`     // Taken with automatic cropping of black bars
+	Crop            string    `json:"crop,omitempty"`         // Crop filter cropdetect found, empty if the movie has no bars
+}
+
+// uploaded reports whether the image has been uploaded
+func (i UploadedImage) uploaded() bool {
+	return i.Direct != "" || i.Thumb != ""
+}
+
+// hasScreenshot reports whether the screenshot with the given index has been uploaded
+func (u UploadSet) hasScreenshot(index int) bool {
+	return index < len(u.Screenshots) && u.Screenshots[index].uploaded()
+}
+
+// clone returns a copy of the movie that shares no maps or slices with the original
+func (m Movie) clone() Movie {
+	m.Params = maps.Clone(m.Params)
+	m.Errors = slices.Clone(m.Errors)
+	m.Media.Screenshots = slices.Clone(m.Media.Screenshots)
+	m.Streams = slices.Clone(m.Streams)
+	m.Chapters = slices.Clone(m.Chapters)
+	m.Media.ScreenshotTimestamps = slices.Clone(m.Media.ScreenshotTimestamps)
+	m.Media.FrameTimestamps = slices.Clone(m.Media.FrameTimestamps)
+	if m.Uploads != nil {
+		uploads := make(map[string]UploadSet, len(m.Uploads))
+		for code, set := range m.Uploads {
+			set.Screenshots = slices.Clone(set.Screenshots)
+			uploads[code] = set
+		}
+		m.Uploads = uploads
+	}
+	return m
 }
 
 // Processing state constants
@@ -71,9 +137,44 @@ type AppState struct {
 
 // MediaInfo represents extracted media information
 type MediaInfo struct {
-	General map[string]string `json:"general"`
-	Video   map[string]string `json:"video"`
-	Audio   map[string]string `json:"audio"`
+	General  map[string]string `json:"general"`
+	Video    map[string]string `json:"video"`    // First video stream
+	Audio    map[string]string `json:"audio"`    // Default audio track, or the first one
+	Streams  []MediaStream     `json:"streams"`  // Every video, audio and subtitle stream in file order
+	Chapters []Chapter         `json:"chapters"` // Chapters in file order
+}
+
+// MovieOverrides replaces the global screenshot settings for one movie
+type MovieOverrides struct {
+	Timestamps      string  `json:"timestamps,omitempty"`      // Comma-separated positions, e.g. "00:12:30, 45:10"; beat every other setting
+	ScreenshotCount int     `json:"screenshotCount,omitempty"` // Evenly spaced screenshots, 0 = global setting
+	StartPercent    float64 `json:"startPercent,omitempty"`    // Screenshots and the contact sheet skip the part before, e.g. the intro
+	EndPercent      float64 `json:"endPercent,omitempty"`      // ... and after, e.g. the credits; 0 = the end
+}
+
+// Chapter is a chapter of a file with its bounds in seconds
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title,omitempty"`
+}
+
+// MediaStream describes a single video, audio or subtitle stream of a file
+type MediaStream struct {
+	Index         int    `json:"index"` // ffprobe stream index
+	Type          string `json:"type"`  // video, audio or subtitle
+	Codec         string `json:"codec"`
+	Language      string `json:"language,omitempty"` // ISO 639 code from the stream tags
+	Title         string `json:"title,omitempty"`
+	Default       bool   `json:"default,omitempty"`
+	Forced        bool   `json:"forced,omitempty"`
+	BitRate       string `json:"bitRate,omitempty"` // Bits per second
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	FrameRate     string `json:"frameRate,omitempty"` // Fractional, e.g. 24000/1001
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channelLayout,omitempty"`
+	SampleRate    string `json:"sampleRate,omitempty"`
 }
 
 // AppSettings represents application settings
@@ -90,6 +191,93 @@ type AppSettings struct {
 	HamsterPassword string `json:"hamsterPassword"` // Hamster.is password
 	// Save media settings
 	SaveMediaDirectory string `json:"saveMediaDirectory"` // Directory to save generated media (empty = disabled)
+	// Upload retry settings
+	UploadRetries          int `json:"uploadRetries"`          // Retries after a failed upload (0 = disabled)
+	UploadRetryBaseDelayMs int `json:"uploadRetryBaseDelayMs"` // Delay before the first retry, doubled on every retry
+	UploadRetryMaxDelayMs  int `json:"uploadRetryMaxDelayMs"`  // Upper bound of a single retry delay
+	// Network settings
+	Proxy string `json:"proxy"` // Proxy URL for all image hosts (empty = direct connection)
+	// Upload cache settings
+	VerifyCachedUploads bool `json:"verifyCachedUploads"` // Check that cached links still resolve before reusing them
+	// Screenshot placement
+	ScreenshotPerChapter bool `json:"screenshotPerChapter"` // One screenshot per chapter instead of even intervals, for files with chapters
+	// Smart frame selection settings
+	SmartFrameSelection     bool `json:"smartFrameSelection"`     // Pick the best of several frames around each screenshot position
+	SmartFrameCandidates    int  `json:"smartFrameCandidates"`    // Frames scored per screenshot
+	SmartFrameWindowSeconds int  `json:"smartFrameWindowSeconds"` // Length of the window the candidates are spread over
+	// Screenshot output format settings; ScreenshotQuality is the JPEG quality
+	ScreenshotFormat string `json:"screenshotFormat"` // jpg, png, webp or avif
+	WebPQuality      int    `json:"webpQuality"`      // 1-100, higher is better
+	AVIFQuality      int    `json:"avifQuality"`      // CRF 0-63, lower is better
+	// HDR settings
+	TonemapHDR       bool   `json:"tonemapHdr"`       // Tonemap screenshots of HDR10 and HLG video to SDR
+	TonemapAlgorithm string `json:"tonemapAlgorithm"` // hable, mobius or reinhard
+	KeepUntonemapped bool   `json:"keepUntonemapped"` // Also keep the untonemapped frame of each screenshot
+	// Cropping settings
+	AutoCrop bool `json:"autoCrop"` // Crop letterbox and pillarbox bars off screenshots
+}
+
+// ConnectionStatus is the result of a connection test against one image host
+type ConnectionStatus struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Reachable bool   `json:"reachable"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TemplateError is a template syntax or rendering error with its 1-based position in the template
+type TemplateError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// TemplateValidation is the result of linting a template
+type TemplateValidation struct {
+	Error               *TemplateError      `json:"error,omitempty"`     // Syntax or rendering error, nil if the template renders
+	UnknownPlaceholders []PlaceholderIssue  `json:"unknownPlaceholders"` // Placeholders and hosts that do not exist
+	EmptyPlaceholders   []PlaceholderIssue  `json:"emptyPlaceholders"`   // Placeholders that stay empty with the current settings
+	UnbalancedTags      []TemplateError     `json:"unbalancedTags"`      // BBCode tags without a matching opening or closing tag
+	Hosts               []TemplateHostUsage `json:"hosts"`               // Uploads the template triggers
+}
+
+// PlaceholderIssue is a problem with a placeholder at its first use in the template
+type PlaceholderIssue struct {
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// TemplateHostUsage tells which artifacts a template uploads to a host
+type TemplateHostUsage struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	ContactSheet bool   `json:"contactSheet"`
+	Screenshots  bool   `json:"screenshots"`
+}
+
+// TemplatePreview is a template rendered against a movie without processing it
+type TemplatePreview struct {
+	Result string              `json:"result"`
+	Error  *TemplateError      `json:"error,omitempty"` // Syntax or rendering error, Result is empty then
+	Sample bool                `json:"sample"`          // Rendered against the built-in sample movie
+	Hosts  []TemplateHostUsage `json:"hosts"`           // Uploads the template triggers
+}
+
+// PlaceholderInfo describes a template placeholder for the editor
+type PlaceholderInfo struct {
+	Name        string `json:"name"` // e.g. %SCREENSHOTS_FP%
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Example     string `json:"example"`
+	Host        string `json:"host,omitempty"`     // Code of the host the value is uploaded to
+	Artifact    string `json:"artifact,omitempty"` // "contactSheet" or "screenshots" for upload placeholders
 }
 
 // TemplateData represents data for template processing
@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestamps parses the Timestamps override; an empty override gives no timestamps
+func (o MovieOverrides) timestamps() ([]float64, error) {
+	var timestamps []float64
+	for _, field := range strings.Split(o.Timestamps, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		timestamp, err := parseTimestamp(field)
+		if err != nil {
+			return nil, err
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	return timestamps, nil
+}
+
+// span returns the part of the movie in seconds that screenshots are spread over
+func (o MovieOverrides) span(duration float64) (float64, float64) {
+	end := o.EndPercent
+	if end == 0 {
+		end = 100
+	}
+	return duration * o.StartPercent / 100, duration * end / 100
+}
+
+// validate checks the overrides against the duration of the movie, which is 0 if unknown
+func (o MovieOverrides) validate(duration float64) error {
+	timestamps, err := o.timestamps()
+	if err != nil {
+		return err
+	}
+	if len(timestamps) > maxScreenshotCount {
+		return fmt.Errorf("at most %d timestamps can be set", maxScreenshotCount)
+	}
+	for _, timestamp := range timestamps {
+		if duration > 0 && timestamp >= duration {
+			return fmt.Errorf("timestamp %s is past the end of the movie", FormatDuration(time.Duration(timestamp*float64(time.Second))))
+		}
+	}
+
+	if o.ScreenshotCount < 0 || o.ScreenshotCount > maxScreenshotCount {
+		return fmt.Errorf("screenshot count must be between 0 and %d", maxScreenshotCount)
+	}
+	if o.StartPercent < 0 || o.StartPercent >= 100 || o.EndPercent < 0 || o.EndPercent > 100 {
+		return fmt.Errorf("range must be between 0 and 100 percent")
+	}
+	if o.EndPercent != 0 && o.EndPercent <= o.StartPercent {
+		return fmt.Errorf("range end must be after its start")
+	}
+	return nil
+}
+
+// parseTimestamp parses seconds or [HH:]MM:SS positions, e.g. 95.5, 12:30 or 1:02:03.5
+func parseTimestamp(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var seconds float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		// Only the seconds may have a fraction; minutes and seconds after a colon stay below 60
+		if err != nil || number < 0 || i < len(parts)-1 && number != float64(int(number)) || i > 0 && number >= 60 {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
+
+// SetMovieOverrides replaces the processing overrides of a movie. Media and uploads made for the
+// previous overrides are dropped, and a completed movie is queued again to redo them.
+func (s *SpoilerService) SetMovieOverrides(movieID string, overrides MovieOverrides) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.processing {
+		return fmt.Errorf("overrides cannot be changed while processing")
+	}
+	movie, exists := s.getMovieByIDLocked(movieID)
+	if !exists {
+		return fmt.Errorf("movie %s not found", movieID)
+	}
+	if err := overrides.validate(movie.Duration); err != nil {
+		return err
+	}
+
+	s.updateMovieByIDLocked(movieID, func(m *Movie) {
+		// The contact sheet covers the range
+		rangeChanged := m.Overrides.StartPercent != overrides.StartPercent || m.Overrides.EndPercent != overrides.EndPercent
+		m.Overrides = overrides
+
+		dropped := s.dropStaleScreenshots(m)
+		if rangeChanged && m.Media.ContactSheet != "" {
+			os.Remove(m.Media.ContactSheet)
+			m.Media.ContactSheet = ""
+		}
+		if rangeChanged {
+			for code, set := range m.Uploads {
+				if set.ContactSheet.uploaded() {
+					set.ContactSheet = UploadedImage{}
+					m.Uploads[code] = set
+					dropped = true
+				}
+			}
+		}
+		if dropped && m.ProcessingState == StateCompleted {
+			m.ProcessingState = StatePending
+		}
+	})
+	s.emitStateLocked()
+	return nil
+}
@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"spoilr/backend/img_uploaders"
+	"strings"
+)
+
+// Artifacts a placeholder can depend on
+const (
+	artifactContactSheet = "contactSheet"
+	artifactScreenshots  = "screenshots"
+)
+
+// Placeholder categories, in the order the editor shows them
+const (
+	categoryFileInfo      = "File Info"
+	categoryVideo         = "Video"
+	categoryAudio         = "Audio"
+	categoryContactSheets = "Contact Sheets"
+	categoryMediaInfo     = "Media Info"
+	categoryBatch         = "Batch"
+	categoryTracks        = "Tracks"
+)
+
+// sampleScreenshotCount is the number of screenshots the sample movie has on every host
+const sampleScreenshotCount = 3
+
+// moviePlaceholders describes the placeholders filled from movie fields and params
+var moviePlaceholders = []PlaceholderInfo{
+	{Name: "FILE_NAME", Category: categoryFileInfo, Description: "Original filename of the video file"},
+	{Name: "FILE_SIZE", Category: categoryFileInfo, Description: "File size in human-readable format"},
+	{Name: "DURATION", Category: categoryFileInfo, Description: "Video duration in HH:MM:SS or MM:SS format"},
+	{Name: "CHAPTER_COUNT", Category: categoryFileInfo, Description: "Number of chapters"},
+	{Name: "CHAPTERS", Category: categoryFileInfo, Description: "Chapter list, one \"1. 0:00 Title\" line per chapter"},
+	{Name: "WIDTH", Category: categoryVideo, Description: "Video width in pixels"},
+	{Name: "HEIGHT", Category: categoryVideo, Description: "Video height in pixels"},
+	{Name: "DISPLAY_WIDTH", Category: categoryVideo, Description: "Displayed width in pixels, wider than WIDTH for anamorphic video"},
+	{Name: "DISPLAY_HEIGHT", Category: categoryVideo, Description: "Displayed height in pixels"},
+	{Name: "DAR", Category: categoryVideo, Description: "Display aspect ratio, e.g. 16:9 or 2.40:1"},
+	{Name: "BIT_RATE", Category: categoryVideo, Description: "Overall bitrate of the file"},
+	{Name: "VIDEO_BIT_RATE", Category: categoryVideo, Description: "Video stream bitrate"},
+	{Name: "VIDEO_CODEC", Category: categoryVideo, Description: "Video codec name"},
+	{Name: "VIDEO_FPS", Category: categoryVideo, Description: "Video framerate in decimal format"},
+	{Name: "VIDEO_FPS_FRACTIONAL", Category: categoryVideo, Description: "Video framerate in fractional format"},
+	{Name: "VIDEO_HDR", Category: categoryVideo, Description: "Dynamic range: SDR, HDR10, HDR10+, HLG or Dolby Vision with its profile"},
+	{Name: "VIDEO_BIT_DEPTH", Category: categoryVideo, Description: "Video bit depth"},
+	{Name: "VIDEO_MASTERING_DISPLAY", Category: categoryVideo, Description: "Mastering display primaries and luminance of HDR video"},
+	{Name: "VIDEO_MAX_CLL", Category: categoryVideo, Description: "Maximum content light level of HDR video"},
+	{Name: "VIDEO_MAX_FALL", Category: categoryVideo, Description: "Maximum frame-average light level of HDR video"},
+	{Name: "AUDIO_BIT_RATE", Category: categoryAudio, Description: "Audio stream bitrate"},
+	{Name: "AUDIO_CODEC", Category: categoryAudio, Description: "Audio codec name"},
+	{Name: "AUDIO_SAMPLE_RATE", Category: categoryAudio, Description: "Audio sample rate"},
+	{Name: "AUDIO_CHANNELS", Category: categoryAudio, Description: "Audio channel count"},
+}
+
+// sampleMediaInfo is the ffprobe output of the sample movie
+var sampleMediaInfo = MediaInfo{
+	General: map[string]string{"duration": "6137.500000", "size": "4718592000", "bit_rate": "6150000"},
+	Video: map[string]string{
+		"codec_name": "h264", "width": "1920", "height": "1080", "duration": "6137.500000", "bit_rate": "5800000",
+		"sample_aspect_ratio": "1:1", "display_aspect_ratio": "16:9", "display_width": "1920", "display_height": "1080",
+		"r_frame_rate": "24000/1001", "fps_decimal": "23.976", "avg_frame_rate": "24000/1001",
+		"pix_fmt": "yuv420p10le", "bit_depth": "10", "color_range": "tv", "color_space": "bt2020nc",
+		"color_transfer": "smpte2084", "color_primaries": "bt2020", "hdr_format": "HDR10",
+		"mastering_display": "Display P3, min 0.0050 cd/m², max 1000 cd/m²", "max_cll": "1000 cd/m²", "max_fall": "400 cd/m²",
+	},
+	Audio: map[string]string{
+		"codec_name": "aac", "duration": "6137.500000", "bit_rate": "192000", "sample_rate": "48000",
+		"channels": "2", "channel_layout": "stereo",
+	},
+	Streams: []MediaStream{
+		{Index: 0, Type: "video", Codec: "h264", Language: "eng", Title: "Sample Movie", Default: true, BitRate: "5800000", Width: 1920, Height: 1080, FrameRate: "24000/1001"},
+		{Index: 1, Type: "audio", Codec: "aac", Language: "eng", Title: "Original", Default: true, BitRate: "192000", Channels: 2, ChannelLayout: "stereo", SampleRate: "48000"},
+		{Index: 2, Type: "audio", Codec: "ac3", Language: "rus", Title: "Dub", BitRate: "448000", Channels: 6, ChannelLayout: "5.1(side)", SampleRate: "48000"},
+		{Index: 3, Type: "subtitle", Codec: "subrip", Language: "eng", Title: "Full", Default: true, BitRate: "62000"},
+		{Index: 4, Type: "subtitle", Codec: "subrip", Language: "rus", Title: "Signs", Forced: true},
+	},
+	Chapters: []Chapter{
+		{Start: 0, End: 312.5, Title: "Opening"},
+		{Start: 312.5, End: 2710, Title: "The Heist"},
+		{Start: 2710, End: 5890.25, Title: "The Escape"},
+		{Start: 5890.25, End: 6137.5, Title: "End Credits"},
+	},
+}
+
+// sampleMovie returns a made-up, fully processed movie with the given number of screenshots
+// uploaded to every host. Its links point to example.com and never change.
+func sampleMovie(screenshotCount int) Movie {
+	movie := Movie{
+		ID:              "sample",
+		FileName:        "Sample.Movie.2024.1080p.mkv",
+		FilePath:        "Sample.Movie.2024.1080p.mkv",
+		FileSizeBytes:   4718592000,
+		FileSize:        FormatFileSize(4718592000),
+		Uploads:         make(map[string]UploadSet),
+		Params:          make(map[string]string),
+		ProcessingState: StateCompleted,
+	}
+	ExtractMediaInfo(&movie, sampleMediaInfo)
+
+	for _, host := range img_uploaders.Hosts() {
+		code := strings.ToLower(host.Code)
+		set := UploadSet{ContactSheet: sampleUploadedImage(code, "contact-sheet")}
+		for i := 1; i <= screenshotCount; i++ {
+			set.Screenshots = append(set.Screenshots, sampleUploadedImage(code, fmt.Sprintf("screenshot-%d", i)))
+		}
+		movie.Uploads[host.Code] = set
+	}
+	return movie
+}
+
+func sampleUploadedImage(hostCode, name string) UploadedImage {
+	base := fmt.Sprintf("https://example.com/%s/%s", hostCode, name)
+	return UploadedImage{
+		Thumb:  base + "_thumb.jpg",
+		Direct: base + ".jpg",
+		Viewer: base + ".html",
+	}
+}
+
+// hostPlaceholders describes the upload placeholders of a host
+func hostPlaceholders(host img_uploaders.Host) []PlaceholderInfo {
+	contactSheet := func(suffix, description string) PlaceholderInfo {
+		return PlaceholderInfo{
+			Name:        "CONTACT_SHEET_" + host.Code + suffix,
+			Category:    categoryContactSheets,
+			Description: strings.TrimSpace(fmt.Sprintf("%s contact sheet %s", host.Name, description)),
+			Host:        host.Code,
+			Artifact:    artifactContactSheet,
+		}
+	}
+	screenshots := func(suffix, description string) PlaceholderInfo {
+		return PlaceholderInfo{
+			Name:        "SCREENSHOTS_" + host.Code + suffix,
+			Category:    host.Name + " Screenshots",
+			Description: fmt.Sprintf("%s screenshots %s", host.Name, description),
+			Host:        host.Code,
+			Artifact:    artifactScreenshots,
+		}
+	}
+
+	return []PlaceholderInfo{
+		contactSheet("", ""),
+		contactSheet("_BIG", "big"),
+		screenshots("", "(newline separated)"),
+		screenshots("_SPACED", "(space separated)"),
+		screenshots("_BIG", "big (newline separated)"),
+		screenshots("_BIG_SPACED", "big (space separated)"),
+	}
+}
+
+// staticPlaceholders returns every placeholder that does not depend on the loaded movies,
+// without the surrounding % signs
+func staticPlaceholders() []PlaceholderInfo {
+	placeholders := slices.Clone(moviePlaceholders)
+	placeholders = append(placeholders, trackPlaceholders()...)
+	placeholders = append(placeholders, batchPlaceholders...)
+	for _, host := range img_uploaders.Hosts() {
+		placeholders = append(placeholders, hostPlaceholders(host)...)
+	}
+	for _, section := range []string{"General", "Video", "Audio"} {
+		for _, key := range mediaInfoKeys[section] {
+			placeholders = append(placeholders, mediaInfoPlaceholder(section, key))
+		}
+	}
+	return placeholders
+}
+
+func mediaInfoPlaceholder(section, key string) PlaceholderInfo {
+	return PlaceholderInfo{
+		Name:        section + "@" + key,
+		Category:    categoryMediaInfo,
+		Description: fmt.Sprintf("Raw ffprobe %s value %s", strings.ToLower(section), key),
+	}
+}
+
+// GetPlaceholderCatalog returns every template placeholder with a description and an example
+// value, including the %Video@key% style values found in the loaded movies
+func (s *SpoilerService) GetPlaceholderCatalog() []PlaceholderInfo {
+	catalog := staticPlaceholders()
+	known := make(map[string]bool, len(catalog))
+	for _, placeholder := range catalog {
+		known[placeholder.Name] = true
+	}
+
+	// Raw values of the loaded movies serve as examples and add keys missing from the catalog
+	s.mu.RLock()
+	seen := make(map[string]string)
+	for _, movie := range s.movies {
+		for param, value := range movie.Params {
+			name := strings.Trim(param, "%")
+			if _, _, ok := strings.Cut(name, "@"); ok && value != "" && seen[name] == "" {
+				seen[name] = value
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	var dynamic []PlaceholderInfo
+	for name := range seen {
+		section, key, _ := strings.Cut(name, "@")
+		if _, ok := mediaInfoKeys[section]; ok && !known[name] {
+			dynamic = append(dynamic, mediaInfoPlaceholder(section, key))
+		}
+	}
+	sort.Slice(dynamic, func(i, j int) bool { return dynamic[i].Name < dynamic[j].Name })
+	catalog = append(catalog, dynamic...)
+
+	// Loaded movies give real examples; the sample movie fills in the rest
+	sample := sampleMovie(sampleScreenshotCount)
+	examples := newTemplateContext(sample, newBatchContext([]Movie{sample}), bbCodeMarkup{}).placeholders
+	for i := range catalog {
+		example := seen[catalog[i].Name]
+		if example == "" {
+			example = examples[catalog[i].Name]
+		}
+		catalog[i].Example = example
+		catalog[i].Name = "%" + catalog[i].Name + "%"
+	}
+	return catalog
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderTemplatePreview renders an unsaved template against a loaded movie, or against the
+// built-in sample movie if movieID is empty. Images that are not uploaded yet get placeholder
+// links, so the preview shows the layout of the final post. Aggregate placeholders cover the
+// completed movies, or just the previewed movie if none is completed. Images are rendered in
+// the markup, BBCode if it is empty.
+func (s *SpoilerService) RenderTemplatePreview(template, markup, movieID string) (TemplatePreview, error) {
+	if err := validateMarkup(markup); err != nil {
+		return TemplatePreview{}, err
+	}
+
+	// The sample has chapters, so its screenshot count follows the screenshot placement
+	movie := sampleMovie(len(s.screenshotTimestamps(sampleMovie(0))))
+	batch := []Movie{movie}
+	if movieID != "" {
+		loaded, exists := s.getMovieByID(movieID)
+		if !exists {
+			return TemplatePreview{}, fmt.Errorf("movie %s not found", movieID)
+		}
+		movie = loaded
+
+		s.mu.RLock()
+		batch = completedMovies(s.copyMoviesLocked())
+		s.mu.RUnlock()
+		if len(batch) == 0 {
+			batch = []Movie{movie}
+		}
+	}
+
+	preview := TemplatePreview{
+		Sample: movieID == "",
+		Hosts:  s.templateHostUsage(template),
+	}
+	fillPreviewUploads(&movie, preview.Hosts, len(s.screenshotTimestamps(movie)))
+
+	tmpl, err := parseSpoilerTemplate(template)
+	if err != nil {
+		preview.Error = err.(*TemplateError)
+		return preview, nil
+	}
+	if preview.Result, err = executeSpoilerTemplate(tmpl, template, newTemplateContext(movie, newBatchContext(batch), newMarkupRenderer(markup))); err != nil {
+		preview.Error = err.(*TemplateError)
+	}
+	return preview, nil
+}
+
+// fillPreviewUploads gives every image the template would upload a placeholder link unless it
+// is uploaded already
+func fillPreviewUploads(movie *Movie, hosts []TemplateHostUsage, screenshotCount int) {
+	uploads := make(map[string]UploadSet, len(movie.Uploads))
+	for code, set := range movie.Uploads {
+		uploads[code] = set
+	}
+
+	for _, host := range hosts {
+		code := strings.ToLower(host.Code)
+		set := uploads[host.Code]
+		if host.ContactSheet && !set.ContactSheet.uploaded() {
+			set.ContactSheet = sampleUploadedImage(code, "contact-sheet")
+		}
+		if host.Screenshots {
+			screenshots := make([]UploadedImage, max(len(set.Screenshots), screenshotCount))
+			copy(screenshots, set.Screenshots)
+			for i := range screenshots {
+				if !screenshots[i].uploaded() {
+					screenshots[i] = sampleUploadedImage(code, fmt.Sprintf("screenshot-%d", i+1))
+				}
+			}
+			set.Screenshots = screenshots
+		}
+		uploads[host.Code] = set
+	}
+	movie.Uploads = uploads
+}
@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// sessionVersion is bumped when the session file format changes incompatibly
+const sessionVersion = 1
+
+// SessionData is the movie queue persisted between application runs
+type SessionData struct {
+	Version int     `json:"version"`
+	Movies  []Movie `json:"movies"`
+}
+
+// sessionFilePath places the session file next to the config file
+func sessionFilePath() string {
+	initSpoilerConfigPath()
+	return filepath.Join(filepath.Dir(ConfigPath), "spoilr.session")
+}
+
+// loadSession reads the persisted movie queue. A missing session file is not an error.
+func loadSession(path string) ([]Movie, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %v", err)
+	}
+
+	var session SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session file: %v", err)
+	}
+	if session.Version != sessionVersion {
+		return nil, fmt.Errorf("unsupported session file version %d", session.Version)
+	}
+
+	return session.Movies, nil
+}
+
+// saveSession writes the movie queue atomically so a crash never leaves a truncated file
+func saveSession(path string, movies []Movie) error {
+	data, err := json.Marshal(SessionData{Version: sessionVersion, Movies: movies})
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace session file: %v", err)
+	}
+
+	return nil
+}
+
+// restoreMovies prepares movies loaded from the session file for a new run: source files
+// are checked again and movies interrupted mid-processing go back to pending
+func restoreMovies(movies []Movie) []Movie {
+	restored := make([]Movie, 0, len(movies))
+	for _, movie := range movies {
+		if movie.ID == "" || movie.FilePath == "" {
+			continue
+		}
+
+		if movie.Params == nil {
+			movie.Params = make(map[string]string)
+		}
+		if movie.Uploads == nil {
+			movie.Uploads = make(map[string]UploadSet)
+		}
+
+		_, err := os.Stat(movie.FilePath)
+		movie.FileMissing = err != nil
+
+		switch movie.ProcessingState {
+		case StatePending, StateCompleted, StateError, StateAnalyzingMedia:
+		default:
+			movie.ProcessingState = StatePending
+			movie.ProcessingError = ""
+		}
+
+		// Uploaded links stay usable, but a missing file can't be processed again
+		if movie.FileMissing {
+			log.Printf("Session file %s no longer exists", movie.FilePath)
+			if movie.ProcessingState != StateCompleted {
+				movie.ProcessingState = StateError
+				movie.ProcessingError = "Source file not found"
+			}
+		}
+
+		restored = append(restored, movie)
+	}
+	return restored
+}
@@ -10,18 +10,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"spoilr/backend/img_uploaders"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 type SpoilerService struct {
-	mu                  sync.RWMutex // Protects movies, processing, cancelCtx, cancelFn
-	app                 *application.App
+	mu                  sync.RWMutex // Protects movies, processing, cancelCtx, cancelFn, hosts
+	events              EventSink
+	dialogs             DialogProvider
 	movies              []Movie
 	settings            AppSettings
 	processing          bool
@@ -30,79 +32,149 @@ type SpoilerService struct {
 	screenshotSemaphore chan struct{} // Limits concurrent screenshot generation
 	uploadSemaphore     chan struct{} // Limits concurrent uploads
 	configManager       *ConfigService
+	sessionPath         string                // Session file saved on every state change, empty if the queue is not persisted
+	sessionMu           sync.Mutex            // Serializes session file writes
+	preset              *TemplatePreset       // Used instead of the current preset when set
+	hostFilter          []string              // Restricts uploads to these host codes when set
+	hostBaseURLs        map[string]string     // Site roots by host code, overriding the public sites
+	hosts               map[string]HostConfig // Host overrides from the config, keyed by host code
+	uploadCache         *uploadCache
+}
+
+// HostRequirement tracks which artifacts the template needs from a single image host
+type HostRequirement struct {
+	ContactSheet bool
+	Screenshots  bool
 }
 
 // UploaderRequirements tracks what uploaders are needed based on template
 type UploaderRequirements struct {
-	NeedsFastpic bool
-	NeedsImgbox  bool
-	NeedsHamster bool
+	Hosts map[string]HostRequirement // Keyed by host code
+}
+
+// NeedsContactSheet reports whether any host needs the contact sheet
+func (r UploaderRequirements) NeedsContactSheet() bool {
+	for _, host := range r.Hosts {
+		if host.ContactSheet {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsScreenshots reports whether any host needs individual screenshots
+func (r UploaderRequirements) NeedsScreenshots() bool {
+	for _, host := range r.Hosts {
+		if host.Screenshots {
+			return true
+		}
+	}
+	return false
+}
 
-	FastpicContactSheet bool
-	FastpicScreenshots  bool
-	ImgboxContactSheet  bool
-	ImgboxScreenshots   bool
-	HamsterContactSheet bool
-	HamsterScreenshots  bool
+func NewSpoilerService(opts ...Option) *SpoilerService {
+	service := newSpoilerService(opts...)
+	service.restoreSession()
+	return service
 }
 
-func NewSpoilerService() *SpoilerService {
+// newSpoilerService creates a service with an empty queue that is not persisted
+func newSpoilerService(opts ...Option) *SpoilerService {
 	configManager := NewConfigService()
 	config := configManager.GetConfig()
 
 	service := &SpoilerService{
-		movies: make([]Movie, 0),
-		settings: AppSettings{
-			ScreenshotCount:          config.ScreenshotCount,
-			FastpicSID:               config.FastpicSID,
-			ScreenshotQuality:        config.ScreenshotQuality,
-			MaxConcurrentScreenshots: config.MaxConcurrentScreenshots,
-			MaxConcurrentUploads:     config.MaxConcurrentUploads,
-			MtnArgs:                  config.MtnArgs,
-			ImageMiniatureSize:       config.ImageMiniatureSize,
-			HamsterEmail:             config.HamsterEmail,
-			HamsterPassword:          config.HamsterPassword,
-			SaveMediaDirectory:       config.SaveMediaDirectory,
-		},
+		events:        discardEvents{},
+		dialogs:       noDialogs{},
+		movies:        make([]Movie, 0),
+		settings:      appSettingsFromConfig(config),
+		hosts:         config.Hosts,
 		processing:    false,
 		configManager: configManager,
+		uploadCache:   newUploadCache(),
+	}
+	for _, opt := range opts {
+		opt(service)
 	}
 
 	service.initSemaphores()
 	return service
 }
 
+// restoreSession loads the movie queue saved by a previous run and enables persistence
+func (s *SpoilerService) restoreSession() {
+	path := sessionFilePath()
+	movies, err := loadSession(path)
+	if err != nil {
+		log.Printf("Failed to restore session: %v", err)
+	}
+
+	s.mu.Lock()
+	s.movies = restoreMovies(movies)
+	s.sessionPath = path
+
+	// Movies that were still being analyzed need their media info again
+	var analyzeIDs []string
+	for _, movie := range s.movies {
+		if movie.ProcessingState == StateAnalyzingMedia {
+			analyzeIDs = append(analyzeIDs, movie.ID)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(s.movies) > 0 {
+		log.Printf("Restored %d movies from session", len(s.movies))
+	}
+	if len(analyzeIDs) > 0 {
+		go s.analyzeMovies(analyzeIDs)
+	}
+}
+
 func (s *SpoilerService) initSemaphores() {
 	s.screenshotSemaphore = make(chan struct{}, s.settings.MaxConcurrentScreenshots)
 	s.uploadSemaphore = make(chan struct{}, s.settings.MaxConcurrentUploads)
 }
 
-func (s *SpoilerService) SetApp(app *application.App) {
-	s.app = app
-}
-
 func (s *SpoilerService) GetState() AppState {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return AppState{
-		Processing: s.processing,
-		Movies:     append([]Movie(nil), s.movies...),
-	}
+	return s.getStateLocked()
 }
 
 // getStateLocked returns state without locking — caller must hold s.mu.
 func (s *SpoilerService) getStateLocked() AppState {
 	return AppState{
 		Processing: s.processing,
-		Movies:     append([]Movie(nil), s.movies...),
+		Movies:     s.copyMoviesLocked(),
 	}
 }
 
-// emitStateLocked emits state to the frontend — caller must hold s.mu (read or write).
+// copyMoviesLocked returns deep copies of all movies — caller must hold s.mu.
+func (s *SpoilerService) copyMoviesLocked() []Movie {
+	movies := make([]Movie, len(s.movies))
+	for i, movie := range s.movies {
+		movies[i] = movie.clone()
+	}
+	return movies
+}
+
+// emitStateLocked emits state to the frontend and saves the session — caller must hold s.mu (read or write).
 func (s *SpoilerService) emitStateLocked() {
-	if s.app != nil {
-		state := s.getStateLocked()
-		s.app.Event.Emit("state", state)
+	s.events.State(s.getStateLocked())
+	s.saveSessionLocked()
+}
+
+// saveSessionLocked writes the movie queue to the session file — caller must hold s.mu (read or write).
+func (s *SpoilerService) saveSessionLocked() {
+	if s.sessionPath == "" {
+		return
+	}
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	if err := saveSession(s.sessionPath, s.movies); err != nil {
+		log.Printf("Failed to save session: %v", err)
 	}
 }
 
@@ -127,58 +199,58 @@ Audio: %AUDIO_CODEC% / %AUDIO_SAMPLE_RATE% / %AUDIO_CHANNELS% / %AUDIO_BIT_RATE%
 [/spoiler]`
 }
 
-// getUploaderRequirements analyzes template to determine which uploaders are needed
+// getUploaderRequirements analyzes the current template to determine which uploaders are needed
 func (s *SpoilerService) getUploaderRequirements() UploaderRequirements {
-	req := UploaderRequirements{}
+	preset := s.currentPreset()
+	req := s.uploaderRequirements(strings.Join([]string{preset.Header, preset.Template, preset.Footer}, "\n"))
+	log.Printf("Uploader requirements: %+v", req)
+	return req
+}
 
-	// Get current template from config
-	template := s.configManager.GetCurrentTemplate()
+// uploaderRequirements returns the hosts and artifacts the template needs uploads for
+func (s *SpoilerService) uploaderRequirements(template string) UploaderRequirements {
+	req := UploaderRequirements{Hosts: make(map[string]HostRequirement)}
 
 	// Check what types of content are needed first
-	needsContactSheet := strings.Contains(template, "CONTACT_SHEET")
-	needsScreenshots := strings.Contains(template, "SCREENSHOTS")
+	needsContactSheet := strings.Contains(template, "CONTACT_SHEET") || strings.Contains(template, ".ContactSheet")
+	needsScreenshots := strings.Contains(template, "SCREENSHOTS") || strings.Contains(template, ".Screenshots")
 
 	// Early return if no image content is needed
 	if !needsContactSheet && !needsScreenshots {
 		return req
 	}
 
-	// Check for fastpic hosting suffix
-	if strings.Contains(template, "_FP_") || strings.Contains(template, "_FP%") {
-		req.NeedsFastpic = true
-		if needsContactSheet {
-			req.FastpicContactSheet = true
+	// Check for each registered hosting suffix
+	for _, host := range img_uploaders.Hosts() {
+		if len(s.hostFilter) > 0 && !slices.Contains(s.hostFilter, host.Code) {
+			continue
 		}
-		if needsScreenshots {
-			req.FastpicScreenshots = true
+		if templateUsesHost(template, host.Code) {
+			req.Hosts[host.Code] = HostRequirement{
+				ContactSheet: needsContactSheet,
+				Screenshots:  needsScreenshots,
+			}
 		}
 	}
 
-	// Check for imgbox hosting suffix
-	if strings.Contains(template, "_IB_") || strings.Contains(template, "_IB%") {
-		req.NeedsImgbox = true
-		if needsContactSheet {
-			req.ImgboxContactSheet = true
-		}
-		if needsScreenshots {
-			req.ImgboxScreenshots = true
-		}
-	}
+	return req
+}
 
-	// Check for hamster hosting suffix
-	if strings.Contains(template, "_HAM_") || strings.Contains(template, "_HAM%") {
-		req.NeedsHamster = true
-		if needsContactSheet {
-			req.HamsterContactSheet = true
-		}
-		if needsScreenshots {
-			req.HamsterScreenshots = true
-		}
+// currentPreset returns the preset whose sections are used for requirements and results
+func (s *SpoilerService) currentPreset() TemplatePreset {
+	if s.preset != nil {
+		return *s.preset
 	}
+	return s.configManager.GetCurrentPreset()
+}
 
-	fmt.Printf("%+v\n", req)
-
-	return req
+// templateUsesHost checks whether the template contains placeholders with the host suffix
+// or reads the uploads of the host, e.g. {{.Uploads.FP.ContactSheet.Thumb}}
+func templateUsesHost(template, code string) bool {
+	if strings.Contains(template, "_"+code+"_") || strings.Contains(template, "_"+code+"%") {
+		return true
+	}
+	return regexp.MustCompile(`\.Uploads\.` + code + `\b|index \.Uploads "` + code + `"`).MatchString(template)
 }
 
 // updateMovieByIDLocked updates a movie by ID — caller must hold s.mu write lock.
@@ -202,7 +274,7 @@ func (s *SpoilerService) updateMovieByID(id string, updateFn func(*Movie)) bool
 func (s *SpoilerService) getMovieByIDLocked(id string) (Movie, bool) {
 	for _, movie := range s.movies {
 		if movie.ID == id {
-			return movie, true
+			return movie.clone(), true
 		}
 	}
 	return Movie{}, false
@@ -235,16 +307,14 @@ func (s *SpoilerService) AddMovies(filePaths []string) error {
 		}
 
 		movie := Movie{
-			ID:                uuid.New().String(),
-			FileName:          filepath.Base(path),
-			FilePath:          path,
-			FileSize:          FormatFileSize(fileInfo.Size()),
-			FileSizeBytes:     fileInfo.Size(),
-			Params:            make(map[string]string),
-			ScreenshotURLs:    make([]string, 0),
-			ScreenshotURLsIB:  make([]string, 0),
-			ScreenshotURLsHam: make([]string, 0),
-			ProcessingState:   StateAnalyzingMedia,
+			ID:              uuid.New().String(),
+			FileName:        filepath.Base(path),
+			FilePath:        path,
+			FileSize:        FormatFileSize(fileInfo.Size()),
+			FileSizeBytes:   fileInfo.Size(),
+			Params:          make(map[string]string),
+			Uploads:         make(map[string]UploadSet),
+			ProcessingState: StateAnalyzingMedia,
 		}
 
 		s.movies = append(s.movies, movie)
@@ -254,6 +324,15 @@ func (s *SpoilerService) AddMovies(filePaths []string) error {
 	s.mu.Unlock()
 
 	// Second: check each file and remove non-video files
+	validCount := s.analyzeMovies(movieIDs)
+
+	log.Printf("Added %d video files out of %d total files", validCount, len(expandedPaths))
+	return nil
+}
+
+// analyzeMovies reads media info of the given movies and removes the ones that are not videos.
+// It returns the number of video files.
+func (s *SpoilerService) analyzeMovies(movieIDs []string) int {
 	var wg sync.WaitGroup
 	var validMu sync.Mutex
 	var validMovieIDs []string
@@ -303,8 +382,7 @@ func (s *SpoilerService) AddMovies(filePaths []string) error {
 	// Emit final state with only video files
 	s.emitState()
 
-	log.Printf("Added %d video files out of %d total files", len(validMovieIDs), len(expandedPaths))
-	return nil
+	return len(validMovieIDs)
 }
 
 func (s *SpoilerService) RemoveMovie(id string) {
@@ -317,16 +395,35 @@ func (s *SpoilerService) RemoveMovie(id string) {
 	}
 	s.emitStateLocked()
 	s.mu.Unlock()
+
+	removeMediaCache(id)
 }
 
 func (s *SpoilerService) ClearMovies() {
 	s.mu.Lock()
+	removed := s.movies
 	s.movies = make([]Movie, 0)
 	s.emitStateLocked()
 	s.mu.Unlock()
+
+	for _, movie := range removed {
+		removeMediaCache(movie.ID)
+	}
 }
 
 func (s *SpoilerService) StartProcessing() error {
+	if err := s.beginProcessing(); err != nil {
+		return err
+	}
+
+	go s.runProcessing()
+	return nil
+}
+
+// beginProcessing marks the service as processing and creates the cancel context
+func (s *SpoilerService) beginProcessing() error {
+	s.loadHostConfigs()
+
 	s.mu.Lock()
 	if s.processing {
 		s.mu.Unlock()
@@ -344,29 +441,30 @@ func (s *SpoilerService) StartProcessing() error {
 	s.emitStateLocked()
 	s.mu.Unlock()
 
-	go func() {
-		defer func() {
-			s.mu.Lock()
-			s.processing = false
-			// Reset any movies that are still in processing states back to pending
-			for i := range s.movies {
-				if s.movies[i].ProcessingState != StateCompleted && s.movies[i].ProcessingState != StateError {
-					s.movies[i].ProcessingState = StatePending
-					s.movies[i].ProcessingError = ""
-				}
-			}
-			s.emitStateLocked()
-			s.mu.Unlock()
-			log.Println("Processing completed")
-		}()
+	return nil
+}
 
-		err := s.processAllMoviesConcurrently()
-		if err != nil {
-			log.Printf("Processing error: %v", err)
+// runProcessing processes all pending movies and blocks until they are done
+func (s *SpoilerService) runProcessing() {
+	defer func() {
+		s.mu.Lock()
+		s.processing = false
+		// Reset any movies that are still in processing states back to pending
+		for i := range s.movies {
+			if s.movies[i].ProcessingState != StateCompleted && s.movies[i].ProcessingState != StateError {
+				s.movies[i].ProcessingState = StatePending
+				s.movies[i].ProcessingError = ""
+			}
 		}
+		s.emitStateLocked()
+		s.mu.Unlock()
+		log.Println("Processing completed")
 	}()
 
-	return nil
+	err := s.processAllMoviesConcurrently()
+	if err != nil {
+		log.Printf("Processing error: %v", err)
+	}
 }
 
 func (s *SpoilerService) addMovieError(id string, errorMsg string) {
@@ -390,28 +488,70 @@ func (s *SpoilerService) ResetMovieStatuses() {
 		// Clear any processing errors and individual errors
 		s.movies[i].ProcessingError = ""
 		s.movies[i].Errors = make([]string, 0) // Clear individual errors
+		s.movies[i].UploadRetries = 0
+		s.movies[i].UploadRetryDelayMs = 0
+
+		// Clear upload results for all hosts and generated media
+		s.movies[i].Uploads = make(map[string]UploadSet)
+		s.movies[i].Media = GeneratedMedia{}
+		removeMediaCache(s.movies[i].ID)
+
+		// A restored movie may point to a file that has been moved or deleted since
+		_, err := os.Stat(s.movies[i].FilePath)
+		s.movies[i].FileMissing = err != nil
+		if s.movies[i].FileMissing {
+			s.movies[i].ProcessingState = StateError
+			s.movies[i].ProcessingError = "Source file not found"
+		}
+	}
+	s.emitStateLocked()
+	s.mu.Unlock()
+}
 
-		// Clear processing results - Fastpic fields
-		s.movies[i].ContactSheetURL = ""
-		s.movies[i].ContactSheetBigURL = ""
-		s.movies[i].ScreenshotURLs = make([]string, 0)
-		s.movies[i].ScreenshotBigURLs = make([]string, 0)
-		s.movies[i].ScreenshotAlbum = ""
-
-		// Clear imgbox results
-		s.movies[i].ContactSheetURLIB = ""
-		s.movies[i].ContactSheetBigURLIB = ""
-		s.movies[i].ScreenshotURLsIB = make([]string, 0)
-		s.movies[i].ScreenshotBigURLsIB = make([]string, 0)
-
-		// Clear hamster results
-		s.movies[i].ContactSheetURLHam = ""
-		s.movies[i].ContactSheetBigURLHam = ""
-		s.movies[i].ScreenshotURLsHam = make([]string, 0)
-		s.movies[i].ScreenshotBigURLsHam = make([]string, 0)
+// RetryFailed queues movies that failed, completed with errors or lack uploads the current
+// template needs, and starts processing. Uploaded links and generated media are kept, so
+// only the missing pieces are redone.
+func (s *SpoilerService) RetryFailed() error {
+	requirements := s.getUploaderRequirements()
+
+	s.mu.Lock()
+	if s.processing {
+		s.mu.Unlock()
+		return fmt.Errorf("processing already in progress")
+	}
+
+	queued := 0
+	for i := range s.movies {
+		m := &s.movies[i]
+
+		switch m.ProcessingState {
+		case StateError:
+		case StateCompleted:
+			if len(m.Errors) == 0 && !s.missingMediaWork(*m, requirements).any() {
+				continue
+			}
+		default:
+			continue
+		}
+
+		_, err := os.Stat(m.FilePath)
+		m.FileMissing = err != nil
+		if m.FileMissing {
+			continue
+		}
+
+		m.ProcessingState = StatePending
+		m.ProcessingError = ""
+		queued++
 	}
 	s.emitStateLocked()
 	s.mu.Unlock()
+
+	if queued == 0 {
+		return fmt.Errorf("no failed movies to retry")
+	}
+
+	return s.StartProcessing()
 }
 
 func (s *SpoilerService) ReorderMovies(newOrder []string) error {
@@ -474,119 +614,198 @@ func (s *SpoilerService) processAllMoviesConcurrently() error {
 	}
 
 	requirements := s.getUploaderRequirements()
-	tempDir, err := s.createTempDirectory()
-	if err != nil {
-		return err
-	}
-	defer os.RemoveAll(tempDir)
-
-	uploaderServices, err := s.initializeUploaderServices(requirements)
-	if err != nil {
-		return err
-	}
+	uploaders := s.initializeUploaderServices(requirements)
 
 	log.Printf("Starting concurrent media processing for %d movies (screenshot limit: %d, upload limit: %d)",
 		len(pendingMovies), s.settings.MaxConcurrentScreenshots, s.settings.MaxConcurrentUploads)
 
-	s.processMoviesConcurrently(pendingMovies, tempDir, uploaderServices, requirements)
+	s.processMoviesConcurrently(pendingMovies, uploaders, requirements)
+
+	if err := s.uploadCache.flush(); err != nil {
+		log.Printf("Failed to save upload cache: %v", err)
+	}
 	return nil
 }
 
-// Create temporary directory for processing
-func (s *SpoilerService) createTempDirectory() (string, error) {
-	tempDir, err := os.MkdirTemp("", "media_processing_*")
+// mediaCacheDir returns the directory that keeps the generated media of a movie between runs
+func mediaCacheDir(movieID string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp directory: %v", err)
+		return "", err
 	}
-	return tempDir, nil
+	return filepath.Join(cacheDir, "spoilr", "media", movieID), nil
 }
 
-// UploaderServices holds all uploader service instances
-type UploaderServices struct {
-	Fastpic *img_uploaders.FastpicService
-	Imgbox  *img_uploaders.ImgboxService
-	Hamster *img_uploaders.HamsterService
+// removeMediaCache deletes the generated media of a movie
+func removeMediaCache(movieID string) {
+	dir, err := mediaCacheDir(movieID)
+	if err != nil {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("Failed to remove media cache %s: %v", dir, err)
+	}
 }
 
 // Initialize required uploader services based on requirements
-func (s *SpoilerService) initializeUploaderServices(requirements UploaderRequirements) (*UploaderServices, error) {
-	services := &UploaderServices{}
-	imageMiniatureSize := s.configManager.GetConfig().ImageMiniatureSize
+func (s *SpoilerService) initializeUploaderServices(requirements UploaderRequirements) map[string]*img_uploaders.Session {
+	uploaders := make(map[string]*img_uploaders.Session)
+
+	for _, host := range img_uploaders.Hosts() {
+		if _, needed := requirements.Hosts[host.Code]; !needed {
+			continue
+		}
 
-	if requirements.NeedsFastpic {
-		services.Fastpic = img_uploaders.NewFastpicService(s.settings.FastpicSID, imageMiniatureSize)
-		err := services.Fastpic.GetFastpicUploadID(s.cancelCtx)
+		uploader, err := host.New(s.uploaderOptions(host.Code))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get fastpic upload ID: %v", err)
+			s.emitError(fmt.Sprintf("failed to create %s uploader: %v", host.Name, err))
+			continue
+		}
+
+		// Keep the uploader on init failure: every upload retries the session on its own
+		// and reports its error on the movie
+		session := img_uploaders.NewSession(uploader)
+		if err := session.Init(s.cancelCtx); err != nil {
+			s.emitError(fmt.Sprintf("failed to initialize %s: %v", host.Name, err))
+		} else {
+			log.Printf("%s service initialized", host.Name)
 		}
-		log.Printf("Fastpic service initialized")
+
+		uploaders[host.Code] = session
+	}
+
+	return uploaders
+}
+
+// loadHostConfigs reads the host overrides from the config file. Processing and connection
+// tests load them before they start, so edits to the file apply from the next run.
+func (s *SpoilerService) loadHostConfigs() {
+	s.setHostConfigs(s.configManager.GetConfig().Hosts)
+}
+
+func (s *SpoilerService) setHostConfigs(hosts map[string]HostConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts = hosts
+}
+
+// hostConfig returns the overrides of the given host
+func (s *SpoilerService) hostConfig(code string) HostConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hosts[code]
+}
+
+// uploaderOptions returns the settings passed to the uploader of the given host
+func (s *SpoilerService) uploaderOptions(code string) img_uploaders.Options {
+	host := s.hostConfig(code)
+	opts := img_uploaders.Options{
+		ImageMiniatureSize: s.settings.ImageMiniatureSize,
+		BaseURL:            host.BaseURL,
+		UserAgent:          host.UserAgent,
+		Timeout:            time.Duration(host.TimeoutSeconds) * time.Second,
+	}
+	if baseURL := s.hostBaseURLs[code]; baseURL != "" {
+		opts.BaseURL = baseURL
 	}
 
-	if requirements.NeedsImgbox {
-		services.Imgbox = img_uploaders.NewImgboxService(imageMiniatureSize)
-		log.Printf("Imgbox service initialized")
+	// A host proxy replaces the global one; "direct" turns it off for the host
+	opts.Proxy = s.settings.Proxy
+	if host.Proxy != "" {
+		opts.Proxy = host.Proxy
+	}
+	if opts.Proxy == directProxy {
+		opts.Proxy = ""
 	}
 
-	if requirements.NeedsHamster {
-		services.Hamster = img_uploaders.NewHamsterService(s.settings.HamsterEmail, s.settings.HamsterPassword)
-		err := services.Hamster.Login(s.cancelCtx)
-		if err != nil {
-			err = fmt.Errorf("failed to log in hamster: %v", err)
-			s.app.Event.Emit("error", map[string]string{
-				"message": err.Error(),
-			})
+	switch code {
+	case "FP":
+		opts.SessionID = s.settings.FastpicSID
+	case "HAM":
+		opts.Email = s.settings.HamsterEmail
+		opts.Password = s.settings.HamsterPassword
+	}
 
-		} else {
-			log.Printf("Hamster service initialized")
-		}
+	return opts
+}
 
+// retryPolicy returns the upload retry policy from the current settings
+func (s *SpoilerService) retryPolicy() img_uploaders.RetryPolicy {
+	return img_uploaders.RetryPolicy{
+		MaxRetries: s.settings.UploadRetries,
+		BaseDelay:  time.Duration(s.settings.UploadRetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(s.settings.UploadRetryMaxDelayMs) * time.Millisecond,
 	}
+}
 
-	return services, nil
+// emitError sends an error message to the frontend
+func (s *SpoilerService) emitError(message string) {
+	log.Println(message)
+	s.events.Error(message)
 }
 
 // Process all movies concurrently
-func (s *SpoilerService) processMoviesConcurrently(movies []Movie, tempDir string, services *UploaderServices, requirements UploaderRequirements) {
+func (s *SpoilerService) processMoviesConcurrently(movies []Movie, uploaders map[string]*img_uploaders.Session, requirements UploaderRequirements) {
 	var wg sync.WaitGroup
 	for _, movie := range movies {
 		wg.Add(1)
 		go func(movie Movie) {
 			defer wg.Done()
-			s.processMovieWithLimits(movie, tempDir, services.Fastpic, services.Imgbox, services.Hamster, requirements)
+			s.processMovieWithLimits(movie, uploaders, requirements)
 		}(movie)
 	}
 	wg.Wait()
 }
 
-func (s *SpoilerService) processMovieWithLimits(movie Movie, tempDir string, fastpicService *img_uploaders.FastpicService, imgboxService *img_uploaders.ImgboxService, hamsterService *img_uploaders.HamsterService, requirements UploaderRequirements) {
+func (s *SpoilerService) processMovieWithLimits(movie Movie, uploaders map[string]*img_uploaders.Session, requirements UploaderRequirements) {
 	s.clearMovieErrors(movie.ID)
 	s.updateMovieState(movie.ID, StateWaitingForScreenshotSlot)
 
-	movieTempDir, err := s.createMovieTempDirectory(tempDir, movie.ID)
+	movie, ok := s.prepareMovieMedia(movie.ID)
+	if !ok {
+		return
+	}
+
+	cacheDir, err := s.createMovieCacheDirectory(movie.ID)
 	if err != nil {
-		s.setMovieError(movie.ID, fmt.Sprintf("Failed to create temp directory: %v", err))
+		s.setMovieError(movie.ID, fmt.Sprintf("Failed to create cache directory: %v", err))
 		return
 	}
 
-	contactSheetPath, screenshotPaths, err := s.generateMediaConcurrently(movie, movieTempDir, requirements)
+	// Reuse media generated by a previous run and generate only what is still missing
+	work := s.missingMediaWork(movie, requirements)
+	contactSheetPath, screenshotPaths := cachedMedia(movie)
+	timestamps := s.screenshotTimestamps(movie)
+
+	contactSheetPath, screenshotPaths, err = s.generateMediaConcurrently(movie, cacheDir, work, contactSheetPath, screenshotPaths, timestamps)
+	s.updateMovieByID(movie.ID, func(m *Movie) {
+		// FrameTimestamps are recorded as the screenshots are taken
+		m.Media.ContactSheet = contactSheetPath
+		m.Media.Screenshots = screenshotPaths
+		m.Media.ScreenshotTimestamps = timestamps
+		m.Media.SmartFrames = s.settings.SmartFrameSelection
+		m.Media.Tonemap = s.tonemapping(*m)
+		m.Media.Untonemapped = s.keepsUntonemapped(*m)
+		m.Media.AutoCrop = s.settings.AutoCrop
+	})
 	if err != nil {
 		s.setMovieError(movie.ID, fmt.Sprintf("Media generation failed: %v", err))
 		return
 	}
 
-	if !s.hasMediaToUpload(contactSheetPath, screenshotPaths) {
+	if work.any() && !s.hasMediaToUpload(contactSheetPath, screenshotPaths) {
 		s.setMovieError(movie.ID, "No media generated")
 		return
 	}
 
-	// Save media to directory if enabled (before upload, while files still exist in temp)
+	// Save media to directory if enabled
 	if err := s.saveMediaToDirectory(movie, contactSheetPath, screenshotPaths); err != nil {
 		s.addMovieError(movie.ID, fmt.Sprintf("Failed to save media to directory: %v", err))
 	}
 
 	s.updateMovieState(movie.ID, StateWaitingForUploadSlot)
 
-	err = s.uploadMediaConcurrently(movie, contactSheetPath, screenshotPaths, fastpicService, imgboxService, hamsterService, requirements)
+	err = s.uploadMediaConcurrently(movie, contactSheetPath, screenshotPaths, uploaders, requirements)
 	if err != nil {
 		s.setMovieError(movie.ID, fmt.Sprintf("Upload failed: %v", err))
 		return
@@ -595,11 +814,13 @@ func (s *SpoilerService) processMovieWithLimits(movie Movie, tempDir string, fas
 	s.finalizeMovieProcessing(movie.ID)
 }
 
-// Clear previous movie errors
+// Clear previous movie errors and retry statistics
 func (s *SpoilerService) clearMovieErrors(movieID string) {
 	s.mu.Lock()
 	s.updateMovieByIDLocked(movieID, func(m *Movie) {
 		m.Errors = make([]string, 0)
+		m.UploadRetries = 0
+		m.UploadRetryDelayMs = 0
 	})
 	s.mu.Unlock()
 }
@@ -625,18 +846,124 @@ func (s *SpoilerService) setMovieError(movieID string, errorMsg string) {
 	s.mu.Unlock()
 }
 
-// Create movie-specific temporary directory
-func (s *SpoilerService) createMovieTempDirectory(tempDir, movieID string) (string, error) {
-	movieTempDir := filepath.Join(tempDir, movieID)
-	if err := os.MkdirAll(movieTempDir, 0755); err != nil {
+// Create movie-specific cache directory
+func (s *SpoilerService) createMovieCacheDirectory(movieID string) (string, error) {
+	cacheDir, err := mediaCacheDir(movieID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return "", err
 	}
-	return movieTempDir, nil
+	return cacheDir, nil
 }
 
 // Check if we have any media to upload
 func (s *SpoilerService) hasMediaToUpload(contactSheetPath string, screenshotPaths []string) bool {
-	return contactSheetPath != "" || len(screenshotPaths) > 0
+	return contactSheetPath != "" || len(s.filterNonEmptyStrings(screenshotPaths)) > 0
+}
+
+// mediaWork lists the media a movie still needs, because at least one host lacks its upload
+type mediaWork struct {
+	contactSheet bool
+	screenshots  []bool // By screenshot index
+}
+
+func (w mediaWork) any() bool {
+	return w.contactSheet || slices.Contains(w.screenshots, true)
+}
+
+// missingMediaWork compares the uploads of a movie with what the template requires
+func (s *SpoilerService) missingMediaWork(movie Movie, requirements UploaderRequirements) mediaWork {
+	work := mediaWork{screenshots: make([]bool, len(s.screenshotTimestamps(movie)))}
+
+	for code, hostReq := range requirements.Hosts {
+		set := movie.Uploads[code]
+		if hostReq.ContactSheet && !set.ContactSheet.uploaded() {
+			work.contactSheet = true
+		}
+		if hostReq.Screenshots {
+			for i := range work.screenshots {
+				if !set.hasScreenshot(i) {
+					work.screenshots[i] = true
+				}
+			}
+		}
+	}
+
+	return work
+}
+
+// prepareMovieMedia drops screenshots taken at other timestamps, e.g. after the screenshot
+// count changed, or with the other frame selection, and returns the updated movie. Media saved
+// without timestamps is checked by count.
+func (s *SpoilerService) prepareMovieMedia(movieID string) (Movie, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updateMovieByIDLocked(movieID, func(m *Movie) {
+		s.dropStaleScreenshots(m)
+	})
+
+	return s.getMovieByIDLocked(movieID)
+}
+
+// dropStaleScreenshots removes the screenshots and their uploads if they were not taken at the
+// current timestamps, in the current format or with the current tonemapping and cropping and
+// reports whether it did — caller must hold s.mu.
+func (s *SpoilerService) dropStaleScreenshots(m *Movie) bool {
+	timestamps := s.screenshotTimestamps(*m)
+	sameTimestamps := m.Media.ScreenshotTimestamps == nil && len(m.Media.Screenshots) == len(timestamps) ||
+		slices.Equal(m.Media.ScreenshotTimestamps, timestamps)
+	sameFormat := !slices.ContainsFunc(m.Media.Screenshots, func(path string) bool {
+		return path != "" && imageFormat(path) != s.screenshotFormat()
+	})
+	sameTonemap := m.Media.Tonemap == s.tonemapping(*m) && m.Media.Untonemapped == s.keepsUntonemapped(*m)
+	if sameTimestamps && sameFormat && sameTonemap && m.Media.AutoCrop == s.settings.AutoCrop &&
+		m.Media.SmartFrames == s.settings.SmartFrameSelection {
+		return false
+	}
+
+	for _, path := range m.Media.Screenshots {
+		if path != "" {
+			os.Remove(path)
+			os.Remove(untonemappedPath(path))
+		}
+	}
+	m.Media.Screenshots = nil
+	m.Media.ScreenshotTimestamps = nil
+	m.Media.FrameTimestamps = nil
+	m.Media.Crop = ""
+	for code, set := range m.Uploads {
+		set.Screenshots = nil
+		m.Uploads[code] = set
+	}
+	return true
+}
+
+// cachedMedia returns the generated media files of a movie that still exist on disk
+func cachedMedia(movie Movie) (string, []string) {
+	contactSheetPath := movie.Media.ContactSheet
+	if !fileExists(contactSheetPath) {
+		contactSheetPath = ""
+	}
+
+	screenshotPaths := make([]string, len(movie.Media.Screenshots))
+	for i, path := range movie.Media.Screenshots {
+		if fileExists(path) {
+			screenshotPaths[i] = path
+		}
+	}
+
+	return contactSheetPath, screenshotPaths
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Size() > 0
 }
 
 // Finalize movie processing and set final state
@@ -662,49 +989,58 @@ func (s *SpoilerService) finalizeMovieProcessing(movieID string) {
 	s.mu.Unlock()
 }
 
-// Generate contact sheet and screenshots with proper concurrency control
-func (s *SpoilerService) generateMediaConcurrently(movie Movie, tempDir string, requirements UploaderRequirements) (string, []string, error) {
+// Generate the missing contact sheet and screenshots with proper concurrency control.
+// Screenshot paths keep their index; failed screenshots are left empty.
+func (s *SpoilerService) generateMediaConcurrently(movie Movie, cacheDir string, work mediaWork, contactSheetPath string, screenshotPaths []string, timestamps []float64) (string, []string, error) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var generationStarted bool
-	var contactSheetPath string
-	var screenshotPaths []string
 
-	needsContactSheet := s.needsContactSheet(requirements)
-	needsScreenshots := s.needsScreenshots(requirements)
+	if len(screenshotPaths) != len(timestamps) {
+		screenshotPaths = make([]string, len(timestamps))
+	}
 
-	if needsContactSheet {
-		wg.Add(1)
-		go s.generateContactSheetAsync(&wg, &mu, &generationStarted, movie, tempDir, &contactSheetPath)
+	// Screenshots that are needed and not cached
+	var pending []int
+	for i := range screenshotPaths {
+		if screenshotPaths[i] == "" && i < len(work.screenshots) && work.screenshots[i] {
+			pending = append(pending, i)
+		}
 	}
+	generateContactSheet := work.contactSheet && contactSheetPath == ""
 
-	if needsScreenshots && s.settings.ScreenshotCount > 0 {
-		screenshotPaths = make([]string, s.settings.ScreenshotCount)
-		s.generateScreenshotsAsync(&wg, &mu, &generationStarted, movie, tempDir, screenshotPaths)
+	// Bars are detected once per movie, so all screenshots get the same crop
+	if len(pending) > 0 && s.settings.AutoCrop && movie.Media.Crop == "" {
+		movie.Media.Crop = s.detectCrop(movie, timestamps)
+		s.updateMovieByID(movie.ID, func(m *Movie) {
+			m.Media.Crop = movie.Media.Crop
+		})
 	}
 
-	wg.Wait()
+	total := len(pending)
+	if generateContactSheet {
+		total++
+	}
+	progress := s.newProgressTracker(movie.ID, StateGeneratingScreenshots, total)
 
-	if s.cancelCtx.Err() != nil {
-		return "", nil, s.cancelCtx.Err()
+	if generateContactSheet {
+		wg.Add(1)
+		go s.generateContactSheetAsync(&wg, &mu, &generationStarted, progress, movie, cacheDir, &contactSheetPath)
 	}
 
-	validScreenshots := s.filterValidScreenshots(screenshotPaths)
-	return contactSheetPath, validScreenshots, nil
-}
+	s.generateScreenshotsAsync(&wg, &mu, &generationStarted, progress, movie, cacheDir, screenshotPaths, timestamps, pending)
 
-// Check if contact sheet is needed
-func (s *SpoilerService) needsContactSheet(requirements UploaderRequirements) bool {
-	return requirements.FastpicContactSheet || requirements.ImgboxContactSheet || requirements.HamsterContactSheet
-}
+	wg.Wait()
 
-// Check if screenshots are needed
-func (s *SpoilerService) needsScreenshots(requirements UploaderRequirements) bool {
-	return requirements.FastpicScreenshots || requirements.ImgboxScreenshots || requirements.HamsterScreenshots
+	if s.cancelCtx.Err() != nil {
+		return contactSheetPath, screenshotPaths, s.cancelCtx.Err()
+	}
+
+	return contactSheetPath, screenshotPaths, nil
 }
 
 // Generate contact sheet asynchronously
-func (s *SpoilerService) generateContactSheetAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, movie Movie, tempDir string, contactSheetPath *string) {
+func (s *SpoilerService) generateContactSheetAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, progress *progressTracker, movie Movie, cacheDir string, contactSheetPath *string) {
 	defer wg.Done()
 
 	select {
@@ -713,8 +1049,9 @@ func (s *SpoilerService) generateContactSheetAsync(wg *sync.WaitGroup, mu *sync.
 
 		s.markGenerationStarted(mu, generationStarted, movie.ID)
 
-		path, err := s.generateMovieContactSheet(movie.FilePath, tempDir)
+		path, err := s.generateMovieContactSheet(movie, cacheDir)
 		*contactSheetPath = path
+		progress.step()
 
 		if err != nil {
 			s.addMovieError(movie.ID, fmt.Sprintf("Contact sheet generation failed: %v", err))
@@ -726,18 +1063,16 @@ func (s *SpoilerService) generateContactSheetAsync(wg *sync.WaitGroup, mu *sync.
 	}
 }
 
-// Generate screenshots asynchronously
-func (s *SpoilerService) generateScreenshotsAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, movie Movie, tempDir string, screenshotPaths []string) {
-	interval := movie.Duration / float64(s.settings.ScreenshotCount+1)
-
-	for i := 0; i < s.settings.ScreenshotCount; i++ {
+// Generate the screenshots with the given indexes asynchronously
+func (s *SpoilerService) generateScreenshotsAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, progress *progressTracker, movie Movie, cacheDir string, screenshotPaths []string, timestamps []float64, indexes []int) {
+	for _, i := range indexes {
 		wg.Add(1)
-		go s.generateSingleScreenshotAsync(wg, mu, generationStarted, movie, tempDir, screenshotPaths, i, interval)
+		go s.generateSingleScreenshotAsync(wg, mu, generationStarted, progress, movie, cacheDir, screenshotPaths, i, timestamps[i])
 	}
 }
 
 // Generate a single screenshot asynchronously
-func (s *SpoilerService) generateSingleScreenshotAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, movie Movie, tempDir string, screenshotPaths []string, index int, interval float64) {
+func (s *SpoilerService) generateSingleScreenshotAsync(wg *sync.WaitGroup, mu *sync.Mutex, generationStarted *bool, progress *progressTracker, movie Movie, cacheDir string, screenshotPaths []string, index int, timestamp float64) {
 	defer wg.Done()
 
 	select {
@@ -746,12 +1081,21 @@ func (s *SpoilerService) generateSingleScreenshotAsync(wg *sync.WaitGroup, mu *s
 
 		s.markGenerationStarted(mu, generationStarted, movie.ID)
 
-		timestamp := interval * float64(index+1)
-		outputPath := filepath.Join(tempDir, fmt.Sprintf("screenshot_%d.jpg", index+1))
+		outputPath := filepath.Join(cacheDir, fmt.Sprintf("screenshot_%d.%s", index+1, s.screenshotFormat()))
+		if s.settings.SmartFrameSelection {
+			timestamp = s.selectFrame(movie, timestamp)
+		}
 
-		err := s.generateScreenshot(movie.FilePath, outputPath, timestamp)
+		err := s.generateScreenshot(movie.FilePath, outputPath, timestamp, s.screenshotFilter(movie, true))
+		if err == nil && s.keepsUntonemapped(movie) {
+			if err := s.generateScreenshot(movie.FilePath, untonemappedPath(outputPath), timestamp, s.screenshotFilter(movie, false)); err != nil {
+				log.Printf("Failed to keep untonemapped screenshot %d for %s: %v", index+1, movie.FileName, err)
+			}
+		}
+		progress.step()
 		if err == nil {
 			screenshotPaths[index] = outputPath
+			s.recordFrameTimestamp(movie.ID, index, len(screenshotPaths), timestamp)
 		} else {
 			s.addMovieError(movie.ID, fmt.Sprintf("Screenshot %d generation failed: %v", index+1, err))
 			log.Printf("Failed to generate screenshot %d for %s: %v", index+1, movie.FileName, err)
@@ -762,6 +1106,18 @@ func (s *SpoilerService) generateSingleScreenshotAsync(wg *sync.WaitGroup, mu *s
 	}
 }
 
+// recordFrameTimestamp stores the position screenshot index was taken at, so it can be reproduced
+func (s *SpoilerService) recordFrameTimestamp(movieID string, index, count int, timestamp float64) {
+	s.updateMovieByID(movieID, func(m *Movie) {
+		if len(m.Media.FrameTimestamps) != count {
+			frameTimestamps := make([]float64, count)
+			copy(frameTimestamps, m.Media.FrameTimestamps)
+			m.Media.FrameTimestamps = frameTimestamps
+		}
+		m.Media.FrameTimestamps[index] = timestamp
+	})
+}
+
 // Mark generation as started (thread-safe)
 func (s *SpoilerService) markGenerationStarted(mu *sync.Mutex, generationStarted *bool, movieID string) {
 	mu.Lock()
@@ -776,134 +1132,60 @@ func (s *SpoilerService) markGenerationStarted(mu *sync.Mutex, generationStarted
 	}
 }
 
-// Filter out failed screenshots
-func (s *SpoilerService) filterValidScreenshots(screenshotPaths []string) []string {
-	var validScreenshots []string
-	for _, path := range screenshotPaths {
-		if path != "" {
-			validScreenshots = append(validScreenshots, path)
-		}
-	}
-	return validScreenshots
-}
-
-// Upload media with proper concurrency control to all three services
-func (s *SpoilerService) uploadMediaConcurrently(movie Movie, contactSheetPath string, screenshotPaths []string, fastpicService *img_uploaders.FastpicService, imgboxService *img_uploaders.ImgboxService, hamsterService *img_uploaders.HamsterService, requirements UploaderRequirements) error {
+// Upload media with proper concurrency control to all required services
+func (s *SpoilerService) uploadMediaConcurrently(movie Movie, contactSheetPath string, screenshotPaths []string, uploaders map[string]*img_uploaders.Session, requirements UploaderRequirements) error {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var uploadStarted bool
 
 	baseFileName := strings.TrimSuffix(filepath.Base(movie.FilePath), filepath.Ext(movie.FilePath))
 
-	s.uploadContactSheets(&wg, &mu, &uploadStarted, movie, contactSheetPath, baseFileName, fastpicService, imgboxService, hamsterService, requirements)
-	s.uploadScreenshots(&wg, &mu, &uploadStarted, movie, screenshotPaths, baseFileName, fastpicService, imgboxService, hamsterService, requirements)
-
-	wg.Wait()
-
-	if s.cancelCtx.Err() != nil {
-		return s.cancelCtx.Err()
-	}
-
-	return nil
-}
-
-// Upload contact sheets to all required services
-func (s *SpoilerService) uploadContactSheets(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, contactSheetPath, baseFileName string, fastpicService *img_uploaders.FastpicService, imgboxService *img_uploaders.ImgboxService, hamsterService *img_uploaders.HamsterService, requirements UploaderRequirements) {
-	if contactSheetPath == "" {
-		return
-	}
+	// Collect the uploads first so progress knows the total
+	var uploads []func(progress *progressTracker)
+	for _, host := range img_uploaders.Hosts() {
+		session, ok := uploaders[host.Code]
+		if !ok {
+			continue
+		}
+		hostReq := requirements.Hosts[host.Code]
+		set := movie.Uploads[host.Code]
 
-	if requirements.FastpicContactSheet && fastpicService != nil {
-		wg.Add(1)
-		go s.uploadContactSheetToFastpic(wg, mu, uploadStarted, movie, contactSheetPath, baseFileName, fastpicService)
-	}
+		// Links uploaded by a previous run are kept
+		if hostReq.ContactSheet && contactSheetPath != "" && !set.ContactSheet.uploaded() {
+			uploads = append(uploads, func(progress *progressTracker) {
+				s.uploadContactSheet(&wg, &mu, &uploadStarted, progress, movie, contactSheetPath, baseFileName, host, session)
+			})
+		}
 
-	if requirements.ImgboxContactSheet && imgboxService != nil {
-		wg.Add(1)
-		go s.uploadContactSheetToImgbox(wg, mu, uploadStarted, movie, contactSheetPath, imgboxService)
+		if hostReq.Screenshots {
+			for i, screenshotPath := range screenshotPaths {
+				if screenshotPath == "" || set.hasScreenshot(i) {
+					continue
+				}
+				uploads = append(uploads, func(progress *progressTracker) {
+					s.uploadSingleScreenshot(&wg, &mu, &uploadStarted, progress, movie, screenshotPath, baseFileName, i, host, session)
+				})
+			}
+		}
 	}
 
-	if requirements.HamsterContactSheet && hamsterService != nil {
+	progress := s.newProgressTracker(movie.ID, StateUploadingScreenshots, len(uploads))
+	for _, upload := range uploads {
 		wg.Add(1)
-		go s.uploadContactSheetToHamster(wg, mu, uploadStarted, movie, contactSheetPath, hamsterService)
-	}
-}
-
-// Upload screenshots to all required services
-func (s *SpoilerService) uploadScreenshots(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPaths []string, baseFileName string, fastpicService *img_uploaders.FastpicService, imgboxService *img_uploaders.ImgboxService, hamsterService *img_uploaders.HamsterService, requirements UploaderRequirements) {
-	if requirements.FastpicScreenshots && fastpicService != nil {
-		s.uploadScreenshotsToFastpic(wg, mu, uploadStarted, movie, screenshotPaths, baseFileName, fastpicService)
-	}
-
-	if requirements.ImgboxScreenshots && imgboxService != nil {
-		s.uploadScreenshotsToImgbox(wg, mu, uploadStarted, movie, screenshotPaths, imgboxService)
+		go upload(progress)
 	}
 
-	if requirements.HamsterScreenshots && hamsterService != nil {
-		s.uploadScreenshotsToHamster(wg, mu, uploadStarted, movie, screenshotPaths, hamsterService)
-	}
-}
-
-// Upload contact sheet to Fastpic
-func (s *SpoilerService) uploadContactSheetToFastpic(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, contactSheetPath, baseFileName string, fastpicService *img_uploaders.FastpicService) {
-	defer wg.Done()
-
-	select {
-	case s.uploadSemaphore <- struct{}{}:
-		defer func() { <-s.uploadSemaphore }()
-
-		s.markUploadStarted(mu, uploadStarted, movie.ID)
-
-		fileName := fmt.Sprintf("%s_contact_sheet.jpg", baseFileName)
-		result, err := fastpicService.UploadToFastpic(s.cancelCtx, contactSheetPath, fileName)
-		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Fastpic contact sheet upload failed: %v", err))
-			log.Printf("Failed to upload contact sheet to fastpic for %s: %v", movie.FileName, err)
-			return
-		}
-
-		s.updateMovieByID(movie.ID, func(m *Movie) {
-			m.ContactSheetURL = result.BBThumb
-			m.ContactSheetBigURL = result.BBBig
-			if m.ScreenshotAlbum == "" {
-				m.ScreenshotAlbum = result.AlbumLink
-			}
-		})
+	wg.Wait()
 
-	case <-s.cancelCtx.Done():
-		return
+	if s.cancelCtx.Err() != nil {
+		return s.cancelCtx.Err()
 	}
-}
-
-// Upload contact sheet to Imgbox
-func (s *SpoilerService) uploadContactSheetToImgbox(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, contactSheetPath string, imgboxService *img_uploaders.ImgboxService) {
-	defer wg.Done()
-
-	select {
-	case s.uploadSemaphore <- struct{}{}:
-		defer func() { <-s.uploadSemaphore }()
-
-		s.markUploadStarted(mu, uploadStarted, movie.ID)
 
-		result, err := imgboxService.UploadImage(s.cancelCtx, contactSheetPath)
-		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Imgbox contact sheet upload failed: %v", err))
-			log.Printf("Failed to upload contact sheet to imgbox for %s: %v", movie.FileName, err)
-			return
-		}
-
-		s.updateMovieByID(movie.ID, func(m *Movie) {
-			m.ContactSheetURLIB = result.BBThumb
-			m.ContactSheetBigURLIB = result.BBBig
-		})
-
-	case <-s.cancelCtx.Done():
-		return
-	}
+	return nil
 }
 
-// Upload contact sheet to Hamster
-func (s *SpoilerService) uploadContactSheetToHamster(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, contactSheetPath string, hamsterService *img_uploaders.HamsterService) {
+// Upload contact sheet to a single host
+func (s *SpoilerService) uploadContactSheet(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, progress *progressTracker, movie Movie, contactSheetPath, baseFileName string, host img_uploaders.Host, session *img_uploaders.Session) {
 	defer wg.Done()
 
 	select {
@@ -912,17 +1194,20 @@ func (s *SpoilerService) uploadContactSheetToHamster(wg *sync.WaitGroup, mu *syn
 
 		s.markUploadStarted(mu, uploadStarted, movie.ID)
 
-		result, err := hamsterService.UploadImage(s.cancelCtx, contactSheetPath)
+		fileName := baseFileName + "_contact_sheet" + filepath.Ext(contactSheetPath)
+		result, stats, err := s.uploadFile(host, session, contactSheetPath, fileName)
+		s.recordUploadRetries(movie.ID, stats)
+		progress.step()
 		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Hamster contact sheet upload failed: %v", err))
-			log.Printf("Failed to upload contact sheet to hamster for %s: %v", movie.FileName, err)
+			s.addMovieError(movie.ID, fmt.Sprintf("%s contact sheet upload failed: %v", host.Name, err))
+			log.Printf("Failed to upload contact sheet to %s for %s: %v", host.Name, movie.FileName, err)
 			return
 		}
 
 		s.updateMovieByID(movie.ID, func(m *Movie) {
-			m.ContactSheetURLHam = result.BBThumb
-			m.ContactSheetBigURLHam = result.BBBig
+			setContactSheetResult(m, host.Code, result)
 		})
+		s.emitState()
 
 	case <-s.cancelCtx.Done():
 		return
@@ -943,32 +1228,32 @@ func (s *SpoilerService) markUploadStarted(mu *sync.Mutex, uploadStarted *bool,
 	}
 }
 
-// Upload screenshots to Fastpic
-func (s *SpoilerService) uploadScreenshotsToFastpic(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPaths []string, baseFileName string, fastpicService *img_uploaders.FastpicService) {
-	for i, screenshotPath := range screenshotPaths {
-		wg.Add(1)
-		go s.uploadSingleScreenshotToFastpic(wg, mu, uploadStarted, movie, screenshotPath, baseFileName, i, fastpicService)
-	}
+// progressTracker counts the finished steps of a processing stage of a movie
+type progressTracker struct {
+	mu       sync.Mutex
+	events   EventSink
+	progress Progress
 }
 
-// Upload screenshots to Imgbox
-func (s *SpoilerService) uploadScreenshotsToImgbox(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPaths []string, imgboxService *img_uploaders.ImgboxService) {
-	for i, screenshotPath := range screenshotPaths {
-		wg.Add(1)
-		go s.uploadSingleScreenshotToImgbox(wg, mu, uploadStarted, movie, screenshotPath, i, imgboxService)
+func (s *SpoilerService) newProgressTracker(movieID string, state ProcessingState, total int) *progressTracker {
+	return &progressTracker{
+		events:   s.events,
+		progress: Progress{MovieID: movieID, State: state, Total: total},
 	}
 }
 
-// Upload screenshots to Hamster
-func (s *SpoilerService) uploadScreenshotsToHamster(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPaths []string, hamsterService *img_uploaders.HamsterService) {
-	for i, screenshotPath := range screenshotPaths {
-		wg.Add(1)
-		go s.uploadSingleScreenshotToHamster(wg, mu, uploadStarted, movie, screenshotPath, i, hamsterService)
-	}
+// step marks one step as finished and reports the progress
+func (p *progressTracker) step() {
+	p.mu.Lock()
+	p.progress.Done++
+	progress := p.progress
+	p.mu.Unlock()
+
+	p.events.Progress(progress)
 }
 
-// Upload single screenshot to Fastpic
-func (s *SpoilerService) uploadSingleScreenshotToFastpic(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPath, baseFileName string, index int, fastpicService *img_uploaders.FastpicService) {
+// Upload single screenshot to a single host
+func (s *SpoilerService) uploadSingleScreenshot(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, progress *progressTracker, movie Movie, screenshotPath, baseFileName string, index int, host img_uploaders.Host, session *img_uploaders.Session) {
 	defer wg.Done()
 
 	select {
@@ -977,115 +1262,104 @@ func (s *SpoilerService) uploadSingleScreenshotToFastpic(wg *sync.WaitGroup, mu
 
 		s.markUploadStarted(mu, uploadStarted, movie.ID)
 
-		fileName := fmt.Sprintf("%s_screenshot_%d.jpg", baseFileName, index+1)
-		result, err := fastpicService.UploadToFastpic(s.cancelCtx, screenshotPath, fileName)
+		fileName := fmt.Sprintf("%s_screenshot_%d%s", baseFileName, index+1, filepath.Ext(screenshotPath))
+		result, stats, err := s.uploadFile(host, session, screenshotPath, fileName)
+		s.recordUploadRetries(movie.ID, stats)
+		progress.step()
 		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Fastpic screenshot %d upload failed: %v", index+1, err))
-			log.Printf("Failed to upload screenshot %d to fastpic for %s: %v", index+1, movie.FileName, err)
+			s.addMovieError(movie.ID, fmt.Sprintf("%s screenshot %d upload failed: %v", host.Name, index+1, err))
+			log.Printf("Failed to upload screenshot %d to %s for %s: %v", index+1, host.Name, movie.FileName, err)
 			return
 		}
 
 		s.updateMovieByID(movie.ID, func(m *Movie) {
-			s.ensureScreenshotSliceSize(&m.ScreenshotURLs, index)
-			s.ensureScreenshotSliceSize(&m.ScreenshotBigURLs, index)
-
-			m.ScreenshotURLs[index] = result.BBThumb
-			m.ScreenshotBigURLs[index] = result.BBBig
-			if m.ScreenshotAlbum == "" {
-				m.ScreenshotAlbum = result.AlbumLink
-			}
+			setScreenshotResult(m, host.Code, index, result)
 		})
+		s.emitState()
 
 	case <-s.cancelCtx.Done():
 		return
 	}
 }
 
-// Upload single screenshot to Imgbox
-func (s *SpoilerService) uploadSingleScreenshotToImgbox(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPath string, index int, imgboxService *img_uploaders.ImgboxService) {
-	defer wg.Done()
-
-	select {
-	case s.uploadSemaphore <- struct{}{}:
-		defer func() { <-s.uploadSemaphore }()
-
-		s.markUploadStarted(mu, uploadStarted, movie.ID)
-
-		result, err := imgboxService.UploadImage(s.cancelCtx, screenshotPath)
-		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Imgbox screenshot %d upload failed: %v", index+1, err))
-			log.Printf("Failed to upload screenshot %d to imgbox for %s: %v", index+1, movie.FileName, err)
-			return
-		}
-
-		s.updateMovieByID(movie.ID, func(m *Movie) {
-			s.ensureScreenshotSliceSize(&m.ScreenshotURLsIB, index)
-			s.ensureScreenshotSliceSize(&m.ScreenshotBigURLsIB, index)
-
-			m.ScreenshotURLsIB[index] = result.BBThumb
-			m.ScreenshotBigURLsIB[index] = result.BBBig
-		})
-
-	case <-s.cancelCtx.Done():
+// recordUploadRetries adds the retries of a single upload to the movie totals
+func (s *SpoilerService) recordUploadRetries(movieID string, stats img_uploaders.RetryStats) {
+	if stats.Retries == 0 {
 		return
 	}
+	s.updateMovieByID(movieID, func(m *Movie) {
+		m.UploadRetries += stats.Retries
+		m.UploadRetryDelayMs += stats.Delay.Milliseconds()
+	})
 }
 
-// Upload single screenshot to Hamster
-func (s *SpoilerService) uploadSingleScreenshotToHamster(wg *sync.WaitGroup, mu *sync.Mutex, uploadStarted *bool, movie Movie, screenshotPath string, index int, hamsterService *img_uploaders.HamsterService) {
-	defer wg.Done()
-
-	select {
-	case s.uploadSemaphore <- struct{}{}:
-		defer func() { <-s.uploadSemaphore }()
-
-		s.markUploadStarted(mu, uploadStarted, movie.ID)
-
-		result, err := hamsterService.UploadImage(s.cancelCtx, screenshotPath)
-		if err != nil {
-			s.addMovieError(movie.ID, fmt.Sprintf("Hamster screenshot %d upload failed: %v", index+1, err))
-			log.Printf("Failed to upload screenshot %d to hamster for %s: %v", index+1, movie.FileName, err)
-			return
-		}
-
-		s.updateMovieByID(movie.ID, func(m *Movie) {
-			s.ensureScreenshotSliceSize(&m.ScreenshotURLsHam, index)
-			s.ensureScreenshotSliceSize(&m.ScreenshotBigURLsHam, index)
+// setContactSheetResult stores a contact sheet upload result for the host
+func setContactSheetResult(m *Movie, code string, result *img_uploaders.UploadResult) {
+	set := m.Uploads[code]
+	set.ContactSheet = uploadedImageFromResult(result)
+	if set.Album == "" {
+		set.Album = result.AlbumLink
+	}
+	setMovieUploadSet(m, code, set)
+}
 
-			m.ScreenshotURLsHam[index] = result.BBThumb
-			m.ScreenshotBigURLsHam[index] = result.BBBig
-		})
+// setScreenshotResult stores a screenshot upload result for the host
+func setScreenshotResult(m *Movie, code string, index int, result *img_uploaders.UploadResult) {
+	set := m.Uploads[code]
+	for len(set.Screenshots) <= index {
+		set.Screenshots = append(set.Screenshots, UploadedImage{})
+	}
+	set.Screenshots[index] = uploadedImageFromResult(result)
+	if set.Album == "" {
+		set.Album = result.AlbumLink
+	}
+	setMovieUploadSet(m, code, set)
+}
 
-	case <-s.cancelCtx.Done():
-		return
+// setMovieUploadSet stores the upload set of the host, creating the map if needed
+func setMovieUploadSet(m *Movie, code string, set UploadSet) {
+	if m.Uploads == nil {
+		m.Uploads = make(map[string]UploadSet)
 	}
+	m.Uploads[code] = set
 }
 
-// Ensure screenshot slice has enough capacity
-func (s *SpoilerService) ensureScreenshotSliceSize(slice *[]string, index int) {
-	for len(*slice) <= index {
-		*slice = append(*slice, "")
+// uploadedImageFromResult converts an uploader result into the stored movie format
+func uploadedImageFromResult(result *img_uploaders.UploadResult) UploadedImage {
+	return UploadedImage{
+		Thumb:  result.ThumbURL,
+		Direct: result.DirectURL,
+		Viewer: result.ViewerURL,
 	}
 }
 
-func (s *SpoilerService) generateMovieContactSheet(videoPath, tempDir string) (string, error) {
+func (s *SpoilerService) generateMovieContactSheet(movie Movie, cacheDir string) (string, error) {
+	videoPath := movie.FilePath
+
 	// Check if mtn is available before trying to use it
 	if _, err := exec.LookPath("mtn"); err != nil {
 		// Emit event that mtn is missing (only once per processing session)
-		if s.app != nil {
-			s.app.Event.Emit("error", map[string]string{
-				"message": "MTN (Movie Thumbnailer) is not installed or not found in PATH. Contact sheet generation will be skipped.",
-			})
-		}
+		s.emitError("MTN (Movie Thumbnailer) is not installed or not found in PATH. Contact sheet generation will be skipped.")
 		log.Printf("MTN not found, skipping contact sheet generation for %s", filepath.Base(videoPath))
 		return "", nil // Return empty string to skip contact sheet
 	}
 
+	// mtn names the output after the video, so give it a clean directory of its own
+	tempDir := filepath.Join(cacheDir, "contact_sheet")
+	os.RemoveAll(tempDir)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create contact sheet directory: %v", err)
+	}
+
 	// Parse user-configured MTN arguments
 	mtnArgs := s.parseMtnArgs()
 
 	// Build command arguments: start with "mtn", add user args, add output dir, add video path
 	cmdArgs := append([]string{}, mtnArgs...)
+	// An overridden range skips the start and end of the movie, taking precedence over -B and -E
+	if start, end := movie.Overrides.span(movie.Duration); start > 0 || end < movie.Duration {
+		cmdArgs = append(cmdArgs, "-B", fmt.Sprintf("%.1f", start), "-E", fmt.Sprintf("%.1f", movie.Duration-end))
+	}
 	cmdArgs = append(cmdArgs, "-O", tempDir, videoPath)
 
 	cmd := exec.CommandContext(s.cancelCtx, "mtn", cmdArgs...)
@@ -1140,15 +1414,18 @@ func (s *SpoilerService) generateMovieContactSheet(videoPath, tempDir string) (s
 	return "", fmt.Errorf("contact sheet file not found after generation - no .jpg files in %s", tempDir)
 }
 
-func (s *SpoilerService) generateScreenshot(videoPath, outputPath string, timestamp float64) error {
-	cmd := exec.CommandContext(s.cancelCtx, "ffmpeg",
+func (s *SpoilerService) generateScreenshot(videoPath, outputPath string, timestamp float64, filter string) error {
+	args := []string{
 		"-ss", fmt.Sprintf("%.2f", timestamp),
 		"-i", videoPath,
 		"-vframes", "1",
-		"-q:v", fmt.Sprintf("%d", s.settings.ScreenshotQuality),
-		"-y",
-		outputPath,
-	)
+	}
+	if filter != "" {
+		args = append(args, "-vf", filter)
+	}
+	args = append(args, s.encoderArgs(imageFormat(outputPath), 0)...)
+	args = append(args, "-y", outputPath)
+	cmd := exec.CommandContext(s.cancelCtx, "ffmpeg", args...)
 	hideWindow(cmd)
 
 	err := cmd.Run()
@@ -1195,22 +1472,22 @@ func (s *SpoilerService) GetExpandedFilePaths(paths []string) ([]string, error)
 }
 
 func (s *SpoilerService) GenerateResultForMovie(movieID string) string {
-	s.mu.RLock()
-	var movie *Movie
-	for _, m := range s.movies {
-		if m.ID == movieID {
-			mcopy := m
-			movie = &mcopy
-			break
-		}
+	movie, exists := s.getMovieByID(movieID)
+	if !exists || movie.FileName == "" {
+		return ""
 	}
-	s.mu.RUnlock()
 
-	if movie == nil || movie.FileName == "" {
-		return ""
+	preset := s.currentPreset()
+	sections, err := parseTemplateSections(TemplatePreset{Template: preset.Template, Markup: preset.Markup})
+	if err != nil {
+		return templateErrorResult(err)
 	}
 
-	return s.generateMovieSpoiler(*movie)
+	// Aggregate placeholders still describe the whole post
+	s.mu.RLock()
+	batch := newBatchContext(completedMovies(s.copyMoviesLocked()))
+	s.mu.RUnlock()
+	return renderSection(sections.movie, preset.Template, newTemplateContext(movie, batch, newMarkupRenderer(preset.Markup)))
 }
 
 func (s *SpoilerService) GenerateResult() string {
@@ -1221,152 +1498,25 @@ func (s *SpoilerService) GenerateResult() string {
 	}
 
 	// Copy the movies slice under lock, then release
-	moviesCopy := append([]Movie(nil), s.movies...)
+	moviesCopy := s.copyMoviesLocked()
 	s.mu.RUnlock()
 
-	var result strings.Builder
-
-	for _, movie := range moviesCopy {
-		if movie.FileName == "" || movie.ProcessingState != StateCompleted {
-			continue
-		}
-
-		result.WriteString(s.generateMovieSpoiler(movie))
-		result.WriteString("\n")
-	}
-
-	return result.String()
-}
-
-func (s *SpoilerService) generateMovieSpoiler(movie Movie) string {
-	// Get current template from config
-	template := s.configManager.GetCurrentTemplate()
-
-	template = s.replaceBasicPlaceholders(template, movie)
-	template = s.replaceContactSheetPlaceholders(template, movie)
-	template = s.replaceScreenshotPlaceholders(template, movie)
-	template = s.replaceParameterPlaceholders(template, movie)
-
-	return template
-}
-
-// Replace basic movie information placeholders
-func (s *SpoilerService) replaceBasicPlaceholders(template string, movie Movie) string {
-	replacements := map[string]string{
-		"%FILE_NAME%":      movie.FileName,
-		"%FILE_SIZE%":      movie.FileSize,
-		"%DURATION%":       movie.DurationFormatted,
-		"%WIDTH%":          movie.Width,
-		"%HEIGHT%":         movie.Height,
-		"%BIT_RATE%":       movie.BitRate,
-		"%VIDEO_BIT_RATE%": movie.VideoBitRate,
-		"%AUDIO_BIT_RATE%": movie.AudioBitRate,
-		"%VIDEO_CODEC%":    movie.VideoCodec,
-		"%AUDIO_CODEC%":    movie.AudioCodec,
-	}
-
-	for placeholder, value := range replacements {
-		template = strings.ReplaceAll(template, placeholder, value)
+	sections, err := parseTemplateSections(s.currentPreset())
+	if err != nil {
+		return templateErrorResult(err)
 	}
 
-	return template
-}
-
-// Replace contact sheet placeholders for all services
-func (s *SpoilerService) replaceContactSheetPlaceholders(template string, movie Movie) string {
-	// Fastpic contact sheets
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_FP%", movie.ContactSheetURL)
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_FP_BIG%", movie.ContactSheetBigURL)
-
-	// Imgbox contact sheets
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_IB%", movie.ContactSheetURLIB)
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_IB_BIG%", movie.ContactSheetBigURLIB)
-
-	// Hamster contact sheets
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_HAM%", movie.ContactSheetURLHam)
-	template = s.replaceIfNotEmpty(template, "%CONTACT_SHEET_HAM_BIG%", movie.ContactSheetBigURLHam)
-
-	return template
-}
-
-// Replace screenshot placeholders for all services
-func (s *SpoilerService) replaceScreenshotPlaceholders(template string, movie Movie) string {
-	template = s.replaceFastpicScreenshots(template, movie)
-	template = s.replaceImgboxScreenshots(template, movie)
-	template = s.replaceHamsterScreenshots(template, movie)
-	return template
-}
-
-// Replace Fastpic screenshot placeholders
-func (s *SpoilerService) replaceFastpicScreenshots(template string, movie Movie) string {
-	// Regular screenshots (BBThumb)
-	fastpicScreenshots := s.filterNonEmptyStrings(movie.ScreenshotURLs)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_FP%", "%SCREENSHOTS_FP_SPACED%", fastpicScreenshots)
-
-	// Big screenshots (BBBig)
-	fastpicScreenshotsBig := s.filterNonEmptyStrings(movie.ScreenshotBigURLs)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_FP_BIG%", "%SCREENSHOTS_FP_BIG_SPACED%", fastpicScreenshotsBig)
-
-	return template
-}
-
-// Replace Imgbox screenshot placeholders
-func (s *SpoilerService) replaceImgboxScreenshots(template string, movie Movie) string {
-	// Regular screenshots (BBThumb)
-	imgboxScreenshots := s.filterNonEmptyStrings(movie.ScreenshotURLsIB)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_IB%", "%SCREENSHOTS_IB_SPACED%", imgboxScreenshots)
-
-	// Big screenshots (BBBig)
-	imgboxScreenshotsBig := s.filterNonEmptyStrings(movie.ScreenshotBigURLsIB)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_IB_BIG%", "%SCREENSHOTS_IB_BIG_SPACED%", imgboxScreenshotsBig)
-
-	return template
-}
-
-// Replace Hamster screenshot placeholders
-func (s *SpoilerService) replaceHamsterScreenshots(template string, movie Movie) string {
-	// Regular screenshots (BBThumb)
-	hamsterScreenshots := s.filterNonEmptyStrings(movie.ScreenshotURLsHam)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_HAM%", "%SCREENSHOTS_HAM_SPACED%", hamsterScreenshots)
-
-	// Big screenshots (BBBig)
-	hamsterScreenshotsBig := s.filterNonEmptyStrings(movie.ScreenshotBigURLsHam)
-	template = s.replaceScreenshotGroup(template, "%SCREENSHOTS_HAM_BIG%", "%SCREENSHOTS_HAM_BIG_SPACED%", hamsterScreenshotsBig)
-
-	return template
-}
-
-// Replace screenshot group with both newline and space-separated versions
-func (s *SpoilerService) replaceScreenshotGroup(template, newlinePlaceholder, spacePlaceholder string, screenshots []string) string {
-	if len(screenshots) > 0 {
-		screenshotsStr := strings.Join(screenshots, "\n")
-		screenshotsSpaced := strings.Join(screenshots, " ")
-		template = strings.ReplaceAll(template, newlinePlaceholder, screenshotsStr)
-		template = strings.ReplaceAll(template, spacePlaceholder, screenshotsSpaced)
-	} else {
-		template = strings.ReplaceAll(template, newlinePlaceholder, "")
-		template = strings.ReplaceAll(template, spacePlaceholder, "")
+	// Header and footer are only added when at least one movie is completed
+	completed := completedMovies(moviesCopy)
+	if len(completed) == 0 {
+		return ""
 	}
-	return template
+	return sections.render(completed)
 }
 
-// Replace parameter placeholders with movie-specific parameters
-func (s *SpoilerService) replaceParameterPlaceholders(template string, movie Movie) string {
-	paramPattern := regexp.MustCompile(`%[^%]+%`)
-	return paramPattern.ReplaceAllStringFunc(template, func(param string) string {
-		if value, exists := movie.Params[param]; exists && value != "" {
-			return value
-		}
-		return "−"
-	})
-}
-
-// Helper function to replace placeholder only if value is not empty
-func (s *SpoilerService) replaceIfNotEmpty(template, placeholder, value string) string {
-	if value != "" {
-		return strings.ReplaceAll(template, placeholder, value)
-	}
-	return strings.ReplaceAll(template, placeholder, "")
+// templateErrorResult is shown instead of the spoiler when the template cannot be rendered
+func templateErrorResult(err error) string {
+	return fmt.Sprintf("Template error: %v", err)
 }
 
 // Helper function to filter out empty strings from slice
@@ -1390,16 +1540,8 @@ func (s *SpoilerService) UpdateSettings(settings AppSettings) {
 
 	// Save to config
 	config := s.configManager.GetConfig()
-	config.ScreenshotCount = settings.ScreenshotCount
-	config.FastpicSID = settings.FastpicSID
-	config.ScreenshotQuality = settings.ScreenshotQuality
-	config.MaxConcurrentScreenshots = settings.MaxConcurrentScreenshots
-	config.MaxConcurrentUploads = settings.MaxConcurrentUploads
-	config.MtnArgs = settings.MtnArgs
-	config.ImageMiniatureSize = settings.ImageMiniatureSize
-	config.HamsterEmail = settings.HamsterEmail
-	config.HamsterPassword = settings.HamsterPassword
-	config.SaveMediaDirectory = settings.SaveMediaDirectory
+	applySettingsToConfig(&config, settings)
+	s.setHostConfigs(config.Hosts)
 
 	if err := s.configManager.UpdateConfig(config); err != nil {
 		log.Printf("Failed to save settings: %v", err)
@@ -1410,13 +1552,7 @@ func (s *SpoilerService) UpdateSettings(settings AppSettings) {
 
 // SelectSaveMediaDirectory opens a directory picker dialog and returns the selected path
 func (s *SpoilerService) SelectSaveMediaDirectory() (string, error) {
-	// Use Wails OpenFileDialog with CanChooseDirectories to pick a folder
-	selectedDir, err := s.app.Dialog.OpenFile().
-		SetTitle("Select Save Media Directory").
-		CanChooseDirectories(true).
-		CanChooseFiles(false).
-		CanCreateDirectories(true).
-		PromptForSingleSelection()
+	selectedDir, err := s.dialogs.SelectDirectory("Select Save Media Directory")
 
 	if err != nil {
 		return "", err
@@ -1473,7 +1609,7 @@ func (s *SpoilerService) saveMediaToDirectory(movie Movie, contactSheetPath stri
 
 	// Copy contact sheet if it exists
 	if contactSheetPath != "" {
-		destPath := filepath.Join(movieDir, "contact_sheet.jpg")
+		destPath := filepath.Join(movieDir, "contact_sheet"+filepath.Ext(contactSheetPath))
 		if err := copyFile(contactSheetPath, destPath); err != nil {
 			log.Printf("Failed to save contact sheet for %s: %v", movie.FileName, err)
 		} else {
@@ -1486,12 +1622,19 @@ func (s *SpoilerService) saveMediaToDirectory(movie Movie, contactSheetPath stri
 		if screenshotPath == "" {
 			continue
 		}
-		destPath := filepath.Join(movieDir, fmt.Sprintf("screenshot_%02d.jpg", i+1))
+		destPath := filepath.Join(movieDir, fmt.Sprintf("screenshot_%02d%s", i+1, filepath.Ext(screenshotPath)))
 		if err := copyFile(screenshotPath, destPath); err != nil {
 			log.Printf("Failed to save screenshot %d for %s: %v", i+1, movie.FileName, err)
 		} else {
 			log.Printf("Saved screenshot to %s", destPath)
 		}
+
+		// The untonemapped copy of an HDR screenshot is saved next to it
+		if s.keepsUntonemapped(movie) {
+			if err := copyFile(untonemappedPath(screenshotPath), untonemappedPath(destPath)); err != nil {
+				log.Printf("Failed to save untonemapped screenshot %d for %s: %v", i+1, movie.FileName, err)
+			}
+		}
 	}
 
 	return nil
@@ -1552,14 +1695,29 @@ func (s *SpoilerService) GetTemplate() string {
 	return s.configManager.GetCurrentTemplate()
 }
 
-func (s *SpoilerService) SetTemplate(template string) {
-	// Update the current preset's template
+// SetTemplate saves the template of the current preset. Templates that do not parse are rejected
+// with a *TemplateError.
+func (s *SpoilerService) SetTemplate(template string) error {
+	current := s.configManager.GetCurrentPreset()
+	return s.SetTemplateSections(current.Header, template, current.Footer)
+}
+
+// SetTemplateSections saves the header, per-movie template and footer of the current preset.
+// Sections that do not parse are rejected with a *TemplateError.
+func (s *SpoilerService) SetTemplateSections(header, template, footer string) error {
+	if _, err := parseTemplateSections(TemplatePreset{Header: header, Template: template, Footer: footer}); err != nil {
+		return err
+	}
+
+	// Update the current preset's sections
 	config := s.configManager.GetConfig()
 
 	// Find and update current preset
 	for i, preset := range config.TemplatePresets {
 		if preset.ID == config.CurrentPresetID {
+			config.TemplatePresets[i].Header = header
 			config.TemplatePresets[i].Template = template
+			config.TemplatePresets[i].Footer = footer
 			break
 		}
 	}
@@ -1567,6 +1725,15 @@ func (s *SpoilerService) SetTemplate(template string) {
 	if err := s.configManager.UpdateConfig(config); err != nil {
 		log.Printf("Failed to save template: %v", err)
 	}
+	return nil
+}
+
+// CheckTemplate parses the template and returns the position of the first syntax error, or nil
+func (s *SpoilerService) CheckTemplate(template string) *TemplateError {
+	if _, err := parseSpoilerTemplate(template); err != nil {
+		return err.(*TemplateError)
+	}
+	return nil
 }
 
 func (s *SpoilerService) GetTemplatePresets() []TemplatePreset {
@@ -1579,19 +1746,24 @@ func (s *SpoilerService) GetCurrentPresetID() string {
 	return config.CurrentPresetID
 }
 
-func (s *SpoilerService) SaveTemplatePreset(name, template string) (TemplatePreset, error) {
+// SaveTemplatePreset saves a new preset with the name, sections and markup of the given one;
+// header, footer and markup may be empty
+func (s *SpoilerService) SaveTemplatePreset(preset TemplatePreset) (TemplatePreset, error) {
+	name, template := preset.Name, preset.Template
 	if name == "" {
 		return TemplatePreset{}, fmt.Errorf("preset name cannot be empty")
 	}
 	if template == "" {
 		return TemplatePreset{}, fmt.Errorf("template cannot be empty")
 	}
-
-	preset := TemplatePreset{
-		ID:       "", // Will be generated in config service
-		Name:     name,
-		Template: template,
+	if err := validateMarkup(preset.Markup); err != nil {
+		return TemplatePreset{}, err
 	}
+	if _, err := parseTemplateSections(preset); err != nil {
+		return TemplatePreset{}, err
+	}
+
+	preset.ID = "" // Will be generated in config service
 
 	err := s.configManager.SaveTemplatePreset(preset)
 	if err != nil {
@@ -1609,6 +1781,22 @@ func (s *SpoilerService) SaveTemplatePreset(name, template string) (TemplatePres
 	return preset, nil
 }
 
+// SetTemplateMarkup sets the image markup of the current preset: bbcode, markdown, html or json
+func (s *SpoilerService) SetTemplateMarkup(markup string) error {
+	if err := validateMarkup(markup); err != nil {
+		return err
+	}
+
+	config := s.configManager.GetConfig()
+	for i, preset := range config.TemplatePresets {
+		if preset.ID == config.CurrentPresetID {
+			config.TemplatePresets[i].Markup = markup
+			break
+		}
+	}
+	return s.configManager.UpdateConfig(config)
+}
+
 func (s *SpoilerService) DeleteTemplatePreset(presetID string) error {
 	return s.configManager.DeleteTemplatePreset(presetID)
 }
@@ -0,0 +1,263 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ffprobeStream is a stream as printed by ffprobe -show_streams
+type ffprobeStream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type"`
+	CodecName     string            `json:"codec_name"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Duration      string            `json:"duration"`
+	BitRate       string            `json:"bit_rate"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	AvgFrameRate  string            `json:"avg_frame_rate"`
+	SampleRate    string            `json:"sample_rate"`
+	Channels      int               `json:"channels"`
+	ChannelLayout string            `json:"channel_layout"`
+	Disposition   map[string]int    `json:"disposition"`
+	Tags          map[string]string `json:"tags"`
+
+	PixFmt           string            `json:"pix_fmt"`
+	BitsPerRawSample string            `json:"bits_per_raw_sample"`
+	ColorRange       string            `json:"color_range"`
+	ColorSpace       string            `json:"color_space"`
+	ColorTransfer    string            `json:"color_transfer"`
+	ColorPrimaries   string            `json:"color_primaries"`
+	SideDataList     []ffprobeSideData `json:"side_data_list"`
+
+	SampleAspectRatio  string `json:"sample_aspect_ratio"`
+	DisplayAspectRatio string `json:"display_aspect_ratio"`
+}
+
+// streamPrefixes maps the stream types that are kept to their placeholder prefix
+var streamPrefixes = []struct{ streamType, prefix string }{
+	{"video", "VIDEO"},
+	{"audio", "AUDIO"},
+	{"subtitle", "SUBTITLE"},
+}
+
+// indexedPlaceholderPattern matches per-track placeholders such as AUDIO_2_LANG
+var indexedPlaceholderPattern = regexp.MustCompile(`^(VIDEO|AUDIO|SUBTITLE)_\d+_`)
+
+// streamPrefix returns the placeholder prefix of a stream type, or "" if streams of the type are not kept
+func streamPrefix(streamType string) string {
+	for _, p := range streamPrefixes {
+		if p.streamType == streamType {
+			return p.prefix
+		}
+	}
+	return ""
+}
+
+// tag returns a stream tag by case-insensitive key; containers differ in tag case
+func (s ffprobeStream) tag(key string) string {
+	if value, ok := s.Tags[key]; ok {
+		return value
+	}
+	for k, value := range s.Tags {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// bitRate returns the stream bitrate, falling back to the BPS tag Matroska muxers write
+func (s ffprobeStream) bitRate() string {
+	if s.BitRate != "" {
+		return s.BitRate
+	}
+	return s.tag("BPS")
+}
+
+// mediaStream converts video, audio and subtitle streams. Cover art and data streams are skipped.
+func (s ffprobeStream) mediaStream() (MediaStream, bool) {
+	if streamPrefix(s.CodecType) == "" || s.CodecType == "video" && s.Disposition["attached_pic"] == 1 {
+		return MediaStream{}, false
+	}
+
+	stream := MediaStream{
+		Index:         s.Index,
+		Type:          s.CodecType,
+		Codec:         s.CodecName,
+		Language:      s.tag("language"),
+		Title:         s.tag("title"),
+		Default:       s.Disposition["default"] == 1,
+		Forced:        s.Disposition["forced"] == 1,
+		BitRate:       s.bitRate(),
+		Width:         s.Width,
+		Height:        s.Height,
+		Channels:      s.Channels,
+		ChannelLayout: s.ChannelLayout,
+		SampleRate:    s.SampleRate,
+	}
+	if s.CodecType == "video" {
+		stream.FrameRate = s.RFrameRate
+	}
+	return stream, true
+}
+
+// Flags returns the dispositions of the stream, e.g. "default, forced"
+func (s MediaStream) Flags() string {
+	var flags []string
+	if s.Default {
+		flags = append(flags, "default")
+	}
+	if s.Forced {
+		flags = append(flags, "forced")
+	}
+	return strings.Join(flags, ", ")
+}
+
+// Channel returns the channel layout, or the formatted channel count if the layout is unknown
+func (s MediaStream) Channel() string {
+	if s.ChannelLayout != "" {
+		return s.ChannelLayout
+	}
+	if s.Channels > 0 {
+		return formatChannels(strconv.Itoa(s.Channels))
+	}
+	return ""
+}
+
+// Summary describes the stream on one line, e.g. eng / ac3 / 5.1(side) / 48.0 kHz / 448 kbps / "Dub" / default
+func (s MediaStream) Summary() string {
+	language := s.Language
+	if language == "" {
+		language = "und"
+	}
+	parts := []string{language, s.Codec}
+
+	switch s.Type {
+	case "video":
+		if s.Width > 0 && s.Height > 0 {
+			parts = append(parts, fmt.Sprintf("%dx%d", s.Width, s.Height))
+		}
+		if fps := parseFrameRate(s.FrameRate); fps > 0 {
+			parts = append(parts, fmt.Sprintf("%.3f fps", fps))
+		}
+	case "audio":
+		parts = append(parts, s.Channel(), formatSampleRate(s.SampleRate))
+	}
+	parts = append(parts, FormatBitRate(s.BitRate))
+	if s.Title != "" {
+		parts = append(parts, strconv.Quote(s.Title))
+	}
+	parts = append(parts, s.Flags())
+
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, " / ")
+}
+
+// streamsOfType returns the streams of the type in file order
+func streamsOfType(streams []MediaStream, streamType string) []MediaStream {
+	var matching []MediaStream
+	for _, stream := range streams {
+		if stream.Type == streamType {
+			matching = append(matching, stream)
+		}
+	}
+	return matching
+}
+
+// streamPlaceholders returns the track placeholders: counts and summaries of every type are
+// always present, per-track values such as AUDIO_2_LANG only when the track has them
+func streamPlaceholders(streams []MediaStream) map[string]string {
+	values := make(map[string]string)
+	for _, p := range streamPrefixes {
+		tracks := streamsOfType(streams, p.streamType)
+		values[p.prefix+"_COUNT"] = strconv.Itoa(len(tracks))
+
+		var lines []string
+		for i, track := range tracks {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, track.Summary()))
+
+			prefix := fmt.Sprintf("%s_%d_", p.prefix, i+1)
+			trackValues := map[string]string{
+				"CODEC":    track.Codec,
+				"LANG":     track.Language,
+				"TITLE":    track.Title,
+				"FLAGS":    track.Flags(),
+				"BIT_RATE": FormatBitRate(track.BitRate),
+				"SUMMARY":  track.Summary(),
+			}
+			switch p.streamType {
+			case "video":
+				if track.Width > 0 && track.Height > 0 {
+					trackValues["RESOLUTION"] = fmt.Sprintf("%dx%d", track.Width, track.Height)
+				}
+				if fps := parseFrameRate(track.FrameRate); fps > 0 {
+					trackValues["FPS"] = fmt.Sprintf("%.3f", fps)
+				}
+			case "audio":
+				trackValues["CHANNELS"] = track.Channel()
+				trackValues["SAMPLE_RATE"] = formatSampleRate(track.SampleRate)
+			}
+			for name, value := range trackValues {
+				if value != "" {
+					values[prefix+name] = value
+				}
+			}
+		}
+		values[p.prefix+"_SUMMARY"] = strings.Join(lines, "\n")
+	}
+	return values
+}
+
+// trackPlaceholders describes the track placeholders, listing only the first track of each
+// type; _2_, _3_ and so on work the same way
+func trackPlaceholders() []PlaceholderInfo {
+	var placeholders []PlaceholderInfo
+	for _, p := range streamPrefixes {
+		name := p.streamType
+		placeholders = append(placeholders,
+			PlaceholderInfo{Name: p.prefix + "_COUNT", Category: categoryTracks, Description: fmt.Sprintf("Number of %s tracks", name)},
+			PlaceholderInfo{Name: p.prefix + "_SUMMARY", Category: categoryTracks, Description: fmt.Sprintf("One summary line per %s track", name)},
+		)
+
+		type trackField struct{ suffix, description string }
+		fields := []trackField{
+			{"CODEC", "codec"},
+			{"LANG", "language code"},
+			{"TITLE", "title"},
+			{"FLAGS", "default and forced flags"},
+			{"BIT_RATE", "bitrate"},
+			{"SUMMARY", "summary line"},
+		}
+		switch p.streamType {
+		case "video":
+			fields = append(fields, trackField{"RESOLUTION", "resolution"}, trackField{"FPS", "framerate"})
+		case "audio":
+			fields = append(fields, trackField{"CHANNELS", "channel layout"}, trackField{"SAMPLE_RATE", "sample rate"})
+		}
+		for _, field := range fields {
+			placeholders = append(placeholders, PlaceholderInfo{
+				Name:        fmt.Sprintf("%s_1_%s", p.prefix, field.suffix),
+				Category:    categoryTracks,
+				Description: fmt.Sprintf("First %s track %s; use _2_, _3_ for the others", name, field.description),
+			})
+		}
+	}
+	return placeholders
+}
+
+// normalizeTrackPlaceholder maps a per-track placeholder to its first-track catalog name,
+// e.g. AUDIO_2_LANG to AUDIO_1_LANG
+func normalizeTrackPlaceholder(name string) string {
+	if match := indexedPlaceholderPattern.FindStringSubmatch(name); match != nil {
+		return match[1] + "_1_" + name[len(match[0]):]
+	}
+	return name
+}
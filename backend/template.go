@@ -0,0 +1,412 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"spoilr/backend/img_uploaders"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// templateName names parsed spoiler templates in error messages
+const templateName = "spoiler"
+
+// missingPlaceholderValue replaces placeholders that have no value for a movie
+const missingPlaceholderValue = "−"
+
+// legacyPlaceholderPattern matches %PLACEHOLDER% tokens of the original template format
+var legacyPlaceholderPattern = regexp.MustCompile(`%[^%\s]+%`)
+
+// templateErrorPattern splits text/template errors into line, optional column and message
+var templateErrorPattern = regexp.MustCompile(`(?s)^template: [^:]*:(\d+)(?::(\d+))?: (.*)$`)
+
+// templateErrorHints finds the quoted token or <node> an error message refers to
+var templateErrorHints = regexp.MustCompile(`<([^>]+)>|"([^"]+)"`)
+
+// mediaInfoKeys are the raw ffprobe values GetVideoMediaInfo collects per section,
+// available as %General@key%, %Video@key% and %Audio@key%
+var mediaInfoKeys = map[string][]string{
+	"General": {"duration", "size", "bit_rate"},
+	"Video": {
+		"codec_name", "width", "height", "duration", "bit_rate", "r_frame_rate", "fps_decimal", "avg_frame_rate",
+		"sample_aspect_ratio", "display_aspect_ratio", "display_width", "display_height",
+		"pix_fmt", "bit_depth", "color_range", "color_space", "color_transfer", "color_primaries",
+		"hdr_format", "mastering_display", "max_cll", "max_fall",
+	},
+	"Audio": {"codec_name", "duration", "bit_rate", "sample_rate", "channels", "channel_layout"},
+}
+
+// templateFuncs are the helpers available to spoiler templates
+var templateFuncs = template.FuncMap{
+	"join":     templateJoin,
+	"pad":      templatePad,
+	"humanize": templateHumanize,
+	"size":     templateSize,
+	"bitrate":  templateBitRate,
+	"duration": templateDuration,
+	"default":  templateDefault,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"json":     templateJSON,
+}
+
+// templateContext is the data spoiler templates are executed with. Movie and batch fields are
+// promoted, so {{.FileName}}, {{.EpisodeCount}} and {{range .Uploads.FP.Screenshots}} work
+// directly. Headers and footers get an empty Movie.
+type templateContext struct {
+	Movie
+	batchContext
+	Uploads     map[string]renderedUploadSet // Movie uploads with embed codes in the preset markup
+	Info        MediaInfo                    // Raw ffprobe values, e.g. {{.Info.Video.codec_name}}
+	AudioTracks []map[string]string          // Raw values of every audio stream
+
+	// Streams by type, e.g. {{range .AudioStreams}}{{.Language}} {{.Summary}}{{end}}
+	VideoStreams    []MediaStream
+	AudioStreams    []MediaStream
+	SubtitleStreams []MediaStream
+
+	placeholders map[string]string
+}
+
+// Placeholder returns the value of a %PLACEHOLDER% by name, or "−" if the movie has none.
+// Legacy tokens are rewritten into calls of this method.
+func (c templateContext) Placeholder(name string) string {
+	if value, ok := c.placeholders[name]; ok {
+		return value
+	}
+	return missingPlaceholderValue
+}
+
+// parseSpoilerTemplate parses a template in text/template syntax. %PLACEHOLDER% tokens outside
+// of actions are rewritten into Placeholder calls, so legacy presets keep working.
+func parseSpoilerTemplate(source string) (*template.Template, error) {
+	tmpl, err := template.New(templateName).Funcs(templateFuncs).Parse(rewriteLegacyPlaceholders(source))
+	if err != nil {
+		return nil, newTemplateError(source, err)
+	}
+	return tmpl, nil
+}
+
+// rewriteLegacyPlaceholders turns %NAME% into {{$.Placeholder "NAME"}} in the text between
+// actions. No lines are added, so parse errors keep their line numbers.
+func rewriteLegacyPlaceholders(source string) string {
+	var b strings.Builder
+	for _, segment := range templateSegments(source) {
+		text := source[segment.start:segment.end]
+		if segment.action {
+			b.WriteString(text)
+			continue
+		}
+		b.WriteString(legacyPlaceholderPattern.ReplaceAllStringFunc(text, func(token string) string {
+			return fmt.Sprintf("{{$.Placeholder %q}}", strings.Trim(token, "%"))
+		}))
+	}
+	return b.String()
+}
+
+// templateSegment is a byte range of a template that is either plain text or a {{...}} action
+type templateSegment struct {
+	start, end int
+	action     bool
+}
+
+// templateSegments splits a template into text and actions. An unclosed action runs to the end.
+func templateSegments(source string) []templateSegment {
+	var segments []templateSegment
+	for pos := 0; pos < len(source); {
+		start := strings.Index(source[pos:], "{{")
+		if start < 0 {
+			segments = append(segments, templateSegment{start: pos, end: len(source)})
+			break
+		}
+		start += pos
+		if start > pos {
+			segments = append(segments, templateSegment{start: pos, end: start})
+		}
+
+		end := len(source)
+		if i := strings.Index(source[start:], "}}"); i >= 0 {
+			end = start + i + len("}}")
+		}
+		segments = append(segments, templateSegment{start: start, end: end, action: true})
+		pos = end
+	}
+	return segments
+}
+
+// executeSpoilerTemplate renders a template section with the context
+func executeSpoilerTemplate(tmpl *template.Template, source string, ctx templateContext) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ctx); err != nil {
+		return "", newTemplateError(source, err)
+	}
+	return b.String(), nil
+}
+
+// newTemplateContext returns the context of a movie block with images in the markup. Movie
+// placeholders take precedence over the aggregate ones of the batch.
+func newTemplateContext(movie Movie, batch batchContext, markup markupRenderer) templateContext {
+	info := mediaInfoFromParams(movie.Params)
+	ctx := templateContext{
+		Movie:        movie,
+		batchContext: batch,
+		Uploads:      renderUploads(markup, movie.Uploads),
+		Info:         info,
+		placeholders: batch.placeholders(),
+
+		VideoStreams:    streamsOfType(movie.Streams, "video"),
+		AudioStreams:    streamsOfType(movie.Streams, "audio"),
+		SubtitleStreams: streamsOfType(movie.Streams, "subtitle"),
+	}
+	for name, value := range legacyPlaceholders(movie, markup) {
+		ctx.placeholders[name] = value
+	}
+
+	// Movies analyzed before streams were kept only have the raw values of one audio track
+	for _, stream := range ctx.AudioStreams {
+		ctx.AudioTracks = append(ctx.AudioTracks, audioTrackValues(stream))
+	}
+	if len(ctx.AudioTracks) == 0 && len(info.Audio) > 0 {
+		ctx.AudioTracks = []map[string]string{info.Audio}
+	}
+	return ctx
+}
+
+// audioTrackValues returns an audio stream in the ffprobe key format of .Info.Audio
+func audioTrackValues(stream MediaStream) map[string]string {
+	values := map[string]string{
+		"codec_name":     stream.Codec,
+		"bit_rate":       stream.BitRate,
+		"sample_rate":    stream.SampleRate,
+		"channel_layout": stream.ChannelLayout,
+		"language":       stream.Language,
+		"title":          stream.Title,
+	}
+	if stream.Channels > 0 {
+		values["channels"] = strconv.Itoa(stream.Channels)
+	}
+	return values
+}
+
+// newBatchTemplateContext returns the context of a header or footer, where only the
+// aggregate placeholders have values
+func newBatchTemplateContext(batch batchContext) templateContext {
+	return templateContext{
+		batchContext: batch,
+		Uploads:      make(map[string]renderedUploadSet),
+		Info:         mediaInfoFromParams(nil),
+		placeholders: batch.placeholders(),
+	}
+}
+
+// mediaInfoFromParams restores the raw ffprobe values stored as %General@key% style params
+func mediaInfoFromParams(params map[string]string) MediaInfo {
+	info := MediaInfo{
+		General: make(map[string]string),
+		Video:   make(map[string]string),
+		Audio:   make(map[string]string),
+	}
+	sections := map[string]map[string]string{
+		"General": info.General,
+		"Video":   info.Video,
+		"Audio":   info.Audio,
+	}
+	for param, value := range params {
+		section, key, ok := strings.Cut(strings.Trim(param, "%"), "@")
+		if target, known := sections[section]; ok && known {
+			target[key] = value
+		}
+	}
+	return info
+}
+
+// legacyPlaceholders returns the values of the %PLACEHOLDER% tokens by name, with images in the
+// markup. Basic and image placeholders are always present, params only when they have a value.
+func legacyPlaceholders(movie Movie, markup markupRenderer) map[string]string {
+	values := map[string]string{
+		"FILE_NAME":      movie.FileName,
+		"FILE_SIZE":      movie.FileSize,
+		"DURATION":       movie.DurationFormatted,
+		"WIDTH":          movie.Width,
+		"HEIGHT":         movie.Height,
+		"BIT_RATE":       movie.BitRate,
+		"VIDEO_BIT_RATE": movie.VideoBitRate,
+		"AUDIO_BIT_RATE": movie.AudioBitRate,
+		"VIDEO_CODEC":    movie.VideoCodec,
+		"AUDIO_CODEC":    movie.AudioCodec,
+	}
+
+	for _, host := range img_uploaders.Hosts() {
+		uploads := movie.Uploads[host.Code]
+		values["CONTACT_SHEET_"+host.Code] = renderImage(markup, uploads.ContactSheet, false)
+		values["CONTACT_SHEET_"+host.Code+"_BIG"] = renderImage(markup, uploads.ContactSheet, true)
+
+		prefix := "SCREENSHOTS_" + host.Code
+		values[prefix] = renderImages(markup, uploads.Screenshots, false, false)
+		values[prefix+"_SPACED"] = renderImages(markup, uploads.Screenshots, false, true)
+		values[prefix+"_BIG"] = renderImages(markup, uploads.Screenshots, true, false)
+		values[prefix+"_BIG_SPACED"] = renderImages(markup, uploads.Screenshots, true, true)
+	}
+	values["CHAPTER_COUNT"] = strconv.Itoa(len(movie.Chapters))
+	values["CHAPTERS"] = chapterList(movie.Chapters)
+	for name, value := range streamPlaceholders(movie.Streams) {
+		values[name] = value
+	}
+
+	for param, value := range movie.Params {
+		name := strings.Trim(param, "%")
+		if _, exists := values[name]; !exists && value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// newTemplateError converts a text/template error into a TemplateError positioned in the
+// original source. Columns are found from the token the message names, because actions
+// inserted for legacy placeholders shift the columns text/template reports.
+func newTemplateError(source string, err error) *TemplateError {
+	var execErr template.ExecError
+	message := err.Error()
+	if errors.As(err, &execErr) {
+		message = execErr.Err.Error()
+	}
+
+	match := templateErrorPattern.FindStringSubmatch(message)
+	if match == nil {
+		return &TemplateError{Line: 1, Column: 1, Message: message}
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	message = strings.TrimPrefix(match[3], fmt.Sprintf("executing %q ", templateName))
+
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return &TemplateError{Line: max(line, 1), Column: 1, Message: message}
+	}
+	return &TemplateError{Line: line, Column: templateErrorColumn(lines[line-1], message, column), Message: message}
+}
+
+// templateErrorColumn returns the 1-based column of the token the message refers to, or of
+// the first action on the line if the message names none
+func templateErrorColumn(line, message string, reported int) int {
+	for _, hint := range templateErrorHints.FindAllStringSubmatch(message, -1) {
+		token := hint[1] + hint[2]
+		if i := strings.Index(line, token); i >= 0 {
+			return utf8.RuneCountInString(line[:i]) + 1
+		}
+	}
+	if reported > 0 && reported <= utf8.RuneCountInString(line) {
+		return reported
+	}
+	if i := strings.Index(line, "{{"); i >= 0 {
+		return utf8.RuneCountInString(line[:i]) + 1
+	}
+	return 1
+}
+
+// templateJoin joins the elements of a slice with the separator: {{join ", " .AudioTracks}}
+func templateJoin(sep string, items any) string {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(items)
+	}
+
+	parts := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if part := fmt.Sprint(v.Index(i).Interface()); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// templatePad pads the value with spaces to the width, aligning it right if the width is negative
+func templatePad(width int, value any) string {
+	s := fmt.Sprint(value)
+	padding := int(math.Abs(float64(width))) - utf8.RuneCountInString(s)
+	if padding <= 0 {
+		return s
+	}
+	if width < 0 {
+		return strings.Repeat(" ", padding) + s
+	}
+	return s + strings.Repeat(" ", padding)
+}
+
+// templateHumanize shortens a number with a k, M or G suffix, e.g. 5200000 becomes 5.2M
+func templateHumanize(value any) string {
+	n, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	for _, unit := range []struct {
+		size   float64
+		suffix string
+	}{{1e9, "G"}, {1e6, "M"}, {1e3, "k"}} {
+		if math.Abs(n) >= unit.size {
+			return strconv.FormatFloat(math.Round(n/unit.size*10)/10, 'f', -1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// templateSize formats a byte count, e.g. {{size .Info.General.size}}
+func templateSize(value any) string {
+	n, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	return FormatFileSize(int64(n))
+}
+
+// templateBitRate formats bits per second, e.g. {{bitrate .Info.Audio.bit_rate}}
+func templateBitRate(value any) string {
+	n, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	return FormatBitRate(strconv.FormatFloat(n, 'f', -1, 64))
+}
+
+// templateDuration formats seconds as [h:]mm:ss, e.g. {{duration .Info.General.duration}}
+func templateDuration(value any) string {
+	n, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	return FormatDuration(time.Duration(n * float64(time.Second)))
+}
+
+// templateDefault returns the fallback if the value is empty: {{.AudioCodec | default "none"}}
+func templateDefault(fallback string, value any) string {
+	if s := fmt.Sprint(value); value != nil && s != "" {
+		return s
+	}
+	return fallback
+}
+
+// templateNumber converts numbers and numeric strings to float64
+func templateNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,256 @@
+package backend
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"spoilr/backend/img_uploaders"
+	"strings"
+	"unicode/utf8"
+)
+
+// placeholderCallPattern matches placeholders read by name in actions: {{$.Placeholder "NAME"}}
+var placeholderCallPattern = regexp.MustCompile(`Placeholder\s+"([^"]*)"`)
+
+// uploadsHostPattern matches the host codes actions read uploads of: .Uploads.FP or index .Uploads "FP"
+var uploadsHostPattern = regexp.MustCompile(`\.Uploads\.(\w+)|index \.Uploads "(\w+)"`)
+
+// bbCodeTagPattern matches opening and closing BBCode tags that must come in pairs
+var bbCodeTagPattern = regexp.MustCompile(`(?i)\[(/?)(spoiler|quote|code|center|url|b|i|u|s)(?:=[^\]\n]*)?\]`)
+
+// templateReference is a placeholder or host code used in a template, with its byte offset
+type templateReference struct {
+	name   string
+	offset int
+}
+
+// ValidateTemplate lints a template: syntax errors, unknown placeholders, placeholders that stay
+// empty with the current settings, unbalanced BBCode tags and the uploads the template triggers
+func (s *SpoilerService) ValidateTemplate(template string) TemplateValidation {
+	validation := TemplateValidation{
+		UnknownPlaceholders: []PlaceholderIssue{},
+		EmptyPlaceholders:   []PlaceholderIssue{},
+		UnbalancedTags:      []TemplateError{},
+	}
+
+	// Rendering an empty movie catches references to fields that do not exist
+	if tmpl, err := parseSpoilerTemplate(template); err != nil {
+		validation.Error = err.(*TemplateError)
+	} else if _, err := executeSpoilerTemplate(tmpl, template, newTemplateContext(Movie{}, newBatchContext(nil), bbCodeMarkup{})); err != nil {
+		validation.Error = err.(*TemplateError)
+	}
+
+	placeholders, hostCodes := templateReferences(template)
+	known := make(map[string]bool)
+	catalog := make(map[string]PlaceholderInfo)
+	for _, placeholder := range s.GetPlaceholderCatalog() {
+		name := strings.Trim(placeholder.Name, "%")
+		known[name] = true
+		catalog[name] = placeholder
+	}
+	reported := make(map[string]bool)
+	for _, ref := range placeholders {
+		if reported[ref.name] {
+			continue
+		}
+		reported[ref.name] = true
+
+		line, column := templatePosition(template, ref.offset)
+		issue := PlaceholderIssue{Name: "%" + ref.name + "%", Line: line, Column: column}
+		if name := normalizeTrackPlaceholder(ref.name); !known[name] {
+			issue.Message = "unknown placeholder"
+			if suggestion := closestPlaceholder(ref.name, known); suggestion != "" {
+				issue.Message += fmt.Sprintf(", did you mean %%%s%%?", suggestion)
+			}
+			validation.UnknownPlaceholders = append(validation.UnknownPlaceholders, issue)
+		} else if reason := s.emptyPlaceholderReason(catalog[name]); reason != "" {
+			issue.Message = reason
+			validation.EmptyPlaceholders = append(validation.EmptyPlaceholders, issue)
+		}
+	}
+	for _, ref := range hostCodes {
+		if _, ok := img_uploaders.Lookup(ref.name); ok || reported[".Uploads."+ref.name] {
+			continue
+		}
+		reported[".Uploads."+ref.name] = true
+
+		line, column := templatePosition(template, ref.offset)
+		validation.UnknownPlaceholders = append(validation.UnknownPlaceholders, PlaceholderIssue{
+			Name:    ".Uploads." + ref.name,
+			Line:    line,
+			Column:  column,
+			Message: "unknown image host",
+		})
+	}
+
+	validation.UnbalancedTags = unbalancedBBCodeTags(template)
+
+	validation.Hosts = s.templateHostUsage(template)
+
+	return validation
+}
+
+// templateHostUsage lists the hosts the template uploads to, in registry order
+func (s *SpoilerService) templateHostUsage(template string) []TemplateHostUsage {
+	usage := []TemplateHostUsage{}
+	requirements := s.uploaderRequirements(template)
+	for _, host := range img_uploaders.Hosts() {
+		if req, ok := requirements.Hosts[host.Code]; ok {
+			usage = append(usage, TemplateHostUsage{
+				Code:         host.Code,
+				Name:         host.Name,
+				ContactSheet: req.ContactSheet,
+				Screenshots:  req.Screenshots,
+			})
+		}
+	}
+	return usage
+}
+
+// templateReferences returns the placeholders and the upload host codes a template uses,
+// in source order
+func templateReferences(source string) (placeholders, hostCodes []templateReference) {
+	for _, segment := range templateSegments(source) {
+		text := source[segment.start:segment.end]
+		if !segment.action {
+			for _, loc := range legacyPlaceholderPattern.FindAllStringIndex(text, -1) {
+				name := strings.Trim(text[loc[0]:loc[1]], "%")
+				placeholders = append(placeholders, templateReference{name, segment.start + loc[0]})
+			}
+			continue
+		}
+
+		for _, loc := range placeholderCallPattern.FindAllStringSubmatchIndex(text, -1) {
+			placeholders = append(placeholders, templateReference{text[loc[2]:loc[3]], segment.start + loc[2]})
+		}
+		for _, loc := range uploadsHostPattern.FindAllStringSubmatchIndex(text, -1) {
+			group := 2
+			if loc[group] < 0 {
+				group = 4
+			}
+			hostCodes = append(hostCodes, templateReference{text[loc[group]:loc[group+1]], segment.start + loc[group]})
+		}
+	}
+	return placeholders, hostCodes
+}
+
+// emptyPlaceholderReason explains why a placeholder will be empty for every movie with the
+// current settings, or returns "" if it can have a value
+func (s *SpoilerService) emptyPlaceholderReason(placeholder PlaceholderInfo) string {
+	host, ok := img_uploaders.Lookup(placeholder.Host)
+	if !ok {
+		return ""
+	}
+
+	if len(s.hostFilter) > 0 && !slices.Contains(s.hostFilter, host.Code) {
+		return fmt.Sprintf("%s is not among the selected hosts", host.Name)
+	}
+	if host.Code == "HAM" && (s.settings.HamsterEmail == "" || s.settings.HamsterPassword == "") {
+		return "Hamster email and password are not set"
+	}
+	switch placeholder.Artifact {
+	case artifactScreenshots:
+		if s.settings.ScreenshotCount <= 0 && !s.settings.ScreenshotPerChapter {
+			return "screenshot count is 0"
+		}
+	case artifactContactSheet:
+		if _, err := exec.LookPath("mtn"); err != nil {
+			return "mtn is not installed"
+		}
+	}
+	return ""
+}
+
+// unbalancedBBCodeTags returns closing tags without an opening tag and opening tags that are
+// never closed
+func unbalancedBBCodeTags(source string) []TemplateError {
+	type openTag struct {
+		name   string
+		offset int
+	}
+	var open []openTag
+	issues := []TemplateError{}
+
+	for _, loc := range bbCodeTagPattern.FindAllStringSubmatchIndex(source, -1) {
+		name := strings.ToLower(source[loc[4]:loc[5]])
+		if loc[3] == loc[2] {
+			open = append(open, openTag{name, loc[0]})
+			continue
+		}
+
+		// Close the innermost matching tag; tags opened inside it are left unclosed
+		matched := -1
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i].name == name {
+				matched = i
+				break
+			}
+		}
+		if matched < 0 {
+			line, column := templatePosition(source, loc[0])
+			issues = append(issues, TemplateError{Line: line, Column: column, Message: fmt.Sprintf("[/%s] without opening [%s]", name, name)})
+			continue
+		}
+		for _, tag := range open[matched+1:] {
+			line, column := templatePosition(source, tag.offset)
+			issues = append(issues, TemplateError{Line: line, Column: column, Message: fmt.Sprintf("[%s] closed by outer [/%s]", tag.name, name)})
+		}
+		open = open[:matched]
+	}
+
+	for _, tag := range open {
+		line, column := templatePosition(source, tag.offset)
+		issues = append(issues, TemplateError{Line: line, Column: column, Message: fmt.Sprintf("[%s] is never closed", tag.name)})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// templatePosition converts a byte offset into a 1-based line and column
+func templatePosition(source string, offset int) (int, int) {
+	before := source[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// closestPlaceholder returns the known placeholder most similar to name, or "" if none is close
+func closestPlaceholder(name string, known map[string]bool) string {
+	best, bestDistance := "", 4 // Suggest only up to three edits away
+	for candidate := range known {
+		distance := editDistance(strings.ToUpper(name), strings.ToUpper(candidate))
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Operators of the ffmpeg tonemap filter offered in the settings
+const (
+	tonemapHable    = "hable"
+	tonemapMobius   = "mobius"
+	tonemapReinhard = "reinhard"
+)
+
+var tonemapAlgorithms = []string{tonemapHable, tonemapMobius, tonemapReinhard}
+
+// tonemapping returns the algorithm HDR screenshots of the movie are tonemapped with, or "" if
+// the movie is SDR or tonemapping is off
+func (s *SpoilerService) tonemapping(movie Movie) string {
+	if !s.settings.TonemapHDR {
+		return ""
+	}
+	switch movie.Params["%Video@color_transfer%"] {
+	case transferPQ, transferHLG:
+	default:
+		return ""
+	}
+	if slices.Contains(tonemapAlgorithms, s.settings.TonemapAlgorithm) {
+		return s.settings.TonemapAlgorithm
+	}
+	return tonemapHable
+}
+
+// keepsUntonemapped reports whether an untonemapped copy is kept of each screenshot of the movie
+func (s *SpoilerService) keepsUntonemapped(movie Movie) bool {
+	return s.settings.KeepUntonemapped && s.tonemapping(movie) != ""
+}
+
+// tonemapFilter returns the filter chain converting an HDR frame of the movie to SDR BT.709:
+// zscale linearises it, tonemap compresses the highlights in float RGB and zscale converts the
+// result back. The source colour properties come from the probe, as frames often lack them.
+func tonemapFilter(movie Movie, algorithm string) string {
+	param := func(key, fallback string) string {
+		if value := movie.Params["%Video@"+key+"%"]; value != "" && value != "unknown" {
+			return value
+		}
+		return fallback
+	}
+
+	return fmt.Sprintf(
+		"zscale=tin=%s:pin=%s:min=%s:t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,"+
+			"tonemap=tonemap=%s:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p",
+		param("color_transfer", transferPQ), param("color_primaries", "bt2020"), param("color_space", "bt2020nc"), algorithm,
+	)
+}
+
+// untonemappedPath returns where the untonemapped copy of a screenshot is kept, e.g. screenshot_1_hdr.png
+func untonemappedPath(screenshotPath string) string {
+	ext := filepath.Ext(screenshotPath)
+	return strings.TrimSuffix(screenshotPath, ext) + "_hdr" + ext
+}
@@ -0,0 +1,276 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"spoilr/backend/img_uploaders"
+	"sync"
+	"time"
+)
+
+// uploadCacheVersion is bumped when the upload cache format changes incompatibly
+const uploadCacheVersion = 1
+
+// uploadCacheEntry is an upload result remembered for identical image content
+type uploadCacheEntry struct {
+	Result   img_uploaders.UploadResult `json:"result"`
+	Created  time.Time                  `json:"created"`
+	LastUsed time.Time                  `json:"lastUsed"`
+}
+
+type uploadCacheFile struct {
+	Version int                         `json:"version"`
+	Entries map[string]uploadCacheEntry `json:"entries"`
+}
+
+// uploadCache maps image content hashes to the links a host returned for them, so identical
+// images are not uploaded twice. Entries are loaded on first use and saved by flush.
+type uploadCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]uploadCacheEntry // Nil until loaded
+	dirty   bool
+}
+
+// newUploadCache returns the cache stored in the user cache directory
+func newUploadCache() *uploadCache {
+	cache := &uploadCache{}
+	if dir, err := os.UserCacheDir(); err == nil {
+		cache.path = filepath.Join(dir, "spoilr", "uploads.json")
+	}
+	return cache
+}
+
+// uploadCacheKey identifies an upload by image content, host, thumbnail size and the size
+// limit the image was fitted to, zero if it was uploaded as is
+func uploadCacheKey(hash, hostCode string, miniatureSize int, fittedTo int64) string {
+	return fmt.Sprintf("%s:%s:%d:%d", hash, hostCode, miniatureSize, fittedTo)
+}
+
+// fileSHA256 returns the hex SHA-256 of the file content
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadLocked reads the cache file once; a missing or broken file starts an empty cache
+func (c *uploadCache) loadLocked() {
+	if c.entries != nil {
+		return
+	}
+	c.entries = make(map[string]uploadCacheEntry)
+	if c.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read upload cache: %v", err)
+		return
+	}
+
+	var file uploadCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != uploadCacheVersion {
+		log.Printf("Ignoring unreadable upload cache %s", c.path)
+		return
+	}
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+}
+
+// get returns the cached result for the key and marks it as used
+func (c *uploadCache) get(key string) (img_uploaders.UploadResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return img_uploaders.UploadResult{}, false
+	}
+	entry.LastUsed = time.Now()
+	c.entries[key] = entry
+	c.dirty = true
+	return entry.Result, true
+}
+
+func (c *uploadCache) put(key string, result img_uploaders.UploadResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	now := time.Now()
+	c.entries[key] = uploadCacheEntry{Result: result, Created: now, LastUsed: now}
+	c.dirty = true
+}
+
+func (c *uploadCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	delete(c.entries, key)
+	c.dirty = true
+}
+
+// prune removes entries not used since the cutoff and returns how many were removed.
+// A zero cutoff removes everything.
+func (c *uploadCache) prune(cutoff time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	removed := 0
+	for key, entry := range c.entries {
+		if cutoff.IsZero() || entry.LastUsed.Before(cutoff) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.dirty = true
+	}
+	return removed, c.flushLocked()
+}
+
+// len returns the number of cached uploads
+func (c *uploadCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+	return len(c.entries)
+}
+
+// flush writes pending changes to the cache file
+func (c *uploadCache) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked()
+}
+
+func (c *uploadCache) flushLocked() error {
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(uploadCacheFile{Version: uploadCacheVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode upload cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create upload cache directory: %v", err)
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upload cache: %v", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace upload cache: %v", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// uploadFile uploads an image unless the upload cache has links for identical content
+// on the host. With VerifyCachedUploads the cached direct link must still resolve.
+func (s *SpoilerService) uploadFile(host img_uploaders.Host, session *img_uploaders.Session, filePath, fileName string) (*img_uploaders.UploadResult, img_uploaders.RetryStats, error) {
+	maxBytes := s.maxUploadSize(host.Code)
+	hash, err := fileSHA256(filePath)
+	if err != nil {
+		log.Printf("Failed to hash %s, uploading without cache: %v", filePath, err)
+		return s.uploadFitted(host, session, filePath, fileName, maxBytes)
+	}
+
+	// An image over the host limit is uploaded re-encoded, so a new limit needs a new upload
+	var fittedTo int64
+	if maxBytes > 0 {
+		if info, err := os.Stat(filePath); err == nil && info.Size() > maxBytes {
+			fittedTo = maxBytes
+		}
+	}
+
+	key := uploadCacheKey(hash, host.Code, s.settings.ImageMiniatureSize, fittedTo)
+	if result, ok := s.uploadCache.get(key); ok {
+		if !s.settings.VerifyCachedUploads || s.cachedUploadAlive(host, result) {
+			log.Printf("Reusing cached %s upload of %s", host.Name, fileName)
+			return &result, img_uploaders.RetryStats{}, nil
+		}
+		s.uploadCache.remove(key)
+	}
+
+	result, stats, err := s.uploadFitted(host, session, filePath, fileName, maxBytes)
+	if err == nil {
+		s.uploadCache.put(key, *result)
+	}
+	return result, stats, err
+}
+
+// uploadFitted uploads an image, or a smaller copy of it if it is over maxBytes
+func (s *SpoilerService) uploadFitted(host img_uploaders.Host, session *img_uploaders.Session, filePath, fileName string, maxBytes int64) (*img_uploaders.UploadResult, img_uploaders.RetryStats, error) {
+	fittedPath, err := s.fitImage(filePath, host.Code, maxBytes)
+	if err != nil {
+		return nil, img_uploaders.RetryStats{}, err
+	}
+	if fittedPath != filePath {
+		defer os.Remove(fittedPath)
+	}
+	return session.Upload(s.cancelCtx, s.retryPolicy(), fittedPath, fileName)
+}
+
+// cachedUploadAlive checks that the image of a cached upload is still served
+func (s *SpoilerService) cachedUploadAlive(host img_uploaders.Host, result img_uploaders.UploadResult) bool {
+	link := result.DirectURL
+	if link == "" {
+		link = result.ViewerURL
+	}
+	if link == "" {
+		return false
+	}
+
+	if err := img_uploaders.CheckURL(s.cancelCtx, s.uploaderOptions(host.Code), link); err != nil {
+		log.Printf("Cached %s upload %s is gone, uploading again: %v", host.Name, link, err)
+		return false
+	}
+	return true
+}
+
+// PruneUploadCache removes cached uploads not reused within the given number of days.
+// Zero days clears the cache. It returns the number of removed entries.
+func (s *SpoilerService) PruneUploadCache(days int) (int, error) {
+	if days < 0 {
+		return 0, fmt.Errorf("days must not be negative")
+	}
+
+	var cutoff time.Time
+	if days > 0 {
+		cutoff = time.Now().AddDate(0, 0, -days)
+	}
+	return s.uploadCache.prune(cutoff)
+}
+
+// GetUploadCacheSize returns the number of cached uploads
+func (s *SpoilerService) GetUploadCacheSize() int {
+	return s.uploadCache.len()
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +16,7 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		filePath,
 	)
 	hideWindow(cmd)
@@ -31,37 +33,31 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 			BitRate  string            `json:"bit_rate"`
 			Tags     map[string]string `json:"tags"`
 		} `json:"format"`
-		Streams []struct {
-			CodecType     string            `json:"codec_type"`
-			CodecName     string            `json:"codec_name"`
-			Width         int               `json:"width"`
-			Height        int               `json:"height"`
-			Duration      string            `json:"duration"`
-			BitRate       string            `json:"bit_rate"`
-			RFrameRate    string            `json:"r_frame_rate"`
-			AvgFrameRate  string            `json:"avg_frame_rate"`
-			SampleRate    string            `json:"sample_rate"`
-			Channels      int               `json:"channels"`
-			ChannelLayout string            `json:"channel_layout"`
-			Tags          map[string]string `json:"tags"`
-		} `json:"streams"`
+		Streams  []ffprobeStream  `json:"streams"`
+		Chapters []ffprobeChapter `json:"chapters"`
 	}
 
 	if err := json.Unmarshal(output, &result); err != nil {
 		return MediaInfo{}, false, fmt.Errorf("failed to parse ffprobe output: %v", err)
 	}
 
-	// Check if it has video streams
-	hasVideo := false
-	for _, stream := range result.Streams {
-		if stream.CodecType == "video" {
-			hasVideo = true
-			break
-		}
+	// The first real video stream fills the Video section; cover art does not count as video
+	primaryVideo := slices.IndexFunc(result.Streams, func(stream ffprobeStream) bool {
+		return stream.CodecType == "video" && stream.Disposition["attached_pic"] == 0
+	})
+	if primaryVideo < 0 {
+		return MediaInfo{}, false, nil
 	}
 
-	if !hasVideo {
-		return MediaInfo{}, false, nil
+	// The default audio track, or the first one, fills the Audio section
+	primaryAudio := -1
+	for i, stream := range result.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		if primaryAudio < 0 || stream.Disposition["default"] == 1 && result.Streams[primaryAudio].Disposition["default"] == 0 {
+			primaryAudio = i
+		}
 	}
 
 	// Build MediaInfo
@@ -76,10 +72,18 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 	mediaInfo.General["size"] = result.Format.Size
 	mediaInfo.General["bit_rate"] = result.Format.BitRate
 
+	for _, chapter := range result.Chapters {
+		mediaInfo.Chapters = append(mediaInfo.Chapters, chapter.chapter())
+	}
+
 	// Process streams
-	for _, stream := range result.Streams {
-		switch stream.CodecType {
-		case "video":
+	for i, stream := range result.Streams {
+		if track, ok := stream.mediaStream(); ok {
+			mediaInfo.Streams = append(mediaInfo.Streams, track)
+		}
+
+		switch i {
+		case primaryVideo:
 			mediaInfo.Video["codec_name"] = stream.CodecName
 			if stream.Width > 0 {
 				mediaInfo.Video["width"] = strconv.Itoa(stream.Width)
@@ -87,16 +91,23 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 			if stream.Height > 0 {
 				mediaInfo.Video["height"] = strconv.Itoa(stream.Height)
 			}
+
+			// Aspect ratios; ffprobe prints 0:1 or N/A when the container does not set them
+			if parseAspectRatio(stream.SampleAspectRatio) > 0 {
+				mediaInfo.Video["sample_aspect_ratio"] = stream.SampleAspectRatio
+			}
+			if parseAspectRatio(stream.DisplayAspectRatio) > 0 {
+				mediaInfo.Video["display_aspect_ratio"] = stream.DisplayAspectRatio
+			}
+			if width, height := stream.displaySize(); width > 0 && height > 0 {
+				mediaInfo.Video["display_width"] = strconv.Itoa(width)
+				mediaInfo.Video["display_height"] = strconv.Itoa(height)
+			}
 			if stream.Duration != "" {
 				mediaInfo.Video["duration"] = stream.Duration
 			}
-			if stream.BitRate != "" {
-				mediaInfo.Video["bit_rate"] = stream.BitRate
-			}
-			if stream.BitRate == "" && stream.Tags != nil {
-				if br, ok := stream.Tags["BPS"]; ok {
-					mediaInfo.Video["bit_rate"] = br
-				}
+			if bitRate := stream.bitRate(); bitRate != "" {
+				mediaInfo.Video["bit_rate"] = bitRate
 			}
 
 			// Extract framerate info
@@ -111,18 +122,21 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 				mediaInfo.Video["avg_frame_rate"] = stream.AvgFrameRate
 			}
 
-		case "audio":
+			// Colour and HDR metadata; frames are only probed for streams signalling HDR
+			if stream.mayBeHDR() {
+				stream.SideDataList = append(stream.SideDataList, probeFrameSideData(filePath, stream.Index)...)
+			}
+			for key, value := range stream.colorInfo() {
+				mediaInfo.Video[key] = value
+			}
+
+		case primaryAudio:
 			mediaInfo.Audio["codec_name"] = stream.CodecName
 			if stream.Duration != "" {
 				mediaInfo.Audio["duration"] = stream.Duration
 			}
-			if stream.BitRate != "" {
-				mediaInfo.Audio["bit_rate"] = stream.BitRate
-			}
-			if stream.BitRate == "" && stream.Tags != nil {
-				if br, ok := stream.Tags["BPS"]; ok {
-					mediaInfo.Audio["bit_rate"] = br
-				}
+			if bitRate := stream.bitRate(); bitRate != "" {
+				mediaInfo.Audio["bit_rate"] = bitRate
 			}
 
 			// Extract audio-specific info
@@ -142,11 +156,16 @@ func GetVideoMediaInfo(filePath string) (MediaInfo, bool, error) {
 }
 
 func parseFrameRate(frameRate string) float64 {
-	if frameRate == "" || frameRate == "0/0" {
+	return parseRational(frameRate)
+}
+
+// parseRational parses ffprobe rationals such as 24000/1001, returning 0 if they are unset or invalid
+func parseRational(rational string) float64 {
+	if rational == "" || rational == "0/0" {
 		return 0
 	}
 
-	parts := strings.Split(frameRate, "/")
+	parts := strings.Split(rational, "/")
 	if len(parts) != 2 {
 		return 0
 	}
@@ -224,6 +243,22 @@ func ExtractMediaInfo(movie *Movie, mediaInfo MediaInfo) {
 		movie.Params["%AUDIO_CHANNELS%"] = formatChannels(channels)
 	}
 
+	// Store display size info
+	movie.Params["%DISPLAY_WIDTH%"] = mediaInfo.Video["display_width"]
+	movie.Params["%DISPLAY_HEIGHT%"] = mediaInfo.Video["display_height"]
+	displayWidth, _ := strconv.Atoi(mediaInfo.Video["display_width"])
+	displayHeight, _ := strconv.Atoi(mediaInfo.Video["display_height"])
+	movie.Params["%DAR%"] = aspectRatioLabel(displayWidth, displayHeight)
+
+	// Store HDR info
+	movie.Params["%VIDEO_HDR%"] = mediaInfo.Video["hdr_format"]
+	if bitDepth, ok := mediaInfo.Video["bit_depth"]; ok {
+		movie.Params["%VIDEO_BIT_DEPTH%"] = bitDepth + " bit"
+	}
+	movie.Params["%VIDEO_MASTERING_DISPLAY%"] = mediaInfo.Video["mastering_display"]
+	movie.Params["%VIDEO_MAX_CLL%"] = mediaInfo.Video["max_cll"]
+	movie.Params["%VIDEO_MAX_FALL%"] = mediaInfo.Video["max_fall"]
+
 	// Store all raw parameters
 	for key, value := range mediaInfo.General {
 		movie.Params[fmt.Sprintf("%%General@%s%%", key)] = value
@@ -236,6 +271,9 @@ func ExtractMediaInfo(movie *Movie, mediaInfo MediaInfo) {
 	for key, value := range mediaInfo.Audio {
 		movie.Params[fmt.Sprintf("%%Audio@%s%%", key)] = value
 	}
+
+	movie.Streams = mediaInfo.Streams
+	movie.Chapters = mediaInfo.Chapters
 }
 
 func formatSampleRate(sampleRateStr string) string {
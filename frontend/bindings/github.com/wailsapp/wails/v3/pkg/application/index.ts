@@ -1,19 +0,0 @@
-// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
-// This file is automatically generated. DO NOT EDIT
-
-export {
-    App,
-    AutostartManager,
-    BrowserManager,
-    ClipboardManager,
-    ContextMenuManager,
-    DialogManager,
-    EnvironmentManager,
-    EventManager,
-    GlobalShortcutManager,
-    KeyBindingManager,
-    MenuManager,
-    ScreenManager,
-    SystemTrayManager,
-    WindowManager
-} from "./models.js";
@@ -9,7 +9,20 @@ export {
 export {
     AppSettings,
     AppState,
+    Chapter,
+    ConnectionStatus,
+    GeneratedMedia,
+    MediaStream,
     Movie,
+    MovieOverrides,
+    PlaceholderInfo,
+    PlaceholderIssue,
     ProcessingState,
-    TemplatePreset
+    TemplateError,
+    TemplateHostUsage,
+    TemplatePreset,
+    TemplatePreview,
+    TemplateValidation,
+    UploadSet,
+    UploadedImage
 } from "./models.js";
@@ -46,6 +46,94 @@ export class AppSettings {
      */
     "saveMediaDirectory": string;
 
+    /**
+     * Upload retry settings
+     * Retries after a failed upload (0 = disabled)
+     */
+    "uploadRetries": number;
+
+    /**
+     * Delay before the first retry, doubled on every retry
+     */
+    "uploadRetryBaseDelayMs": number;
+
+    /**
+     * Upper bound of a single retry delay
+     */
+    "uploadRetryMaxDelayMs": number;
+
+    /**
+     * Network settings
+     * Proxy URL for all image hosts (empty = direct connection)
+     */
+    "proxy": string;
+
+    /**
+     * Upload cache settings
+     * Check that cached links still resolve before reusing them
+     */
+    "verifyCachedUploads": boolean;
+
+    /**
+     * Screenshot placement
+     * One screenshot per chapter instead of even intervals, for files with chapters
+     */
+    "screenshotPerChapter": boolean;
+
+    /**
+     * Smart frame selection settings
+     * Pick the best of several frames around each screenshot position
+     */
+    "smartFrameSelection": boolean;
+
+    /**
+     * Frames scored per screenshot
+     */
+    "smartFrameCandidates": number;
+
+    /**
+     * Length of the window the candidates are spread over
+     */
+    "smartFrameWindowSeconds": number;
+
+    /**
+     * Screenshot output format settings; ScreenshotQuality is the JPEG quality
+     * jpg, png, webp or avif
+     */
+    "screenshotFormat": string;
+
+    /**
+     * 1-100, higher is better
+     */
+    "webpQuality": number;
+
+    /**
+     * CRF 0-63, lower is better
+     */
+    "avifQuality": number;
+
+    /**
+     * HDR settings
+     * Tonemap screenshots of HDR10 and HLG video to SDR
+     */
+    "tonemapHdr": boolean;
+
+    /**
+     * hable, mobius or reinhard
+     */
+    "tonemapAlgorithm": string;
+
+    /**
+     * Also keep the untonemapped frame of each screenshot
+     */
+    "keepUntonemapped": boolean;
+
+    /**
+     * Cropping settings
+     * Crop letterbox and pillarbox bars off screenshots
+     */
+    "autoCrop": boolean;
+
     /** Creates a new AppSettings instance. */
     constructor($$source: Partial<AppSettings> = {}) {
         if (!("screenshotCount" in $$source)) {
@@ -78,6 +166,54 @@ export class AppSettings {
         if (!("saveMediaDirectory" in $$source)) {
             this["saveMediaDirectory"] = "";
         }
+        if (!("uploadRetries" in $$source)) {
+            this["uploadRetries"] = 0;
+        }
+        if (!("uploadRetryBaseDelayMs" in $$source)) {
+            this["uploadRetryBaseDelayMs"] = 0;
+        }
+        if (!("uploadRetryMaxDelayMs" in $$source)) {
+            this["uploadRetryMaxDelayMs"] = 0;
+        }
+        if (!("proxy" in $$source)) {
+            this["proxy"] = "";
+        }
+        if (!("verifyCachedUploads" in $$source)) {
+            this["verifyCachedUploads"] = false;
+        }
+        if (!("screenshotPerChapter" in $$source)) {
+            this["screenshotPerChapter"] = false;
+        }
+        if (!("smartFrameSelection" in $$source)) {
+            this["smartFrameSelection"] = false;
+        }
+        if (!("smartFrameCandidates" in $$source)) {
+            this["smartFrameCandidates"] = 0;
+        }
+        if (!("smartFrameWindowSeconds" in $$source)) {
+            this["smartFrameWindowSeconds"] = 0;
+        }
+        if (!("screenshotFormat" in $$source)) {
+            this["screenshotFormat"] = "";
+        }
+        if (!("webpQuality" in $$source)) {
+            this["webpQuality"] = 0;
+        }
+        if (!("avifQuality" in $$source)) {
+            this["avifQuality"] = 0;
+        }
+        if (!("tonemapHdr" in $$source)) {
+            this["tonemapHdr"] = false;
+        }
+        if (!("tonemapAlgorithm" in $$source)) {
+            this["tonemapAlgorithm"] = "";
+        }
+        if (!("keepUntonemapped" in $$source)) {
+            this["keepUntonemapped"] = false;
+        }
+        if (!("autoCrop" in $$source)) {
+            this["autoCrop"] = false;
+        }
 
         Object.assign(this, $$source);
     }
@@ -124,95 +260,297 @@ export class AppState {
 }
 
 /**
- * Movie represents a media file with its metadata
+ * Chapter is a chapter of a file with its bounds in seconds
  */
-export class Movie {
-    "id": string;
-    "fileName": string;
-    "filePath": string;
-    "fileSize": string;
-    "fileSizeBytes": number;
-    "duration": string;
+export class Chapter {
+    "start": number;
+    "end": number;
+    "title"?: string;
+
+    /** Creates a new Chapter instance. */
+    constructor($$source: Partial<Chapter> = {}) {
+        if (!("start" in $$source)) {
+            this["start"] = 0;
+        }
+        if (!("end" in $$source)) {
+            this["end"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
 
     /**
-     * for screenshot generation
+     * Creates a new Chapter instance from a string or object.
      */
-    "videDduration": number;
-    "width": string;
-    "height": string;
-    "bitRate": string;
-    "videoBitRate": string;
-    "audioBitRate": string;
-    "videoCodec": string;
-    "audioCodec": string;
+    static createFrom($$source: any = {}): Chapter {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new Chapter($$parsedSource as Partial<Chapter>);
+    }
+}
+
+/**
+ * ConnectionStatus is the result of a connection test against one image host
+ */
+export class ConnectionStatus {
+    "code": string;
+    "name": string;
+    "reachable": boolean;
+    "latencyMs": number;
+    "error"?: string;
+
+    /** Creates a new ConnectionStatus instance. */
+    constructor($$source: Partial<ConnectionStatus> = {}) {
+        if (!("code" in $$source)) {
+            this["code"] = "";
+        }
+        if (!("name" in $$source)) {
+            this["name"] = "";
+        }
+        if (!("reachable" in $$source)) {
+            this["reachable"] = false;
+        }
+        if (!("latencyMs" in $$source)) {
+            this["latencyMs"] = 0;
+        }
+
+        Object.assign(this, $$source);
+    }
 
     /**
-     * Fastpic URLs
-     * MTN-generated contact sheet (small)
+     * Creates a new ConnectionStatus instance from a string or object.
      */
-    "contactSheetUrl": string;
+    static createFrom($$source: any = {}): ConnectionStatus {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new ConnectionStatus($$parsedSource as Partial<ConnectionStatus>);
+    }
+}
+
+/**
+ * GeneratedMedia holds the paths of the media files generated for a movie
+ */
+export class GeneratedMedia {
+    "contactSheet"?: string;
+
+    /**
+     * Indexed by screenshot number, empty if generation failed
+     */
+    "screenshots"?: string[];
+
+    /**
+     * Positions in seconds the screenshots were planned at
+     */
+    "screenshotTimestamps"?: number[];
+
+    /**
+     * Positions the screenshots were actually taken at, which differ with smart frame selection
+     */
+    "frameTimestamps"?: number[];
+
+    /**
+     * Taken with smart frame selection
+     */
+    "smartFrames"?: boolean;
+
+    /**
+     * Algorithm the screenshots were tonemapped with, empty if they were not
+     */
+    "tonemap"?: string;
+
+    /**
+     * An untonemapped copy is kept next to each screenshot
+     */
+    "untonemapped"?: boolean;
+
+    /**
+     * Taken with automatic cropping of black bars
+     */
+    "autoCrop"?: boolean;
+
+    /**
+     * Crop filter cropdetect found, empty if the movie has no bars
+     */
+    "crop"?: string;
+
+    /** Creates a new GeneratedMedia instance. */
+    constructor($$source: Partial<GeneratedMedia> = {}) {
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new GeneratedMedia instance from a string or object.
+     */
+    static createFrom($$source: any = {}): GeneratedMedia {
+        const $$createField1_0 = $$createType6;
+        const $$createField2_0 = $$createType6;
+        const $$createField3_0 = $$createType6;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("screenshots" in $$parsedSource) {
+            $$parsedSource["screenshots"] = $$createField1_0($$parsedSource["screenshots"]);
+        }
+        if ("screenshotTimestamps" in $$parsedSource) {
+            $$parsedSource["screenshotTimestamps"] = $$createField2_0($$parsedSource["screenshotTimestamps"]);
+        }
+        if ("frameTimestamps" in $$parsedSource) {
+            $$parsedSource["frameTimestamps"] = $$createField3_0($$parsedSource["frameTimestamps"]);
+        }
+        return new GeneratedMedia($$parsedSource as Partial<GeneratedMedia>);
+    }
+}
+
+/**
+ * MediaStream describes a single video, audio or subtitle stream of a file
+ */
+export class MediaStream {
+    /**
+     * ffprobe stream index
+     */
+    "index": number;
+
+    /**
+     * video, audio or subtitle
+     */
+    "type": string;
+    "codec": string;
+
+    /**
+     * ISO 639 code from the stream tags
+     */
+    "language"?: string;
+    "title"?: string;
+    "default"?: boolean;
+    "forced"?: boolean;
+
+    /**
+     * Bits per second
+     */
+    "bitRate"?: string;
+    "width"?: number;
+    "height"?: number;
+
+    /**
+     * Fractional, e.g. 24000/1001
+     */
+    "frameRate"?: string;
+    "channels"?: number;
+    "channelLayout"?: string;
+    "sampleRate"?: string;
+
+    /** Creates a new MediaStream instance. */
+    constructor($$source: Partial<MediaStream> = {}) {
+        if (!("index" in $$source)) {
+            this["index"] = 0;
+        }
+        if (!("type" in $$source)) {
+            this["type"] = "";
+        }
+        if (!("codec" in $$source)) {
+            this["codec"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
 
     /**
-     * MTN-generated contact sheet (big)
+     * Creates a new MediaStream instance from a string or object.
      */
-    "contactSheetBigUrl": string;
+    static createFrom($$source: any = {}): MediaStream {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new MediaStream($$parsedSource as Partial<MediaStream>);
+    }
+}
 
+/**
+ * MovieOverrides replaces the global screenshot settings for one movie
+ */
+export class MovieOverrides {
     /**
-     * Individual screenshots (small)
+     * Comma-separated positions, e.g. "00:12:30, 45:10"; beat every other setting
      */
-    "screenshotUrls": string[];
+    "timestamps"?: string;
 
     /**
-     * Individual screenshots (big)
+     * Evenly spaced screenshots, 0 = global setting
      */
-    "screenshotBigUrls": string[];
+    "screenshotCount"?: number;
 
     /**
-     * Album link
+     * Screenshots and the contact sheet skip the part before, e.g. the intro
      */
-    "screenshotAlbum": string;
+    "startPercent"?: number;
 
     /**
-     * Imgbox Results - Renamed from Thumbnail* to ContactSheet*
-     * MTN-generated contact sheet (small)
+     * ... and after, e.g. the credits; 0 = the end
      */
-    "contactSheetUrlIb": string;
+    "endPercent"?: number;
+
+    /** Creates a new MovieOverrides instance. */
+    constructor($$source: Partial<MovieOverrides> = {}) {
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new MovieOverrides instance from a string or object.
+     */
+    static createFrom($$source: any = {}): MovieOverrides {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new MovieOverrides($$parsedSource as Partial<MovieOverrides>);
+    }
+}
+
+/**
+ * Movie represents a media file with its metadata
+ */
+export class Movie {
+    "id": string;
+    "fileName": string;
+    "filePath": string;
 
     /**
-     * MTN-generated contact sheet (big)
+     * Source file disappeared since the session was saved
      */
-    "contactSheetBigUrlIb": string;
+    "fileMissing"?: boolean;
+    "fileSize": string;
+    "fileSizeBytes": number;
+    "duration": string;
 
     /**
-     * Individual screenshots (small)
+     * for screenshot generation
      */
-    "screenshotUrlsIb": string[];
+    "videDduration": number;
+    "width": string;
+    "height": string;
+    "bitRate": string;
+    "videoBitRate": string;
+    "audioBitRate": string;
+    "videoCodec": string;
+    "audioCodec": string;
 
     /**
-     * Individual screenshots (big)
+     * Every video, audio and subtitle stream in file order
      */
-    "screenshotBigUrlsIb": string[];
+    "streams"?: MediaStream[];
 
     /**
-     * Hamster Results
-     * MTN-generated contact sheet (small)
+     * Chapters in file order
      */
-    "contactSheetUrlHam": string;
+    "chapters"?: Chapter[];
 
     /**
-     * MTN-generated contact sheet (big)
+     * Processing overrides; the global settings apply to everything left unset
      */
-    "contactSheetBigUrlHam": string;
+    "overrides": MovieOverrides;
 
     /**
-     * Individual screenshots (small)
+     * Upload results keyed by host code (FP, IB, HAM)
      */
-    "screenshotUrlsHam": string[];
+    "uploads": { [_ in string]?: UploadSet };
 
     /**
-     * Individual screenshots (big)
+     * Generated media kept in the cache directory, reused by later runs
      */
-    "screenshotBigUrlsHam": string[];
+    "media": GeneratedMedia;
     "params": { [_ in string]?: string };
 
     /**
@@ -230,6 +568,16 @@ export class Movie {
      */
     "errors"?: string[];
 
+    /**
+     * Upload retries made for this movie
+     */
+    "uploadRetries"?: number;
+
+    /**
+     * Total backoff delay spent on retries
+     */
+    "uploadRetryDelayMs"?: number;
+
     /** Creates a new Movie instance. */
     constructor($$source: Partial<Movie> = {}) {
         if (!("id" in $$source)) {
@@ -274,44 +622,14 @@ export class Movie {
         if (!("audioCodec" in $$source)) {
             this["audioCodec"] = "";
         }
-        if (!("contactSheetUrl" in $$source)) {
-            this["contactSheetUrl"] = "";
-        }
-        if (!("contactSheetBigUrl" in $$source)) {
-            this["contactSheetBigUrl"] = "";
-        }
-        if (!("screenshotUrls" in $$source)) {
-            this["screenshotUrls"] = [];
-        }
-        if (!("screenshotBigUrls" in $$source)) {
-            this["screenshotBigUrls"] = [];
-        }
-        if (!("screenshotAlbum" in $$source)) {
-            this["screenshotAlbum"] = "";
-        }
-        if (!("contactSheetUrlIb" in $$source)) {
-            this["contactSheetUrlIb"] = "";
+        if (!("overrides" in $$source)) {
+            this["overrides"] = (new MovieOverrides());
         }
-        if (!("contactSheetBigUrlIb" in $$source)) {
-            this["contactSheetBigUrlIb"] = "";
+        if (!("uploads" in $$source)) {
+            this["uploads"] = {};
         }
-        if (!("screenshotUrlsIb" in $$source)) {
-            this["screenshotUrlsIb"] = [];
-        }
-        if (!("screenshotBigUrlsIb" in $$source)) {
-            this["screenshotBigUrlsIb"] = [];
-        }
-        if (!("contactSheetUrlHam" in $$source)) {
-            this["contactSheetUrlHam"] = "";
-        }
-        if (!("contactSheetBigUrlHam" in $$source)) {
-            this["contactSheetBigUrlHam"] = "";
-        }
-        if (!("screenshotUrlsHam" in $$source)) {
-            this["screenshotUrlsHam"] = [];
-        }
-        if (!("screenshotBigUrlsHam" in $$source)) {
-            this["screenshotBigUrlsHam"] = [];
+        if (!("media" in $$source)) {
+            this["media"] = (new GeneratedMedia());
         }
         if (!("params" in $$source)) {
             this["params"] = {};
@@ -327,43 +645,124 @@ export class Movie {
      * Creates a new Movie instance from a string or object.
      */
     static createFrom($$source: any = {}): Movie {
-        const $$createField16_0 = $$createType2;
-        const $$createField17_0 = $$createType2;
-        const $$createField21_0 = $$createType2;
-        const $$createField22_0 = $$createType2;
-        const $$createField25_0 = $$createType2;
-        const $$createField26_0 = $$createType2;
-        const $$createField27_0 = $$createType3;
-        const $$createField30_0 = $$createType2;
+        const $$createField15_0 = $$createType17;
+        const $$createField16_0 = $$createType19;
+        const $$createField17_0 = $$createType20;
+        const $$createField18_0 = $$createType3;
+        const $$createField19_0 = $$createType4;
+        const $$createField20_0 = $$createType5;
+        const $$createField23_0 = $$createType6;
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
-        if ("screenshotUrls" in $$parsedSource) {
-            $$parsedSource["screenshotUrls"] = $$createField16_0($$parsedSource["screenshotUrls"]);
-        }
-        if ("screenshotBigUrls" in $$parsedSource) {
-            $$parsedSource["screenshotBigUrls"] = $$createField17_0($$parsedSource["screenshotBigUrls"]);
+        if ("streams" in $$parsedSource) {
+            $$parsedSource["streams"] = $$createField15_0($$parsedSource["streams"]);
         }
-        if ("screenshotUrlsIb" in $$parsedSource) {
-            $$parsedSource["screenshotUrlsIb"] = $$createField21_0($$parsedSource["screenshotUrlsIb"]);
+        if ("chapters" in $$parsedSource) {
+            $$parsedSource["chapters"] = $$createField16_0($$parsedSource["chapters"]);
         }
-        if ("screenshotBigUrlsIb" in $$parsedSource) {
-            $$parsedSource["screenshotBigUrlsIb"] = $$createField22_0($$parsedSource["screenshotBigUrlsIb"]);
+        if ("overrides" in $$parsedSource) {
+            $$parsedSource["overrides"] = $$createField17_0($$parsedSource["overrides"]);
         }
-        if ("screenshotUrlsHam" in $$parsedSource) {
-            $$parsedSource["screenshotUrlsHam"] = $$createField25_0($$parsedSource["screenshotUrlsHam"]);
+        if ("uploads" in $$parsedSource) {
+            $$parsedSource["uploads"] = $$createField18_0($$parsedSource["uploads"]);
         }
-        if ("screenshotBigUrlsHam" in $$parsedSource) {
-            $$parsedSource["screenshotBigUrlsHam"] = $$createField26_0($$parsedSource["screenshotBigUrlsHam"]);
+        if ("media" in $$parsedSource) {
+            $$parsedSource["media"] = $$createField19_0($$parsedSource["media"]);
         }
         if ("params" in $$parsedSource) {
-            $$parsedSource["params"] = $$createField27_0($$parsedSource["params"]);
+            $$parsedSource["params"] = $$createField20_0($$parsedSource["params"]);
         }
         if ("errors" in $$parsedSource) {
-            $$parsedSource["errors"] = $$createField30_0($$parsedSource["errors"]);
+            $$parsedSource["errors"] = $$createField23_0($$parsedSource["errors"]);
         }
         return new Movie($$parsedSource as Partial<Movie>);
     }
 }
 
+/**
+ * PlaceholderInfo describes a template placeholder for the editor
+ */
+export class PlaceholderInfo {
+    /**
+     * e.g. %SCREENSHOTS_FP%
+     */
+    "name": string;
+    "description": string;
+    "category": string;
+    "example": string;
+
+    /**
+     * Code of the host the value is uploaded to
+     */
+    "host"?: string;
+
+    /**
+     * "contactSheet" or "screenshots" for upload placeholders
+     */
+    "artifact"?: string;
+
+    /** Creates a new PlaceholderInfo instance. */
+    constructor($$source: Partial<PlaceholderInfo> = {}) {
+        if (!("name" in $$source)) {
+            this["name"] = "";
+        }
+        if (!("description" in $$source)) {
+            this["description"] = "";
+        }
+        if (!("category" in $$source)) {
+            this["category"] = "";
+        }
+        if (!("example" in $$source)) {
+            this["example"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new PlaceholderInfo instance from a string or object.
+     */
+    static createFrom($$source: any = {}): PlaceholderInfo {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new PlaceholderInfo($$parsedSource as Partial<PlaceholderInfo>);
+    }
+}
+
+/**
+ * PlaceholderIssue is a problem with a placeholder at its first use in the template
+ */
+export class PlaceholderIssue {
+    "name": string;
+    "line": number;
+    "column": number;
+    "message": string;
+
+    /** Creates a new PlaceholderIssue instance. */
+    constructor($$source: Partial<PlaceholderIssue> = {}) {
+        if (!("name" in $$source)) {
+            this["name"] = "";
+        }
+        if (!("line" in $$source)) {
+            this["line"] = 0;
+        }
+        if (!("column" in $$source)) {
+            this["column"] = 0;
+        }
+        if (!("message" in $$source)) {
+            this["message"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new PlaceholderIssue instance from a string or object.
+     */
+    static createFrom($$source: any = {}): PlaceholderIssue {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new PlaceholderIssue($$parsedSource as Partial<PlaceholderIssue>);
+    }
+}
+
 /**
  * Processing state constants
  */
@@ -383,14 +782,69 @@ export enum ProcessingState {
     StateError = "error",
 };
 
+/**
+ * TemplateHostUsage tells which artifacts a template uploads to a host
+ */
+export class TemplateHostUsage {
+    "code": string;
+    "name": string;
+    "contactSheet": boolean;
+    "screenshots": boolean;
+
+    /** Creates a new TemplateHostUsage instance. */
+    constructor($$source: Partial<TemplateHostUsage> = {}) {
+        if (!("code" in $$source)) {
+            this["code"] = "";
+        }
+        if (!("name" in $$source)) {
+            this["name"] = "";
+        }
+        if (!("contactSheet" in $$source)) {
+            this["contactSheet"] = false;
+        }
+        if (!("screenshots" in $$source)) {
+            this["screenshots"] = false;
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TemplateHostUsage instance from a string or object.
+     */
+    static createFrom($$source: any = {}): TemplateHostUsage {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new TemplateHostUsage($$parsedSource as Partial<TemplateHostUsage>);
+    }
+}
+
 /**
  * TemplatePreset represents a saved template configuration
  */
 export class TemplatePreset {
     "id": string;
     "name": string;
+
+    /**
+     * Rendered once before the movies
+     */
+    "header": string;
+
+    /**
+     * Rendered for every completed movie
+     */
     "template": string;
 
+    /**
+     * Rendered once after the movies
+     */
+    "footer": string;
+
+    /**
+     * Image markup: bbcode (default), markdown, html or json
+     */
+    "markup": string;
+
     /** Creates a new TemplatePreset instance. */
     constructor($$source: Partial<TemplatePreset> = {}) {
         if (!("id" in $$source)) {
@@ -399,9 +853,18 @@ export class TemplatePreset {
         if (!("name" in $$source)) {
             this["name"] = "";
         }
+        if (!("header" in $$source)) {
+            this["header"] = "";
+        }
         if (!("template" in $$source)) {
             this["template"] = "";
         }
+        if (!("footer" in $$source)) {
+            this["footer"] = "";
+        }
+        if (!("markup" in $$source)) {
+            this["markup"] = "";
+        }
 
         Object.assign(this, $$source);
     }
@@ -415,8 +878,278 @@ export class TemplatePreset {
     }
 }
 
+/**
+ * TemplateError is a template syntax or rendering error with its 1-based position in the template
+ */
+export class TemplateError {
+    "line": number;
+    "column": number;
+    "message": string;
+
+    /** Creates a new TemplateError instance. */
+    constructor($$source: Partial<TemplateError> = {}) {
+        if (!("line" in $$source)) {
+            this["line"] = 0;
+        }
+        if (!("column" in $$source)) {
+            this["column"] = 0;
+        }
+        if (!("message" in $$source)) {
+            this["message"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TemplateError instance from a string or object.
+     */
+    static createFrom($$source: any = {}): TemplateError {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new TemplateError($$parsedSource as Partial<TemplateError>);
+    }
+}
+
+/**
+ * TemplatePreview is a template rendered against a movie without processing it
+ */
+export class TemplatePreview {
+    "result": string;
+
+    /**
+     * Syntax or rendering error, Result is empty then
+     */
+    "error"?: TemplateError | null;
+
+    /**
+     * Rendered against the built-in sample movie
+     */
+    "sample": boolean;
+
+    /**
+     * Uploads the template triggers
+     */
+    "hosts": TemplateHostUsage[];
+
+    /** Creates a new TemplatePreview instance. */
+    constructor($$source: Partial<TemplatePreview> = {}) {
+        if (!("result" in $$source)) {
+            this["result"] = "";
+        }
+        if (!("sample" in $$source)) {
+            this["sample"] = false;
+        }
+        if (!("hosts" in $$source)) {
+            this["hosts"] = [];
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TemplatePreview instance from a string or object.
+     */
+    static createFrom($$source: any = {}): TemplatePreview {
+        const $$createField1_0 = $$createType10;
+        const $$createField3_0 = $$createType15;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("error" in $$parsedSource) {
+            $$parsedSource["error"] = $$createField1_0($$parsedSource["error"]);
+        }
+        if ("hosts" in $$parsedSource) {
+            $$parsedSource["hosts"] = $$createField3_0($$parsedSource["hosts"]);
+        }
+        return new TemplatePreview($$parsedSource as Partial<TemplatePreview>);
+    }
+}
+
+/**
+ * TemplateValidation is the result of linting a template
+ */
+export class TemplateValidation {
+    /**
+     * Syntax or rendering error, nil if the template renders
+     */
+    "error"?: TemplateError | null;
+
+    /**
+     * Placeholders and hosts that do not exist
+     */
+    "unknownPlaceholders": PlaceholderIssue[];
+
+    /**
+     * Placeholders that stay empty with the current settings
+     */
+    "emptyPlaceholders": PlaceholderIssue[];
+
+    /**
+     * BBCode tags without a matching opening or closing tag
+     */
+    "unbalancedTags": TemplateError[];
+
+    /**
+     * Uploads the template triggers
+     */
+    "hosts": TemplateHostUsage[];
+
+    /** Creates a new TemplateValidation instance. */
+    constructor($$source: Partial<TemplateValidation> = {}) {
+        if (!("unknownPlaceholders" in $$source)) {
+            this["unknownPlaceholders"] = [];
+        }
+        if (!("emptyPlaceholders" in $$source)) {
+            this["emptyPlaceholders"] = [];
+        }
+        if (!("unbalancedTags" in $$source)) {
+            this["unbalancedTags"] = [];
+        }
+        if (!("hosts" in $$source)) {
+            this["hosts"] = [];
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new TemplateValidation instance from a string or object.
+     */
+    static createFrom($$source: any = {}): TemplateValidation {
+        const $$createField0_0 = $$createType10;
+        const $$createField1_0 = $$createType12;
+        const $$createField2_0 = $$createType12;
+        const $$createField3_0 = $$createType13;
+        const $$createField4_0 = $$createType15;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("error" in $$parsedSource) {
+            $$parsedSource["error"] = $$createField0_0($$parsedSource["error"]);
+        }
+        if ("unknownPlaceholders" in $$parsedSource) {
+            $$parsedSource["unknownPlaceholders"] = $$createField1_0($$parsedSource["unknownPlaceholders"]);
+        }
+        if ("emptyPlaceholders" in $$parsedSource) {
+            $$parsedSource["emptyPlaceholders"] = $$createField2_0($$parsedSource["emptyPlaceholders"]);
+        }
+        if ("unbalancedTags" in $$parsedSource) {
+            $$parsedSource["unbalancedTags"] = $$createField3_0($$parsedSource["unbalancedTags"]);
+        }
+        if ("hosts" in $$parsedSource) {
+            $$parsedSource["hosts"] = $$createField4_0($$parsedSource["hosts"]);
+        }
+        return new TemplateValidation($$parsedSource as Partial<TemplateValidation>);
+    }
+}
+
+/**
+ * UploadSet holds everything uploaded for a movie to a single host
+ */
+export class UploadSet {
+    /**
+     * MTN-generated contact sheet
+     */
+    "contactSheet": UploadedImage;
+
+    /**
+     * Individual screenshots, indexed by screenshot number
+     */
+    "screenshots": UploadedImage[];
+
+    /**
+     * Album link, if the host groups uploads
+     */
+    "album"?: string;
+
+    /** Creates a new UploadSet instance. */
+    constructor($$source: Partial<UploadSet> = {}) {
+        if (!("contactSheet" in $$source)) {
+            this["contactSheet"] = (new UploadedImage());
+        }
+        if (!("screenshots" in $$source)) {
+            this["screenshots"] = [];
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new UploadSet instance from a string or object.
+     */
+    static createFrom($$source: any = {}): UploadSet {
+        const $$createField0_0 = $$createType7;
+        const $$createField1_0 = $$createType8;
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        if ("contactSheet" in $$parsedSource) {
+            $$parsedSource["contactSheet"] = $$createField0_0($$parsedSource["contactSheet"]);
+        }
+        if ("screenshots" in $$parsedSource) {
+            $$parsedSource["screenshots"] = $$createField1_0($$parsedSource["screenshots"]);
+        }
+        return new UploadSet($$parsedSource as Partial<UploadSet>);
+    }
+}
+
+/**
+ * UploadedImage holds the links of a single image uploaded to a host
+ * Embed codes are rendered from these in the markup of the preset.
+ */
+export class UploadedImage {
+    /**
+     * Thumbnail image URL
+     */
+    "thumb": string;
+
+    /**
+     * Direct image URL
+     */
+    "direct": string;
+
+    /**
+     * Host page showing the image
+     */
+    "viewer": string;
+
+    /** Creates a new UploadedImage instance. */
+    constructor($$source: Partial<UploadedImage> = {}) {
+        if (!("thumb" in $$source)) {
+            this["thumb"] = "";
+        }
+        if (!("direct" in $$source)) {
+            this["direct"] = "";
+        }
+        if (!("viewer" in $$source)) {
+            this["viewer"] = "";
+        }
+
+        Object.assign(this, $$source);
+    }
+
+    /**
+     * Creates a new UploadedImage instance from a string or object.
+     */
+    static createFrom($$source: any = {}): UploadedImage {
+        let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
+        return new UploadedImage($$parsedSource as Partial<UploadedImage>);
+    }
+}
+
 // Private type creation functions
 const $$createType0 = Movie.createFrom;
 const $$createType1 = $Create.Array($$createType0);
-const $$createType2 = $Create.Array($Create.Any);
-const $$createType3 = $Create.Map($Create.Any, $Create.Any);
+const $$createType2 = UploadSet.createFrom;
+const $$createType3 = $Create.Map($Create.Any, $$createType2);
+const $$createType4 = GeneratedMedia.createFrom;
+const $$createType5 = $Create.Map($Create.Any, $Create.Any);
+const $$createType6 = $Create.Array($Create.Any);
+const $$createType7 = UploadedImage.createFrom;
+const $$createType8 = $Create.Array($$createType7);
+const $$createType9 = TemplateError.createFrom;
+const $$createType10 = $Create.Nullable($$createType9);
+const $$createType11 = PlaceholderIssue.createFrom;
+const $$createType12 = $Create.Array($$createType11);
+const $$createType13 = $Create.Array($$createType9);
+const $$createType14 = TemplateHostUsage.createFrom;
+const $$createType15 = $Create.Array($$createType14);
+const $$createType16 = MediaStream.createFrom;
+const $$createType17 = $Create.Array($$createType16);
+const $$createType18 = Chapter.createFrom;
+const $$createType19 = $Create.Array($$createType18);
+const $$createType20 = MovieOverrides.createFrom;
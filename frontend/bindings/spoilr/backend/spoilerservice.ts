@@ -5,10 +5,6 @@
 // @ts-ignore: Unused imports
 import { Call as $Call, CancellablePromise as $CancellablePromise, Create as $Create } from "@wailsio/runtime";
 
-// eslint-disable-next-line @typescript-eslint/ban-ts-comment
-// @ts-ignore: Unused imports
-import * as application$0 from "../../github.com/wailsapp/wails/v3/pkg/application/models.js";
-
 // eslint-disable-next-line @typescript-eslint/ban-ts-comment
 // @ts-ignore: Unused imports
 import * as $models from "./models.js";
@@ -21,6 +17,15 @@ export function CancelProcessing(): $CancellablePromise<void> {
     return $Call.ByID(2842299945);
 }
 
+/**
+ * CheckTemplate parses the template and returns the position of the first syntax error, or nil
+ */
+export function CheckTemplate(template: string): $CancellablePromise<$models.TemplateError | null> {
+    return $Call.ByID(2074152602, template).then(($result: any) => {
+        return $$createType8($result);
+    });
+}
+
 export function ClearMovies(): $CancellablePromise<void> {
     return $Call.ByID(2972937796);
 }
@@ -51,6 +56,16 @@ export function GetExpandedFilePaths(paths: string[]): $CancellablePromise<strin
     });
 }
 
+/**
+ * GetPlaceholderCatalog returns every template placeholder with a description and an example
+ * value, including the %Video@key% style values found in the loaded movies
+ */
+export function GetPlaceholderCatalog(): $CancellablePromise<$models.PlaceholderInfo[]> {
+    return $Call.ByID(2967144844).then(($result: any) => {
+        return $$createType11($result);
+    });
+}
+
 /**
  * Settings management
  */
@@ -79,10 +94,38 @@ export function GetTemplatePresets(): $CancellablePromise<$models.TemplatePreset
     });
 }
 
+/**
+ * GetUploadCacheSize returns the number of cached uploads
+ */
+export function GetUploadCacheSize(): $CancellablePromise<number> {
+    return $Call.ByID(714859340);
+}
+
+/**
+ * PruneUploadCache removes cached uploads not reused within the given number of days.
+ * Zero days clears the cache. It returns the number of removed entries.
+ */
+export function PruneUploadCache(days: number): $CancellablePromise<number> {
+    return $Call.ByID(2628092553, days);
+}
+
 export function RemoveMovie(id: string): $CancellablePromise<void> {
     return $Call.ByID(2848711252, id);
 }
 
+/**
+ * RenderTemplatePreview renders an unsaved template against a loaded movie, or against the
+ * built-in sample movie if movieID is empty. Images that are not uploaded yet get placeholder
+ * links, so the preview shows the layout of the final post. Aggregate placeholders cover the
+ * completed movies, or just the previewed movie if none is completed. Images are rendered in
+ * the markup, BBCode if it is empty.
+ */
+export function RenderTemplatePreview(template: string, markup: string, movieID: string): $CancellablePromise<$models.TemplatePreview> {
+    return $Call.ByID(963476366, template, markup, movieID).then(($result: any) => {
+        return $$createType12($result);
+    });
+}
+
 export function ReorderMovies(newOrder: string[]): $CancellablePromise<void> {
     return $Call.ByID(1964823934, newOrder);
 }
@@ -91,8 +134,21 @@ export function ResetMovieStatuses(): $CancellablePromise<void> {
     return $Call.ByID(1291166359);
 }
 
-export function SaveTemplatePreset(name: string, template: string): $CancellablePromise<$models.TemplatePreset> {
-    return $Call.ByID(1758640140, name, template).then(($result: any) => {
+/**
+ * RetryFailed queues movies that failed, completed with errors or lack uploads the current
+ * template needs, and starts processing. Uploaded links and generated media are kept, so
+ * only the missing pieces are redone.
+ */
+export function RetryFailed(): $CancellablePromise<void> {
+    return $Call.ByID(1311265275);
+}
+
+/**
+ * SaveTemplatePreset saves a new preset with the name, sections and markup of the given one;
+ * header, footer and markup may be empty
+ */
+export function SaveTemplatePreset(preset: $models.TemplatePreset): $CancellablePromise<$models.TemplatePreset> {
+    return $Call.ByID(1758640140, preset).then(($result: any) => {
         return $$createType3($result);
     });
 }
@@ -104,29 +160,80 @@ export function SelectSaveMediaDirectory(): $CancellablePromise<string> {
     return $Call.ByID(1666802570);
 }
 
-export function SetApp(app: application$0.App | null): $CancellablePromise<void> {
-    return $Call.ByID(1525544291, app);
-}
-
 export function SetCurrentPreset(presetID: string): $CancellablePromise<void> {
     return $Call.ByID(2103552966, presetID);
 }
 
+/**
+ * SetMovieOverrides replaces the processing overrides of a movie. Media and uploads made for the
+ * previous overrides are dropped, and a completed movie is queued again to redo them.
+ */
+export function SetMovieOverrides(movieID: string, overrides: $models.MovieOverrides): $CancellablePromise<void> {
+    return $Call.ByID(574968069, movieID, overrides);
+}
+
+/**
+ * SetTemplate saves the template of the current preset. Templates that do not parse are rejected
+ * with a *TemplateError.
+ */
 export function SetTemplate(template: string): $CancellablePromise<void> {
     return $Call.ByID(347943698, template);
 }
 
+/**
+ * SetTemplateMarkup sets the image markup of the current preset: bbcode, markdown, html or json
+ */
+export function SetTemplateMarkup(markup: string): $CancellablePromise<void> {
+    return $Call.ByID(4118775454, markup);
+}
+
+/**
+ * SetTemplateSections saves the header, per-movie template and footer of the current preset.
+ * Sections that do not parse are rejected with a *TemplateError.
+ */
+export function SetTemplateSections(header: string, template: string, footer: string): $CancellablePromise<void> {
+    return $Call.ByID(1928321114, header, template, footer);
+}
+
 export function StartProcessing(): $CancellablePromise<void> {
     return $Call.ByID(2555650935);
 }
 
+/**
+ * TestConnections checks with the current proxy and host settings whether every image host
+ * can be reached. The hosts are tested in parallel and reported in registration order.
+ */
+export function TestConnections(): $CancellablePromise<$models.ConnectionStatus[]> {
+    return $Call.ByID(4049097821).then(($result: any) => {
+        return $$createType6($result);
+    });
+}
+
 export function UpdateSettings(settings: $models.AppSettings): $CancellablePromise<void> {
     return $Call.ByID(1698034644, settings);
 }
 
+/**
+ * ValidateTemplate lints a template: syntax errors, unknown placeholders, placeholders that stay
+ * empty with the current settings, unbalanced BBCode tags and the uploads the template triggers
+ */
+export function ValidateTemplate(template: string): $CancellablePromise<$models.TemplateValidation> {
+    return $Call.ByID(474459440, template).then(($result: any) => {
+        return $$createType9($result);
+    });
+}
+
 // Private type creation functions
 const $$createType0 = $Create.Array($Create.Any);
 const $$createType1 = $models.AppSettings.createFrom;
 const $$createType2 = $models.AppState.createFrom;
 const $$createType3 = $models.TemplatePreset.createFrom;
 const $$createType4 = $Create.Array($$createType3);
+const $$createType5 = $models.ConnectionStatus.createFrom;
+const $$createType6 = $Create.Array($$createType5);
+const $$createType7 = $models.TemplateError.createFrom;
+const $$createType8 = $Create.Nullable($$createType7);
+const $$createType9 = $models.TemplateValidation.createFrom;
+const $$createType10 = $models.PlaceholderInfo.createFrom;
+const $$createType11 = $Create.Array($$createType10);
+const $$createType12 = $models.TemplatePreview.createFrom;
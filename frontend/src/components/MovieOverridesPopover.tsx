@@ -0,0 +1,166 @@
+import {
+  type Movie,
+  MovieOverrides,
+  SpoilerService,
+} from "@bindings/spoilr/backend";
+import { SlidersHorizontal } from "lucide-react";
+import { useState } from "react";
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import {
+  Popover,
+  PopoverContent,
+  PopoverTrigger,
+} from "@/components/ui/popover";
+import { Slider } from "@/components/ui/slider";
+import { useTranslation } from "@/contexts/LanguageContext";
+
+interface MovieOverridesPopoverProps {
+  movie: Movie;
+  disabled: boolean;
+}
+
+// hasOverrides reports whether any global screenshot setting is overridden
+function hasOverrides(overrides: MovieOverrides | undefined) {
+  return Boolean(
+    overrides &&
+      (overrides.timestamps ||
+        overrides.screenshotCount ||
+        overrides.startPercent ||
+        (overrides.endPercent && overrides.endPercent < 100)),
+  );
+}
+
+export default function MovieOverridesPopover({
+  movie,
+  disabled,
+}: MovieOverridesPopoverProps) {
+  const { t } = useTranslation();
+  const [isOpen, setIsOpen] = useState(false);
+  const [timestamps, setTimestamps] = useState("");
+  const [screenshotCount, setScreenshotCount] = useState(0);
+  const [range, setRange] = useState([0, 100]);
+  const [error, setError] = useState("");
+
+  // The form starts from the saved overrides every time it opens
+  const onOpenChange = (open: boolean) => {
+    if (open) {
+      const overrides = movie.overrides;
+      setTimestamps(overrides?.timestamps ?? "");
+      setScreenshotCount(overrides?.screenshotCount ?? 0);
+      setRange([overrides?.startPercent ?? 0, overrides?.endPercent || 100]);
+      setError("");
+    }
+    setIsOpen(open);
+  };
+
+  const save = async (overrides: MovieOverrides) => {
+    try {
+      await SpoilerService.SetMovieOverrides(movie.id, overrides);
+      setIsOpen(false);
+    } catch (err) {
+      setError(String(err));
+    }
+  };
+
+  const onSave = () =>
+    save(
+      new MovieOverrides({
+        timestamps: timestamps.trim(),
+        screenshotCount,
+        startPercent: range[0],
+        endPercent: range[1] === 100 ? 0 : range[1],
+      }),
+    );
+
+  const active = hasOverrides(movie.overrides);
+
+  return (
+    <Popover open={isOpen} onOpenChange={onOpenChange}>
+      <PopoverTrigger asChild>
+        <Button
+          size="sm"
+          variant="ghost"
+          disabled={disabled}
+          title={t("overrides.title")}
+          className={
+            active
+              ? "text-blue-400 hover:bg-blue-500/20"
+              : "hover:bg-blue-500/20 hover:text-blue-400"
+          }
+          onClick={(e) => e.stopPropagation()}
+        >
+          <SlidersHorizontal className="w-4 h-4" />
+        </Button>
+      </PopoverTrigger>
+      <PopoverContent className="w-[360px]" side="left" align="start">
+        <div className="space-y-4">
+          <div className="space-y-1">
+            <h4 className="text-sm font-medium">{t("overrides.title")}</h4>
+            <p className="text-xs text-muted-foreground truncate">
+              {movie.fileName}
+            </p>
+          </div>
+          <div className="space-y-2">
+            <Label className="text-sm font-medium">
+              {t("overrides.timestamps")}
+            </Label>
+            <Input
+              value={timestamps}
+              onChange={(e) => setTimestamps(e.target.value)}
+              placeholder="00:12:30, 45:10, 1:20:00"
+            />
+            <p className="text-xs text-muted-foreground">
+              {t("overrides.timestampsHint")}
+            </p>
+          </div>
+          <div className="space-y-2">
+            <Label className="text-sm font-medium">
+              {t("overrides.screenshotCount")}:{" "}
+              {screenshotCount || t("overrides.global")}
+            </Label>
+            <Slider
+              value={[screenshotCount]}
+              onValueChange={([value]) => setScreenshotCount(value)}
+              max={20}
+              min={0}
+              step={1}
+              disabled={timestamps.trim() !== ""}
+            />
+          </div>
+          <div className="space-y-2">
+            <Label className="text-sm font-medium">
+              {t("overrides.range")}: {range[0]}% - {range[1]}%
+            </Label>
+            <Slider
+              value={range}
+              onValueChange={setRange}
+              max={100}
+              min={0}
+              step={1}
+              minStepsBetweenThumbs={1}
+            />
+            <p className="text-xs text-muted-foreground">
+              {t("overrides.rangeHint")}
+            </p>
+          </div>
+          {error && <p className="text-xs text-red-400">{error}</p>}
+          <div className="flex justify-end gap-2">
+            <Button
+              size="sm"
+              variant="outline"
+              disabled={!active}
+              onClick={() => save(new MovieOverrides())}
+            >
+              {t("overrides.reset")}
+            </Button>
+            <Button size="sm" onClick={onSave}>
+              {t("overrides.save")}
+            </Button>
+          </div>
+        </div>
+      </PopoverContent>
+    </Popover>
+  );
+}
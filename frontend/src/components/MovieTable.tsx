@@ -38,6 +38,7 @@ import {
   TooltipContent,
   TooltipTrigger,
 } from "@/components/ui/tooltip";
+import MovieOverridesPopover from "@/components/MovieOverridesPopover";
 import { useTranslation } from "@/contexts/LanguageContext";
 
 function TruncatedFileName({ fileName }: { fileName: string }) {
@@ -392,10 +393,22 @@ export default function MovieTable({
       },
       cell: ({ row }) => {
         const movie = row.original;
-        return getProcessingBadge(
-          movie.processingState,
-          movie.processingError,
-          movie.errors,
+        return (
+          <div className="flex items-center gap-1">
+            {getProcessingBadge(
+              movie.processingState,
+              movie.processingError,
+              movie.errors,
+            )}
+            {movie.fileMissing && (
+              <Badge
+                variant="outline"
+                className="border-red-400/50 text-red-400"
+              >
+                {t("movieTable.status.fileMissing")}
+              </Badge>
+            )}
+          </div>
         );
       },
     },
@@ -419,6 +432,7 @@ export default function MovieTable({
                 <Copy className="w-4 h-4" />
               </Button>
             )}
+            <MovieOverridesPopover movie={movie} disabled={processing} />
             <Button
               size="sm"
               variant="ghost"
@@ -471,6 +485,11 @@ export default function MovieTable({
   const completedMovies = movies.filter(
     (m) => m.processingState === "completed",
   );
+  const failedCount = movies.filter(
+    (m) =>
+      m.processingState === "error" ||
+      (m.processingState === "completed" && (m.errors?.length ?? 0) > 0),
+  ).length;
 
   return (
     <Card
@@ -498,6 +517,24 @@ export default function MovieTable({
                 <TooltipContent>{t("movieTable.resetTooltip")}</TooltipContent>
               </Tooltip>
             )}
+            {failedCount > 0 && !processing && (
+              <Tooltip>
+                <TooltipTrigger>
+                  <Button
+                    onClick={() =>
+                      SpoilerService.RetryFailed().catch(console.error)
+                    }
+                    variant="outline"
+                    className="border-red-400/50 text-red-400 hover:bg-red-500/20"
+                  >
+                    {t("movieTable.retryFailed")} ({failedCount})
+                  </Button>
+                </TooltipTrigger>
+                <TooltipContent>
+                  {t("movieTable.retryFailedTooltip")}
+                </TooltipContent>
+              </Tooltip>
+            )}
             {!processing && (
               <Button
                 onClick={onClearMovies}
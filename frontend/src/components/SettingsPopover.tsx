@@ -1,6 +1,7 @@
-import type { AppSettings } from "@bindings/spoilr/backend";
+import type { AppSettings, ConnectionStatus } from "@bindings/spoilr/backend";
 import * as SpoilerService from "@bindings/spoilr/backend/spoilerservice";
-import { FolderOpen, X } from "lucide-react";
+import { CheckCircle2, FolderOpen, Loader2, X, XCircle } from "lucide-react";
+import { useState } from "react";
 import AnimatedText from "@/components/AnimatedText";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
@@ -10,11 +11,25 @@ import {
   PopoverContent,
   PopoverTrigger,
 } from "@/components/ui/popover";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 import { Separator } from "@/components/ui/separator";
 import { Slider } from "@/components/ui/slider";
+import { Switch } from "@/components/ui/switch";
 import { Textarea } from "@/components/ui/textarea";
 import { useTranslation } from "@/contexts/LanguageContext";
 
+// Screenshot output formats; PNG is lossless and has no quality setting
+const screenshotFormats = ["jpg", "png", "webp", "avif"];
+
+// Operators of the ffmpeg tonemap filter for HDR screenshots
+const tonemapAlgorithms = ["hable", "mobius", "reinhard"];
+
 interface SettingsPopoverProps {
   settings: AppSettings;
   onUpdateSettings: (settings: Partial<AppSettings>) => void;
@@ -25,9 +40,40 @@ export default function SettingsPopover({
   onUpdateSettings,
 }: SettingsPopoverProps) {
   const { t } = useTranslation();
+  const [testingConnections, setTestingConnections] = useState(false);
+  const [connections, setConnections] = useState<ConnectionStatus[]>([]);
+  const [cacheSize, setCacheSize] = useState<number | null>(null);
+
+  const refreshCacheSize = async () => {
+    try {
+      setCacheSize(await SpoilerService.GetUploadCacheSize());
+    } catch (err) {
+      console.error("Failed to read upload cache size:", err);
+    }
+  };
+
+  const clearUploadCache = async () => {
+    try {
+      await SpoilerService.PruneUploadCache(0);
+    } catch (err) {
+      console.error("Failed to clear upload cache:", err);
+    }
+    await refreshCacheSize();
+  };
+
+  const testConnections = async () => {
+    setTestingConnections(true);
+    try {
+      setConnections(await SpoilerService.TestConnections());
+    } catch (err) {
+      console.error("Failed to test connections:", err);
+    } finally {
+      setTestingConnections(false);
+    }
+  };
 
   return (
-    <Popover>
+    <Popover onOpenChange={(open) => open && refreshCacheSize()}>
       <PopoverTrigger className="cursor-pointer inline-flex items-center justify-center">
         <AnimatedText>{t("header.settings")}</AnimatedText>
       </PopoverTrigger>
@@ -50,6 +96,80 @@ export default function SettingsPopover({
                   step={1}
                 />
               </div>
+              <div className="space-y-2">
+                <div className="flex items-center justify-between gap-2">
+                  <Label
+                    htmlFor="screenshotPerChapter"
+                    className="text-sm font-medium"
+                  >
+                    {t("settings.screenshotPerChapter")}
+                  </Label>
+                  <Switch
+                    id="screenshotPerChapter"
+                    checked={settings.screenshotPerChapter}
+                    onCheckedChange={(checked) =>
+                      onUpdateSettings({ screenshotPerChapter: checked })
+                    }
+                  />
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.screenshotPerChapterDescription")}
+                </p>
+              </div>
+              <div className="space-y-2">
+                <div className="flex items-center justify-between gap-2">
+                  <Label
+                    htmlFor="smartFrameSelection"
+                    className="text-sm font-medium"
+                  >
+                    {t("settings.smartFrameSelection")}
+                  </Label>
+                  <Switch
+                    id="smartFrameSelection"
+                    checked={settings.smartFrameSelection}
+                    onCheckedChange={(checked) =>
+                      onUpdateSettings({ smartFrameSelection: checked })
+                    }
+                  />
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.smartFrameSelectionDescription")}
+                </p>
+              </div>
+              {settings.smartFrameSelection && (
+                <>
+                  <div className="space-y-2">
+                    <Label className="text-sm font-medium">
+                      {t("settings.smartFrameCandidates")}:{" "}
+                      {settings.smartFrameCandidates}
+                    </Label>
+                    <Slider
+                      value={[settings.smartFrameCandidates]}
+                      onValueChange={([value]) =>
+                        onUpdateSettings({ smartFrameCandidates: value })
+                      }
+                      max={20}
+                      min={2}
+                      step={1}
+                    />
+                  </div>
+                  <div className="space-y-2">
+                    <Label className="text-sm font-medium">
+                      {t("settings.smartFrameWindow")}:{" "}
+                      {settings.smartFrameWindowSeconds} s
+                    </Label>
+                    <Slider
+                      value={[settings.smartFrameWindowSeconds]}
+                      onValueChange={([value]) =>
+                        onUpdateSettings({ smartFrameWindowSeconds: value })
+                      }
+                      max={60}
+                      min={1}
+                      step={1}
+                    />
+                  </div>
+                </>
+              )}
               <div className="space-y-2">
                 <Label className="text-sm font-medium">
                   {t("settings.imageMiniatureSize")}:{" "}
@@ -65,19 +185,159 @@ export default function SettingsPopover({
                   step={50}
                 />
               </div>
-              <div className="space-y-2">
+              <div className="flex items-center justify-between">
                 <Label className="text-sm font-medium">
-                  {t("settings.quality")}: {settings.screenshotQuality}
+                  {t("settings.screenshotFormat")}
                 </Label>
-                <Slider
-                  value={[settings.screenshotQuality]}
-                  onValueChange={([value]) =>
-                    onUpdateSettings({ screenshotQuality: value })
+                <Select
+                  value={settings.screenshotFormat}
+                  onValueChange={(value) =>
+                    onUpdateSettings({ screenshotFormat: value })
                   }
-                  max={5}
-                  min={1}
-                  step={1}
-                />
+                >
+                  <SelectTrigger size="sm" className="h-7 w-24 text-xs">
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    {screenshotFormats.map((format) => (
+                      <SelectItem key={format} value={format} className="text-xs">
+                        {format.toUpperCase()}
+                      </SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+              </div>
+              {settings.screenshotFormat === "png" && (
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.pngDescription")}
+                </p>
+              )}
+              {settings.screenshotFormat === "jpg" && (
+                <div className="space-y-2">
+                  <Label className="text-sm font-medium">
+                    {t("settings.quality")}: {settings.screenshotQuality}
+                  </Label>
+                  <Slider
+                    value={[settings.screenshotQuality]}
+                    onValueChange={([value]) =>
+                      onUpdateSettings({ screenshotQuality: value })
+                    }
+                    max={5}
+                    min={1}
+                    step={1}
+                  />
+                </div>
+              )}
+              {settings.screenshotFormat === "webp" && (
+                <div className="space-y-2">
+                  <Label className="text-sm font-medium">
+                    {t("settings.quality")}: {settings.webpQuality}
+                  </Label>
+                  <Slider
+                    value={[settings.webpQuality]}
+                    onValueChange={([value]) =>
+                      onUpdateSettings({ webpQuality: value })
+                    }
+                    max={100}
+                    min={1}
+                    step={1}
+                  />
+                </div>
+              )}
+              {settings.screenshotFormat === "avif" && (
+                <div className="space-y-2">
+                  <Label className="text-sm font-medium">
+                    {t("settings.avifQuality")}: {settings.avifQuality}
+                  </Label>
+                  <Slider
+                    value={[settings.avifQuality]}
+                    onValueChange={([value]) =>
+                      onUpdateSettings({ avifQuality: value })
+                    }
+                    max={63}
+                    min={0}
+                    step={1}
+                  />
+                </div>
+              )}
+              <div className="space-y-2">
+                <div className="flex items-center justify-between gap-2">
+                  <Label htmlFor="tonemapHdr" className="text-sm font-medium">
+                    {t("settings.tonemapHdr")}
+                  </Label>
+                  <Switch
+                    id="tonemapHdr"
+                    checked={settings.tonemapHdr}
+                    onCheckedChange={(checked) =>
+                      onUpdateSettings({ tonemapHdr: checked })
+                    }
+                  />
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.tonemapHdrDescription")}
+                </p>
+              </div>
+              {settings.tonemapHdr && (
+                <>
+                  <div className="flex items-center justify-between">
+                    <Label className="text-sm font-medium">
+                      {t("settings.tonemapAlgorithm")}
+                    </Label>
+                    <Select
+                      value={settings.tonemapAlgorithm}
+                      onValueChange={(value) =>
+                        onUpdateSettings({ tonemapAlgorithm: value })
+                      }
+                    >
+                      <SelectTrigger size="sm" className="h-7 w-28 text-xs">
+                        <SelectValue />
+                      </SelectTrigger>
+                      <SelectContent>
+                        {tonemapAlgorithms.map((algorithm) => (
+                          <SelectItem
+                            key={algorithm}
+                            value={algorithm}
+                            className="text-xs"
+                          >
+                            {t(`settings.tonemapAlgorithms.${algorithm}`)}
+                          </SelectItem>
+                        ))}
+                      </SelectContent>
+                    </Select>
+                  </div>
+                  <div className="flex items-center justify-between gap-2">
+                    <Label
+                      htmlFor="keepUntonemapped"
+                      className="text-sm font-medium"
+                    >
+                      {t("settings.keepUntonemapped")}
+                    </Label>
+                    <Switch
+                      id="keepUntonemapped"
+                      checked={settings.keepUntonemapped}
+                      onCheckedChange={(checked) =>
+                        onUpdateSettings({ keepUntonemapped: checked })
+                      }
+                    />
+                  </div>
+                </>
+              )}
+              <div className="space-y-2">
+                <div className="flex items-center justify-between gap-2">
+                  <Label htmlFor="autoCrop" className="text-sm font-medium">
+                    {t("settings.autoCrop")}
+                  </Label>
+                  <Switch
+                    id="autoCrop"
+                    checked={settings.autoCrop}
+                    onCheckedChange={(checked) =>
+                      onUpdateSettings({ autoCrop: checked })
+                    }
+                  />
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.autoCropDescription")}
+                </p>
               </div>
               <div className="space-y-2">
                 <Label className="text-sm font-medium">
@@ -109,6 +369,20 @@ export default function SettingsPopover({
                   step={1}
                 />
               </div>
+              <div className="space-y-2">
+                <Label className="text-sm font-medium">
+                  {t("settings.uploadRetries")}: {settings.uploadRetries}
+                </Label>
+                <Slider
+                  value={[settings.uploadRetries]}
+                  onValueChange={([value]) =>
+                    onUpdateSettings({ uploadRetries: value })
+                  }
+                  max={10}
+                  min={0}
+                  step={1}
+                />
+              </div>
             </div>
           </div>
 
@@ -240,6 +514,90 @@ export default function SettingsPopover({
                   )}
                 </div>
               </div>
+
+              <Separator />
+
+              {/* Network Settings */}
+              <div className="space-y-2">
+                <Label htmlFor="proxy" className="text-sm font-medium">
+                  {t("settings.proxy")}
+                </Label>
+                <div className="flex gap-2">
+                  <Input
+                    id="proxy"
+                    value={settings.proxy || ""}
+                    onChange={(e) => onUpdateSettings({ proxy: e.target.value })}
+                    placeholder={t("settings.proxyPlaceholder")}
+                    className="flex-1 text-xs"
+                  />
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    onClick={testConnections}
+                    disabled={testingConnections}
+                  >
+                    {testingConnections && (
+                      <Loader2 className="h-4 w-4 animate-spin" />
+                    )}
+                    {t("settings.testConnection")}
+                  </Button>
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.proxyDescription")}
+                </p>
+                {connections.map((connection) => (
+                  <div
+                    key={connection.code}
+                    className="flex items-center gap-2 text-xs"
+                    title={connection.error}
+                  >
+                    {connection.reachable ? (
+                      <CheckCircle2 className="h-4 w-4 text-green-500" />
+                    ) : (
+                      <XCircle className="h-4 w-4 text-destructive" />
+                    )}
+                    <span className="font-medium">{connection.name}</span>
+                    <span className="text-muted-foreground truncate">
+                      {connection.reachable
+                        ? `${connection.latencyMs} ms`
+                        : connection.error}
+                    </span>
+                  </div>
+                ))}
+              </div>
+
+              <Separator />
+
+              {/* Upload Cache Settings */}
+              <div className="space-y-2">
+                <div className="flex items-center justify-between gap-2">
+                  <Label
+                    htmlFor="verifyCachedUploads"
+                    className="text-sm font-medium"
+                  >
+                    {t("settings.verifyCachedUploads")}
+                  </Label>
+                  <Switch
+                    id="verifyCachedUploads"
+                    checked={settings.verifyCachedUploads}
+                    onCheckedChange={(checked) =>
+                      onUpdateSettings({ verifyCachedUploads: checked })
+                    }
+                  />
+                </div>
+                <p className="text-xs text-muted-foreground">
+                  {t("settings.uploadCacheDescription")}
+                </p>
+                <Button
+                  variant="outline"
+                  size="sm"
+                  onClick={clearUploadCache}
+                  disabled={!cacheSize}
+                >
+                  {t("settings.clearUploadCache")}
+                  {cacheSize ? ` (${cacheSize})` : ""}
+                </Button>
+              </div>
             </div>
           </div>
         </div>
@@ -1,6 +1,20 @@
-import { SpoilerService } from "@bindings/spoilr/backend";
-import { Plus, RotateCcw, Save, X } from "lucide-react";
-import { useCallback, useEffect, useState } from "react";
+import {
+  type Movie,
+  type PlaceholderInfo,
+  SpoilerService,
+  TemplatePreset as TemplatePresetModel,
+  type TemplatePreview,
+  type TemplateValidation,
+} from "@bindings/spoilr/backend";
+import {
+  AlertCircle,
+  AlertTriangle,
+  Plus,
+  RotateCcw,
+  Save,
+  X,
+} from "lucide-react";
+import { useCallback, useEffect, useRef, useState } from "react";
 import AnimatedText from "@/components/AnimatedText";
 import { Badge } from "@/components/ui/badge";
 import { Button } from "@/components/ui/button";
@@ -10,6 +24,13 @@ import {
   PopoverContent,
   PopoverTrigger,
 } from "@/components/ui/popover";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 import { Separator } from "@/components/ui/separator";
 import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
 import { Textarea } from "@/components/ui/textarea";
@@ -24,41 +45,85 @@ interface TemplateEditorProps {
   onResetTemplate: () => void;
 }
 
-interface TemplateParam {
-  name: string;
-  description: string;
-  category?: string;
-}
-
 interface TemplatePreset {
   id: string;
   name: string;
+  header: string;
   template: string;
+  footer: string;
+  markup: string;
 }
 
+// Image markups a preset can render placeholders in
+const markupOptions = ["bbcode", "markdown", "html", "json"];
+
+// Sections of a preset: header and footer wrap the per-movie template
+type TemplateSection = "header" | "template" | "footer";
+type TemplateSections = Record<TemplateSection, string>;
+
+const templateSectionOrder: TemplateSection[] = ["header", "template", "footer"];
+
+// Select value of the built-in sample movie
+const sampleMovieId = "sample";
+
 export default function TemplateEditor({
   onResetTemplate,
 }: TemplateEditorProps) {
   const { t } = useTranslation();
   const [isOpen, setIsOpen] = useState(false);
-  const [currentTemplate, setCurrentTemplate] = useState("");
+  const [sections, setSections] = useState<TemplateSections>({
+    header: "",
+    template: "",
+    footer: "",
+  });
+  const [activeSection, setActiveSection] =
+    useState<TemplateSection>("template");
+  const currentTemplate = sections[activeSection];
+  const setCurrentTemplate = (value: string) =>
+    setSections((current) => ({ ...current, [activeSection]: value }));
+  const [markup, setMarkup] = useState("bbcode");
   const [cursorPosition, setCursorPosition] = useState(0);
   const [presets, setPresets] = useState<TemplatePreset[]>([]);
   const [currentPresetId, setCurrentPresetId] = useState<string>("");
   const [newPresetName, setNewPresetName] = useState("");
   const [isSavingPreset, setIsSavingPreset] = useState(false);
   const [showNewPreset, setShowNewPreset] = useState(false);
+  const [validation, setValidation] = useState<TemplateValidation | null>(
+    null,
+  );
+  const templateError = validation?.error ?? null;
+  const textareaRef = useRef<HTMLTextAreaElement>(null);
+  const [catalog, setCatalog] = useState<PlaceholderInfo[]>([]);
+  const [movies, setMovies] = useState<Movie[]>([]);
+  const [previewMovieId, setPreviewMovieId] = useState(sampleMovieId);
+  const [preview, setPreview] = useState<TemplatePreview | null>(null);
 
   const loadPresetsAndCurrentTemplate = useCallback(async () => {
     try {
-      const [loadedPresets, currentPreset, template] = await Promise.all([
-        SpoilerService.GetTemplatePresets(),
-        SpoilerService.GetCurrentPresetID(),
-        SpoilerService.GetTemplate(),
-      ]);
+      const [loadedPresets, currentPreset, template, placeholders, state] =
+        await Promise.all([
+          SpoilerService.GetTemplatePresets(),
+          SpoilerService.GetCurrentPresetID(),
+          SpoilerService.GetTemplate(),
+          SpoilerService.GetPlaceholderCatalog(),
+          SpoilerService.GetState(),
+        ]);
       setPresets(loadedPresets);
+      setCatalog(placeholders);
+      setMovies(state.movies);
+      setPreviewMovieId((current) =>
+        state.movies.some((movie) => movie.id === current)
+          ? current
+          : sampleMovieId,
+      );
+      const preset = loadedPresets.find((p) => p.id === currentPreset);
       setCurrentPresetId(currentPreset);
-      setCurrentTemplate(template);
+      setSections({
+        header: preset?.header ?? "",
+        template,
+        footer: preset?.footer ?? "",
+      });
+      setMarkup(preset?.markup || "bbcode");
     } catch (error) {
       console.error("Failed to load template data:", error);
     }
@@ -70,209 +135,118 @@ export default function TemplateEditor({
     }
   }, [isOpen, loadPresetsAndCurrentTemplate]);
 
-  const templateParams: TemplateParam[] = [
-    // File Information
-    {
-      name: "%FILE_NAME%",
-      description: t("templateEditor.parameters.fileName"),
-      category: "File Info",
-    },
-    {
-      name: "%FILE_SIZE%",
-      description: t("templateEditor.parameters.fileSize"),
-      category: "File Info",
-    },
-    {
-      name: "%DURATION%",
-      description: t("templateEditor.parameters.duration"),
-      category: "File Info",
-    },
-
-    // Video Information
-    {
-      name: "%WIDTH%",
-      description: t("templateEditor.parameters.width"),
-      category: "Video",
-    },
-    {
-      name: "%HEIGHT%",
-      description: t("templateEditor.parameters.height"),
-      category: "Video",
-    },
-    {
-      name: "%BIT_RATE%",
-      description: t("templateEditor.parameters.bitRate"),
-      category: "Video",
-    },
-    {
-      name: "%VIDEO_BIT_RATE%",
-      description: t("templateEditor.parameters.videoBitRate"),
-      category: "Video",
-    },
-    {
-      name: "%VIDEO_CODEC%",
-      description: t("templateEditor.parameters.videoCodec"),
-      category: "Video",
-    },
-    {
-      name: "%VIDEO_FPS%",
-      description: t("templateEditor.parameters.videoFps"),
-      category: "Video",
-    },
-    {
-      name: "%VIDEO_FPS_FRACTIONAL%",
-      description: t("templateEditor.parameters.videoFpsFractional"),
-      category: "Video",
-    },
-
-    // Audio Information
-    {
-      name: "%AUDIO_BIT_RATE%",
-      description: t("templateEditor.parameters.audioBitRate"),
-      category: "Audio",
-    },
-    {
-      name: "%AUDIO_CODEC%",
-      description: t("templateEditor.parameters.audioCodec"),
-      category: "Audio",
-    },
-    {
-      name: "%AUDIO_SAMPLE_RATE%",
-      description: t("templateEditor.parameters.audioSampleRate"),
-      category: "Audio",
-    },
-    {
-      name: "%AUDIO_CHANNELS%",
-      description: t("templateEditor.parameters.audioChannels"),
-      category: "Audio",
-    },
-
-    // Contact Sheets (MTN-generated grids)
-    {
-      name: "%CONTACT_SHEET_FP%",
-      description: t("templateEditor.parameters.contactSheetFp"),
-      category: "Contact Sheets",
-    },
-    {
-      name: "%CONTACT_SHEET_FP_BIG%",
-      description: t("templateEditor.parameters.contactSheetFpBig"),
-      category: "Contact Sheets",
-    },
-    {
-      name: "%CONTACT_SHEET_IB%",
-      description: t("templateEditor.parameters.contactSheetIb"),
-      category: "Contact Sheets",
-    },
-    {
-      name: "%CONTACT_SHEET_IB_BIG%",
-      description: t("templateEditor.parameters.contactSheetIbBig"),
-      category: "Contact Sheets",
-    },
-    {
-      name: "%CONTACT_SHEET_HAM%",
-      description: t("templateEditor.parameters.contactSheetHam"),
-      category: "Contact Sheets",
-    },
-    {
-      name: "%CONTACT_SHEET_HAM_BIG%",
-      description: t("templateEditor.parameters.contactSheetHamBig"),
-      category: "Contact Sheets",
-    },
-
-    // Fastpic Screenshots
-    {
-      name: "%SCREENSHOTS_FP%",
-      description: t("templateEditor.parameters.screenshotsFp"),
-      category: "Fastpic Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_FP_SPACED%",
-      description: t("templateEditor.parameters.screenshotsFpSpaced"),
-      category: "Fastpic Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_FP_BIG%",
-      description: t("templateEditor.parameters.screenshotsFpBig"),
-      category: "Fastpic Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_FP_BIG_SPACED%",
-      description: t("templateEditor.parameters.screenshotsFpBigSpaced"),
-      category: "Fastpic Screenshots",
-    },
-
-    // Imgbox Screenshots
-    {
-      name: "%SCREENSHOTS_IB%",
-      description: t("templateEditor.parameters.screenshotsIb"),
-      category: "Imgbox Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_IB_SPACED%",
-      description: t("templateEditor.parameters.screenshotsIbSpaced"),
-      category: "Imgbox Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_IB_BIG%",
-      description: t("templateEditor.parameters.screenshotsIbBig"),
-      category: "Imgbox Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_IB_BIG_SPACED%",
-      description: t("templateEditor.parameters.screenshotsIbBigSpaced"),
-      category: "Imgbox Screenshots",
-    },
+  // Lint and preview the template shortly after the user stops typing
+  useEffect(() => {
+    if (!isOpen) return;
+    const timer = setTimeout(async () => {
+      try {
+        const [lint, rendered] = await Promise.all([
+          SpoilerService.ValidateTemplate(currentTemplate),
+          SpoilerService.RenderTemplatePreview(
+            currentTemplate,
+            markup,
+            previewMovieId === sampleMovieId ? "" : previewMovieId,
+          ),
+        ]);
+        setValidation(lint);
+        setPreview(rendered);
+      } catch (error) {
+        console.error("Failed to validate template:", error);
+      }
+    }, 300);
+    return () => clearTimeout(timer);
+  }, [isOpen, currentTemplate, markup, previewMovieId]);
+
+  const templateWarnings = validation
+    ? [
+        ...validation.unknownPlaceholders.map((issue) => ({
+          ...issue,
+          message: `${issue.name}: ${issue.message}`,
+        })),
+        ...validation.emptyPlaceholders.map((issue) => ({
+          ...issue,
+          message: `${issue.name}: ${t("templateEditor.alwaysEmpty")} (${issue.message})`,
+        })),
+        ...validation.unbalancedTags,
+      ]
+    : [];
+
+  // Move the cursor to a 1-based line and column of the template
+  const focusTemplatePosition = (line: number, column: number) => {
+    const textarea = textareaRef.current;
+    if (!textarea) return;
+    const lines = currentTemplate.split("\n");
+    let position = column - 1;
+    for (let i = 0; i < line - 1 && i < lines.length; i++) {
+      position += lines[i].length + 1;
+    }
+    textarea.focus();
+    textarea.setSelectionRange(position, position);
+    setCursorPosition(position);
+  };
 
-    // Hamster Screenshots
-    {
-      name: "%SCREENSHOTS_HAM%",
-      description: t("templateEditor.parameters.screenshotsHam"),
-      category: "Hamster Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_HAM_SPACED%",
-      description: t("templateEditor.parameters.screenshotsHamSpaced"),
-      category: "Hamster Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_HAM_BIG%",
-      description: t("templateEditor.parameters.screenshotsHamBig"),
-      category: "Hamster Screenshots",
-    },
-    {
-      name: "%SCREENSHOTS_HAM_BIG_SPACED%",
-      description: t("templateEditor.parameters.screenshotsHamBigSpaced"),
-      category: "Hamster Screenshots",
-    },
-  ];
+  // Prefer the translated description, e.g. templateEditor.parameters.screenshotsFpBig
+  const describePlaceholder = (placeholder: PlaceholderInfo) => {
+    const key = `templateEditor.parameters.${placeholder.name
+      .replace(/%/g, "")
+      .toLowerCase()
+      .replace(/_([a-z])/g, (_, letter: string) => letter.toUpperCase())}`;
+    const translated = t(key);
+    return translated === key ? placeholder.description : translated;
+  };
 
-  const groupedParams = templateParams.reduce(
+  const groupedParams = catalog.reduce(
     (acc, param) => {
-      const category = param.category || "Other";
-      if (!acc[category]) {
-        acc[category] = [];
+      if (!acc[param.category]) {
+        acc[param.category] = [];
       }
-      acc[category].push(param);
+      acc[param.category].push(param);
       return acc;
     },
-    {} as Record<string, TemplateParam[]>,
+    {} as Record<string, PlaceholderInfo[]>,
   );
 
-  const categoryOrder = [
-    "File Info",
-    "Video",
-    "Audio",
-    "Contact Sheets",
-    "Fastpic Screenshots",
-    "Imgbox Screenshots",
-    "Hamster Screenshots",
-  ];
+  // Categories in the order the backend lists them
+  const categoryOrder = Object.keys(groupedParams);
+
+  // The unfinished %PLACEHOLDER the cursor is in, if any
+  const textBeforeCursor = currentTemplate.slice(0, cursorPosition);
+  const partialPlaceholder = textBeforeCursor.match(/%[\w@]*$/)?.[0] ?? "";
+  const lineBeforePartial = textBeforeCursor
+    .slice(0, textBeforeCursor.length - partialPlaceholder.length)
+    .split("\n")
+    .pop();
+  const suggestions =
+    partialPlaceholder.length > 1 &&
+    (lineBeforePartial?.split("%").length ?? 1) % 2 === 1
+      ? catalog
+          .filter(
+            (placeholder) =>
+              placeholder.name
+                .toLowerCase()
+                .startsWith(partialPlaceholder.toLowerCase()) &&
+              placeholder.name !== `${partialPlaceholder}%`,
+          )
+          .slice(0, 8)
+      : [];
+
+  const handleSuggestionClick = (param: string) => {
+    const start = cursorPosition - partialPlaceholder.length;
+    const newValue =
+      currentTemplate.substring(0, start) +
+      param +
+      currentTemplate.substring(cursorPosition);
+    setCurrentTemplate(newValue);
+    setCursorPosition(start + param.length);
+    textareaRef.current?.focus();
+  };
 
   const handleOpenChange = (open: boolean) => {
     setIsOpen(open);
     if (open) {
       setNewPresetName("");
       setShowNewPreset(false);
+      setActiveSection("template");
     }
   };
 
@@ -301,7 +275,12 @@ export default function TemplateEditor({
 
   const handleSaveTemplate = async () => {
     try {
-      await SpoilerService.SetTemplate(currentTemplate);
+      await SpoilerService.SetTemplateSections(
+        sections.header,
+        sections.template,
+        sections.footer,
+      );
+      await SpoilerService.SetTemplateMarkup(markup);
       setIsOpen(false);
     } catch (error) {
       console.error("Failed to save template:", error);
@@ -311,7 +290,12 @@ export default function TemplateEditor({
   const handlePresetClick = async (preset: TemplatePreset) => {
     try {
       await SpoilerService.SetCurrentPreset(preset.id);
-      setCurrentTemplate(preset.template);
+      setSections({
+        header: preset.header,
+        template: preset.template,
+        footer: preset.footer,
+      });
+      setMarkup(preset.markup || "bbcode");
       setCurrentPresetId(preset.id);
     } catch (error) {
       console.error("Failed to set current preset:", error);
@@ -324,8 +308,11 @@ export default function TemplateEditor({
     setIsSavingPreset(true);
     try {
       await SpoilerService.SaveTemplatePreset(
-        newPresetName.trim(),
-        currentTemplate,
+        new TemplatePresetModel({
+          name: newPresetName.trim(),
+          ...sections,
+          markup,
+        }),
       );
       await loadPresetsAndCurrentTemplate(); // Reload data from backend
       setNewPresetName("");
@@ -392,7 +379,11 @@ export default function TemplateEditor({
                   />
                   <Button
                     onClick={handleSavePreset}
-                    disabled={!newPresetName.trim() || isSavingPreset}
+                    disabled={
+                      !newPresetName.trim() ||
+                      isSavingPreset ||
+                      templateError !== null
+                    }
                     size="sm"
                     className="h-6 px-2 text-xs"
                   >
@@ -420,7 +411,12 @@ export default function TemplateEditor({
                 <RotateCcw className="w-3 h-3 mr-1" />
                 {t("templateEditor.resetToDefault")}
               </Button>
-              <Button onClick={handleSaveTemplate} size="sm" className="h-8">
+              <Button
+                onClick={handleSaveTemplate}
+                disabled={templateError !== null}
+                size="sm"
+                className="h-8"
+              >
                 <Save className="w-4 h-4" />
                 {t("templateEditor.saveTemplate")}
               </Button>
@@ -461,23 +457,178 @@ export default function TemplateEditor({
 
           <Separator />
 
+          {/* Section Switcher and Markup */}
+          <div className="flex items-center justify-between gap-2">
+            <Tabs
+              value={activeSection}
+              onValueChange={(value) => {
+                setActiveSection(value as TemplateSection);
+                setCursorPosition(0);
+              }}
+            >
+              <TabsList className="h-8">
+                {templateSectionOrder.map((section) => (
+                  <TabsTrigger
+                    key={section}
+                    value={section}
+                    className="text-xs px-3"
+                  >
+                    {t(`templateEditor.sections.${section}`)}
+                    {section !== "template" &&
+                      sections[section].trim() &&
+                      " •"}
+                  </TabsTrigger>
+                ))}
+              </TabsList>
+            </Tabs>
+            <div className="flex items-center gap-2">
+              <span className="text-xs text-muted-foreground">
+                {t("templateEditor.markup")}
+              </span>
+              <Select value={markup} onValueChange={setMarkup}>
+                <SelectTrigger size="sm" className="h-7 w-32 text-xs">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  {markupOptions.map((option) => (
+                    <SelectItem key={option} value={option} className="text-xs">
+                      {t(`templateEditor.markups.${option}`)}
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+            </div>
+          </div>
+
           {/* Template Textarea */}
           <Textarea
+            ref={textareaRef}
             value={currentTemplate}
+            aria-invalid={templateError !== null}
             onChange={handleTextareaChange}
             onSelect={handleTextareaSelect}
             onKeyUp={handleTextareaSelect}
             onClick={handleTextareaSelect}
             className="min-h-[100px] font-mono text-sm resize-none"
-            placeholder={t("templateEditor.placeholder")}
+            placeholder={
+              activeSection === "template"
+                ? t("templateEditor.placeholder")
+                : t("templateEditor.sectionPlaceholder")
+            }
           />
+          {suggestions.length > 0 && (
+            <div className="flex flex-wrap gap-1">
+              {suggestions.map((suggestion) => (
+                <Tooltip key={suggestion.name}>
+                  <TooltipTrigger asChild>
+                    <button
+                      type="button"
+                      onClick={() => handleSuggestionClick(suggestion.name)}
+                      className="rounded border px-2 py-1 text-xs font-mono transition-colors hover:bg-accent hover:text-accent-foreground"
+                    >
+                      {suggestion.name}
+                    </button>
+                  </TooltipTrigger>
+                  <TooltipContent side="bottom">
+                    <p className="text-xs">{describePlaceholder(suggestion)}</p>
+                  </TooltipContent>
+                </Tooltip>
+              ))}
+            </div>
+          )}
+          {templateError && (
+            <button
+              type="button"
+              onClick={() =>
+                focusTemplatePosition(templateError.line, templateError.column)
+              }
+              className="flex w-full items-start gap-2 text-left text-xs text-destructive"
+            >
+              <AlertCircle className="h-4 w-4 shrink-0" />
+              <span className="font-mono">
+                {t("templateEditor.position")
+                  .replace("{line}", String(templateError.line))
+                  .replace("{column}", String(templateError.column))}{" "}
+                {templateError.message}
+              </span>
+            </button>
+          )}
+          {templateWarnings.map((warning) => (
+            <button
+              key={`${warning.line}:${warning.column}:${warning.message}`}
+              type="button"
+              onClick={() => focusTemplatePosition(warning.line, warning.column)}
+              className="flex w-full items-start gap-2 text-left text-xs text-yellow-400"
+            >
+              <AlertTriangle className="h-4 w-4 shrink-0" />
+              <span className="font-mono">
+                {t("templateEditor.position")
+                  .replace("{line}", String(warning.line))
+                  .replace("{column}", String(warning.column))}{" "}
+                {warning.message}
+              </span>
+            </button>
+          ))}
+          {validation && validation.hosts.length > 0 && (
+            <div className="text-xs text-muted-foreground">
+              {t("templateEditor.uploadsTo")}{" "}
+              {validation.hosts
+                .map((host) =>
+                  [
+                    host.contactSheet && t("templateEditor.contactSheet"),
+                    host.screenshots && t("templateEditor.screenshots"),
+                  ]
+                    .filter(Boolean)
+                    .join(" + ")
+                    .concat(` → ${host.name}`),
+                )
+                .join(", ")}
+            </div>
+          )}
+
+          {/* Preview */}
+          <div className="space-y-2">
+            <div className="flex items-center justify-between gap-2">
+              <div className="text-xs text-muted-foreground font-medium">
+                {t("templateEditor.preview")}
+              </div>
+              <Select value={previewMovieId} onValueChange={setPreviewMovieId}>
+                <SelectTrigger size="sm" className="h-7 w-64 text-xs">
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value={sampleMovieId} className="text-xs">
+                    {t("templateEditor.sampleMovie")}
+                  </SelectItem>
+                  {movies.map((movie) => (
+                    <SelectItem
+                      key={movie.id}
+                      value={movie.id}
+                      className="text-xs"
+                    >
+                      {movie.fileName}
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+            </div>
+            {preview && !preview.error && (
+              <pre className="max-h-48 overflow-auto whitespace-pre-wrap break-all rounded-md border bg-muted/50 p-2 text-xs font-mono">
+                {preview.result}
+              </pre>
+            )}
+          </div>
 
           {/* Parameters Tabs */}
           <div className="space-y-2">
             <div className="text-xs text-muted-foreground font-medium">
               {t("templateEditor.parametersLabel")}
             </div>
-            <Tabs defaultValue={categoryOrder[0]} className="w-full">
+            <Tabs
+              key={categoryOrder[0]}
+              defaultValue={categoryOrder[0]}
+              className="w-full"
+            >
               <TabsList className="flex w-full h-auto flex-wrap justify-start">
                 {categoryOrder.map((category) => {
                   const params = groupedParams[category];
@@ -513,7 +664,14 @@ export default function TemplateEditor({
                             </button>
                           </TooltipTrigger>
                           <TooltipContent side="top">
-                            <p className="text-xs">{param.description}</p>
+                            <p className="text-xs">
+                              {describePlaceholder(param)}
+                            </p>
+                            {param.example && (
+                              <p className="max-w-sm truncate text-xs font-mono opacity-70">
+                                {t("templateEditor.example")} {param.example}
+                              </p>
+                            )}
                           </TooltipContent>
                         </Tooltip>
                       ))}
@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
@@ -62,6 +63,16 @@ func ensureFFmpeg() error {
 }
 
 func main() {
+	// Headless commands run without the window
+	if len(os.Args) > 1 && backend.IsCLICommand(os.Args[1]) {
+		backend.AttachConsole()
+		if err := ensureFFmpeg(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(backend.RunCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	if err := ensureWebView2(); err != nil {
 		showErrorDialog("WebView2 Required", err.Error())
 		return
@@ -73,7 +84,11 @@ func main() {
 		return
 	}
 
-	spoilerService := backend.NewSpoilerService()
+	eventSink := &wailsEventSink{}
+	spoilerService := backend.NewSpoilerService(
+		backend.WithEventSink(eventSink),
+		backend.WithDialogProvider(wailsDialogProvider{events: eventSink}),
+	)
 
 	app := application.New(application.Options{
 		Name:        "Spoilr",
@@ -89,7 +104,7 @@ func main() {
 		},
 	})
 
-	spoilerService.SetApp(app)
+	eventSink.app.Store(app)
 
 	window := app.Window.NewWithOptions(application.WebviewWindowOptions{
 		Title:          "Spoilr",
@@ -0,0 +1,184 @@
+package img_uploaders
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"spoilr/backend"
+	"strings"
+	"testing"
+)
+
+// cliEnv is a config pointing every host at a fake one, with a preset named CLI and two
+// videos in a folder
+type cliEnv struct {
+	hosts    map[string]*fakeHost
+	videoDir string
+}
+
+func newCLIEnv(t *testing.T) *cliEnv {
+	t.Helper()
+	installFakeTools(t)
+	isolateUserDirs(t)
+
+	env := &cliEnv{
+		hosts: map[string]*fakeHost{
+			"FP":  newFakeFastpic(t, 0),
+			"IB":  newFakeImgbox(t),
+			"HAM": newFakeHamster(t),
+		},
+		videoDir: t.TempDir(),
+	}
+	for _, name := range []string{"Episode 01.mkv", "Episode 02.mkv"} {
+		if err := os.WriteFile(filepath.Join(env.videoDir, name), []byte("not really a video"), 0644); err != nil {
+			t.Fatalf("Failed to create video: %v", err)
+		}
+	}
+
+	configService := backend.NewConfigService()
+	config := configService.GetConfig()
+	config.ScreenshotCount = 3
+	config.HamsterEmail = "user@example.com"
+	config.HamsterPassword = "secret"
+	config.Hosts = make(map[string]backend.HostConfig)
+	for code, host := range env.hosts {
+		config.Hosts[code] = backend.HostConfig{BaseURL: host.URL}
+	}
+	config.TemplatePresets = append(config.TemplatePresets, backend.TemplatePreset{
+		ID:       "cli",
+		Name:     "CLI",
+		Template: "%FILE_NAME% %DURATION%\n%CONTACT_SHEET_FP%\n%SCREENSHOTS_IB%",
+	})
+	if err := configService.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	return env
+}
+
+// run runs the command line and returns the exit code, stdout and stderr
+func (e *cliEnv) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := backend.RunCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIUsageErrors(t *testing.T) {
+	env := newCLIEnv(t)
+
+	tests := []struct {
+		name   string
+		args   []string
+		stderr string
+	}{
+		{"no command", nil, "Usage: spoilr generate"},
+		{"unknown command", []string{"upload", env.videoDir}, "Usage: spoilr generate"},
+		{"no paths", []string{"generate", "--preset", "CLI"}, "Usage: spoilr generate"},
+		{"unknown flag", []string{"generate", env.videoDir, "--color"}, "flag provided but not defined: -color"},
+		{"unknown host", []string{"generate", env.videoDir, "--hosts", "ib,xx"}, `unknown host "xx" (available: fp, ham, ib)`},
+		{"unknown preset", []string{"generate", env.videoDir, "--preset", "Nope"}, `template preset "Nope" not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := env.run(tt.args...)
+			if code != 2 {
+				t.Errorf("Exit code = %d, want 2", code)
+			}
+			if stdout != "" {
+				t.Errorf("Stdout = %q, want nothing", stdout)
+			}
+			if !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("Stderr =\n%s\nwant it to contain %q", stderr, tt.stderr)
+			}
+		})
+	}
+
+	for code, host := range env.hosts {
+		if attempts := host.Attempts(); attempts != 0 {
+			t.Errorf("%s received %d uploads from rejected commands", code, attempts)
+		}
+	}
+}
+
+func TestCLIGenerate(t *testing.T) {
+	env := newCLIEnv(t)
+
+	code, stdout, stderr := env.run("generate", env.videoDir, "--preset", "cli")
+	if code != 0 {
+		t.Fatalf("Exit code = %d, want 0; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		"Episode 01.mkv 10:00\n[URL=https://fastpic.test/view/",
+		"Episode 02.mkv 10:00\n",
+		"[IMG]https://thumbs.imgbox.test/",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Stdout does not contain %q:\n%s", want, stdout)
+		}
+	}
+	for _, want := range []string{"[2/2] Episode 02.mkv: completed", "Done: 2 completed, 0 failed"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Stderr does not contain %q:\n%s", want, stderr)
+		}
+	}
+	// Every host in the template gets the two contact sheets and six screenshots
+	want := map[string]int{"FP": 8, "IB": 8, "HAM": 0}
+	for code, host := range env.hosts {
+		if got := len(host.Uploads()); got != want[code] {
+			t.Errorf("%s received %d uploads, want %d", code, got, want[code])
+		}
+	}
+}
+
+func TestCLIGenerateToFileWithHostFilter(t *testing.T) {
+	env := newCLIEnv(t)
+
+	// Flags may follow the paths
+	out := filepath.Join(t.TempDir(), "spoilers.txt")
+	code, stdout, stderr := env.run("generate", filepath.Join(env.videoDir, "Episode 02.mkv"), "--hosts", "ib", "--out", out, "--preset", "CLI")
+	if code != 0 {
+		t.Fatalf("Exit code = %d, want 0; stderr:\n%s", code, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("Stdout = %q, want the result in the file only", stdout)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Failed to read result: %v", err)
+	}
+	result := string(data)
+	if !strings.HasPrefix(result, "Episode 02.mkv 10:00\n") || !strings.Contains(result, "https://thumbs.imgbox.test/") {
+		t.Errorf("Result =\n%s\nwant the imgbox screenshots of Episode 02.mkv", result)
+	}
+	if strings.Contains(result, "fastpic") {
+		t.Errorf("Result has fastpic links although only imgbox was selected:\n%s", result)
+	}
+	if uploads := len(env.hosts["FP"].Uploads()); uploads != 0 {
+		t.Errorf("fastpic received %d uploads, want none", uploads)
+	}
+}
+
+func TestCLIFailedMovieExitCode(t *testing.T) {
+	env := newCLIEnv(t)
+	env.hosts["IB"].RejectUploads(func(file *multipart.FileHeader) int {
+		if file.Filename == "Episode 01_screenshot_2.jpg" {
+			return http.StatusRequestEntityTooLarge
+		}
+		return 0
+	})
+
+	code, stdout, stderr := env.run("generate", env.videoDir, "--preset", "CLI")
+	if code != 1 {
+		t.Errorf("Exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stdout, "Episode 02.mkv 10:00") {
+		t.Errorf("Stdout =\n%s\nwant the result of the completed movie", stdout)
+	}
+	for _, want := range []string{"Episode 01.mkv: completed with errors\n  - ", "Done: 1 completed, 1 failed"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("Stderr does not contain %q:\n%s", want, stderr)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package img_uploaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// fakeHost is a local stand-in for an image host that counts the uploaded files
+type fakeHost struct {
+	*httptest.Server
+	mu       sync.Mutex
+	uploads  []*multipart.FileHeader // Files in upload order
+	attempts int                     // Upload requests with a file, rejected ones included
+	reject   func(file *multipart.FileHeader) int
+}
+
+// RejectUploads answers upload requests with the status code reject returns for the file,
+// or accepts them when it returns 0. Nil accepts everything.
+func (h *fakeHost) RejectUploads(reject func(file *multipart.FileHeader) int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reject = reject
+}
+
+// rejected counts an upload attempt and answers it with an error status if it is rejected
+func (h *fakeHost) rejected(w http.ResponseWriter, file *multipart.FileHeader) bool {
+	h.mu.Lock()
+	h.attempts++
+	status := 0
+	if h.reject != nil {
+		status = h.reject(file)
+	}
+	h.mu.Unlock()
+
+	if status == 0 {
+		return false
+	}
+	http.Error(w, http.StatusText(status), status)
+	return true
+}
+
+// Attempts returns the number of upload requests with a file, rejected ones included
+func (h *fakeHost) Attempts() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.attempts
+}
+
+func (h *fakeHost) recordUpload(file *multipart.FileHeader) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.uploads = append(h.uploads, file)
+	return len(h.uploads)
+}
+
+// Uploads returns the names of the uploaded files
+func (h *fakeHost) Uploads() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, len(h.uploads))
+	for i, file := range h.uploads {
+		names[i] = file.Filename
+	}
+	return names
+}
+
+// UploadedFiles returns the headers of the uploaded files, with their content type and size
+func (h *fakeHost) UploadedFiles() []*multipart.FileHeader {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*multipart.FileHeader(nil), h.uploads...)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// uploadedFile returns the multipart file in the field, or nil if it is missing or empty
+func uploadedFile(r *http.Request, field string) *multipart.FileHeader {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		return nil
+	}
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil
+	}
+	file.Close()
+	if header.Size == 0 {
+		return nil
+	}
+	return header
+}
+
+// newFakeFastpic serves the upload page with an upload_id script and answers uploads with
+// fastpic style image codes that keep the extension of the uploaded file. The first expireUploads uploads get the empty answer fastpic
+// gives for an expired upload ID.
+func newFakeFastpic(t *testing.T, expireUploads int) *fakeHost {
+	t.Helper()
+	host := &fakeHost{}
+	var mu sync.Mutex
+	pageLoads := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pageLoads++
+		uploadID := fmt.Sprintf("fake-upload-%d", pageLoads)
+		mu.Unlock()
+
+		http.SetCookie(w, &http.Cookie{Name: "fp_sid", Value: "fake-sid", Path: "/"})
+		fmt.Fprintf(w, `<html><body><script>var settings = {"upload_id": '%s', "max_files": 30};</script></body></html>`, uploadID)
+	})
+	mux.HandleFunc("POST /v2upload/", func(w http.ResponseWriter, r *http.Request) {
+		file := uploadedFile(r, "file1")
+		if file == nil || r.FormValue("upload_id") == "" {
+			http.Error(w, "missing file or upload_id", http.StatusBadRequest)
+			return
+		}
+
+		if host.rejected(w, file) {
+			return
+		}
+
+		mu.Lock()
+		expired := expireUploads > 0
+		if expired {
+			expireUploads--
+		}
+		mu.Unlock()
+		if expired {
+			writeJSON(w, map[string]string{"codes": ""})
+			return
+		}
+
+		n := host.recordUpload(file)
+		ext := filepath.Ext(file.Filename)
+		view := fmt.Sprintf("https://fastpic.test/view/%d.html", n)
+		thumb := fmt.Sprintf("https://i.fastpic.test/thumb/%d%s", n, ext)
+		big := fmt.Sprintf("https://i.fastpic.test/big/%d%s", n, ext)
+		codes := fmt.Sprintf(`<input type="text" value="%s">`+
+			`<input type="text" value="[URL=%s][IMG]%s[/IMG][/URL]">`+
+			`<input type="text" value="[URL=%s][IMG]%s[/IMG][/URL]">`,
+			big, view, thumb, view, big)
+		writeJSON(w, map[string]string{
+			"thumb_link": fmt.Sprintf("/thumb/%d", n),
+			"view_link":  fmt.Sprintf("/view/%d", n),
+			"album_link": "/album/fake",
+			"codes":      codes,
+		})
+	})
+
+	host.Server = httptest.NewServer(mux)
+	t.Cleanup(host.Close)
+	return host
+}
+
+// newFakeImgbox serves the CSRF token page, the token endpoint and the upload endpoint of imgbox
+func newFakeImgbox(t *testing.T) *fakeHost {
+	t.Helper()
+	host := &fakeHost{}
+	const csrfToken = "fake-csrf-token-0123456789"
+	const tokenSecret = "fake-token-secret"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body><form><input type="hidden" name="authenticity_token" value="%s"></form></body></html>`, csrfToken)
+	})
+	mux.HandleFunc("POST /ajax/token/generate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Csrf-Token") != csrfToken {
+			http.Error(w, "invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true,"token_id":12345678,"token_secret":"%s"}`, tokenSecret)
+	})
+	mux.HandleFunc("POST /upload/process", func(w http.ResponseWriter, r *http.Request) {
+		file := uploadedFile(r, "files[]")
+		if file == nil || r.FormValue("token_secret") != tokenSecret {
+			http.Error(w, "missing file or token", http.StatusBadRequest)
+			return
+		}
+		if host.rejected(w, file) {
+			return
+		}
+
+		n := host.recordUpload(file)
+		writeJSON(w, map[string]any{
+			"files": []map[string]string{{
+				"id":            fmt.Sprint(n),
+				"slug":          fmt.Sprintf("slug%d", n),
+				"name":          file.Filename,
+				"url":           fmt.Sprintf("https://imgbox.test/slug%d", n),
+				"original_url":  fmt.Sprintf("https://images.imgbox.test/slug%d_o.jpg", n),
+				"thumbnail_url": fmt.Sprintf("https://thumbs.imgbox.test/slug%d_t.jpg", n),
+			}},
+		})
+	})
+
+	host.Server = httptest.NewServer(mux)
+	t.Cleanup(host.Close)
+	return host
+}
+
+// newFakeHamster serves the auth_token page, a login that sets KEEP_LOGIN behind a redirect
+// and the JSON upload endpoint of hamster
+func newFakeHamster(t *testing.T) *fakeHost {
+	t.Helper()
+	host := &fakeHost{}
+	const authToken = "fake-auth-token-0123456789"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><script>PF.obj.config.auth_token = "%s";</script></head></html>`, authToken)
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("auth_token") != authToken || r.FormValue("login-subject") == "" {
+			http.Error(w, "invalid login", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "KEEP_LOGIN", Value: "fake-login", Path: "/"})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("POST /json", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("KEEP_LOGIN"); err != nil {
+			http.Error(w, "not logged in", http.StatusForbidden)
+			return
+		}
+		file := uploadedFile(r, "source")
+		if file == nil || r.FormValue("auth_token") != authToken {
+			http.Error(w, "missing file or auth_token", http.StatusBadRequest)
+			return
+		}
+		if host.rejected(w, file) {
+			return
+		}
+
+		n := host.recordUpload(file)
+		writeJSON(w, map[string]any{
+			"status_code": 200,
+			"image": map[string]any{
+				"url":        fmt.Sprintf("https://i.hamster.test/%d.jpg", n),
+				"url_viewer": fmt.Sprintf("https://hamster.test/image/%d", n),
+				"thumb":      map[string]string{"url": fmt.Sprintf("https://i.hamster.test/%d.th.jpg", n)},
+			},
+		})
+	})
+
+	host.Server = httptest.NewServer(mux)
+	t.Cleanup(host.Close)
+	return host
+}
+
+// fakeFFprobeOutput describes a ten minute 1080p video with one audio stream
+const fakeFFprobeOutput = `{
+  "streams": [
+    {"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "bit_rate": "5000000", "r_frame_rate": "24000/1001", "avg_frame_rate": "24000/1001"},
+    {"codec_type": "audio", "codec_name": "aac", "bit_rate": "192000", "sample_rate": "48000", "channels": 2, "channel_layout": "stereo"}
+  ],
+  "format": {"duration": "600.000000", "size": "104857600", "bit_rate": "5200000"}
+}`
+
+// installFakeTools puts stub ffprobe, ffmpeg and mtn executables first on PATH.
+// ffprobe prints fakeFFprobeOutput, or the file named by FAKE_FFPROBE_OUTPUT. ffmpeg writes a
+// placeholder image to its last argument, or prints the file named by FAKE_FFMPEG_METADATA when
+// scoring frames or detecting crops into "-"; with FAKE_FFMPEG_LOG set it appends its arguments to that file. mtn writes <video name>_s.jpg into the -O directory. The placeholders
+// name the video and timestamp, so every image has its own content.
+func installFakeTools(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub tools are shell scripts")
+	}
+
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"ffprobe": "#!/bin/sh\nif [ -n \"$FAKE_FFPROBE_OUTPUT\" ]; then cat \"$FAKE_FFPROBE_OUTPUT\"; exit; fi\ncat <<'EOF'\n" + fakeFFprobeOutput + "\nEOF\n",
+		"ffmpeg": `#!/bin/sh
+if [ -n "$FAKE_FFMPEG_LOG" ]; then echo "$@" >> "$FAKE_FFMPEG_LOG"; fi
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-ss) ss="$2" ;;
+	-i) in="$2" ;;
+	esac
+	out="$1"
+	shift
+done
+if [ "$out" = "-" ]; then
+	if [ -n "$FAKE_FFMPEG_METADATA" ]; then cat "$FAKE_FFMPEG_METADATA" >&2; fi
+	exit 0
+fi
+printf 'fake screenshot of %s at %s' "$(basename "$in")" "$ss" > "$out"
+`,
+		"mtn": `#!/bin/sh
+while [ $# -gt 1 ]; do
+	if [ "$1" = "-O" ]; then dir="$2"; shift; fi
+	shift
+done
+name=$(basename "$1")
+printf 'fake contact sheet of %s' "$name" > "$dir/${name%.*}_s.jpg"
+`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatalf("Failed to write %s stub: %v", name, err)
+		}
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// withFakeFFprobe makes the ffprobe stub print output instead of fakeFFprobeOutput
+func withFakeFFprobe(t *testing.T, output string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffprobe.json")
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		t.Fatalf("Failed to write ffprobe output: %v", err)
+	}
+	t.Setenv("FAKE_FFPROBE_OUTPUT", path)
+}
+
+// withFakeFFmpegMetadata makes the ffmpeg stub log metadata when it scores frames or detects crops
+func withFakeFFmpegMetadata(t *testing.T, metadata string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "metadata.log")
+	if err := os.WriteFile(path, []byte(metadata), 0644); err != nil {
+		t.Fatalf("Failed to write ffmpeg metadata: %v", err)
+	}
+	t.Setenv("FAKE_FFMPEG_METADATA", path)
+}
+
+// withFFmpegLog makes the ffmpeg stub record its arguments and returns the log path
+func withFFmpegLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg.log")
+	t.Setenv("FAKE_FFMPEG_LOG", path)
+	return path
+}
@@ -101,14 +101,6 @@ func TestHamsterService_UploadImage(t *testing.T) {
 		t.Error("ThumbnailURL is empty")
 	}
 
-	if result.BBThumb == "" {
-		t.Error("BBThumb is empty")
-	}
-
-	if result.BBBig == "" {
-		t.Error("BBBig is empty")
-	}
-
 	// Validate URLs contain hamster.is domain
 	if !strings.Contains(result.URL, "hamster.is") {
 		t.Errorf("URL doesn't contain hamster.is: %s", result.URL)
@@ -127,8 +119,6 @@ func TestHamsterService_UploadImage(t *testing.T) {
 	t.Logf("URL: %s", result.URL)
 	t.Logf("Viewer: %s", result.ViewerURL)
 	t.Logf("Thumbnail: %s", result.ThumbnailURL)
-	t.Logf("BBThumb: %s", result.BBThumb)
-	t.Logf("BBBig: %s", result.BBBig)
 }
 
 func TestHamsterService_UploadMultipleFormats(t *testing.T) {
@@ -0,0 +1,416 @@
+package img_uploaders
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"spoilr/backend"
+	"spoilr/backend/img_uploaders"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadersAgainstFakeHosts(t *testing.T) {
+	fastpic := newFakeFastpic(t, 0)
+	imgbox := newFakeImgbox(t)
+	hamster := newFakeHamster(t)
+
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		host     *fakeHost
+		uploader img_uploaders.ImageUploader
+		direct   string
+	}{
+		{"fastpic", fastpic, img_uploaders.NewFastpicService("", 350, img_uploaders.WithBaseURL(fastpic.URL)), "https://i.fastpic.test/big/1.png"},
+		{"imgbox", imgbox, img_uploaders.NewImgboxService(350, img_uploaders.WithBaseURL(imgbox.URL)), "https://images.imgbox.test/slug1_o.jpg"},
+		{"hamster", hamster, img_uploaders.NewHamsterService("user@example.com", "secret", img_uploaders.WithBaseURL(hamster.URL)), "https://i.hamster.test/1.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := tt.uploader.Init(ctx); err != nil {
+				t.Fatalf("Init failed: %v", err)
+			}
+			result, err := tt.uploader.Upload(ctx, imagePath, "image.png")
+			if err != nil {
+				t.Fatalf("Upload failed: %v", err)
+			}
+
+			if result.DirectURL != tt.direct {
+				t.Errorf("DirectURL = %q, want %q", result.DirectURL, tt.direct)
+			}
+			if result.ThumbURL == "" || result.ViewerURL == "" {
+				t.Errorf("Missing links: thumb %q, viewer %q", result.ThumbURL, result.ViewerURL)
+			}
+			if uploads := tt.host.Uploads(); len(uploads) != 1 || uploads[0] != "image.png" {
+				t.Errorf("Host received %v, want [image.png]", uploads)
+			}
+		})
+	}
+}
+
+// recordingTransport remembers the user agent of every request it forwards
+type recordingTransport struct {
+	mu         sync.Mutex
+	userAgents []string
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.userAgents = append(r.userAgents, req.UserAgent())
+	r.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestUploaderUsesInjectedClientAndUserAgent(t *testing.T) {
+	fastpic := newFakeFastpic(t, 0)
+
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	transport := &recordingTransport{}
+	uploader := img_uploaders.NewFastpicService("", 350,
+		img_uploaders.WithBaseURL(fastpic.URL),
+		img_uploaders.WithHTTPClient(&http.Client{Transport: transport}),
+		img_uploaders.WithUserAgent("spoilr-test/1.0"),
+	)
+
+	if _, err := uploader.Upload(context.Background(), imagePath, "image.png"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	if len(transport.userAgents) != 2 {
+		t.Fatalf("Client sent %d requests, want 2", len(transport.userAgents))
+	}
+	for _, userAgent := range transport.userAgents {
+		if userAgent != "spoilr-test/1.0" {
+			t.Errorf("User agent = %q, want spoilr-test/1.0", userAgent)
+		}
+	}
+}
+
+func TestFastpicSessionRenewedAfterExpiredUploadID(t *testing.T) {
+	fastpic := newFakeFastpic(t, 1)
+
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	session := img_uploaders.NewSession(img_uploaders.NewFastpicService("", 350, img_uploaders.WithBaseURL(fastpic.URL)))
+	ctx := context.Background()
+	if err := session.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	policy := img_uploaders.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	result, stats, err := session.Upload(ctx, policy, imagePath, "image.png")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if stats.Retries != 1 {
+		t.Errorf("Retries = %d, want 1", stats.Retries)
+	}
+	if result.DirectURL == "" {
+		t.Error("DirectURL is empty")
+	}
+}
+
+// e2eTemplate uses every host, so each movie uploads its contact sheet and screenshots to all of them
+const e2eTemplate = `[spoiler="%FILE_NAME% | %DURATION%"]
+%VIDEO_CODEC% %WIDTH%x%HEIGHT%
+%CONTACT_SHEET_FP%
+%SCREENSHOTS_IB%
+%SCREENSHOTS_HAM_BIG%
+[/spoiler]`
+
+// isolateUserDirs keeps config, session and caches out of the user's directories and
+// returns the temporary config directory
+func isolateUserDirs(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	configDir := filepath.Join(home, "config")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	return configDir
+}
+
+// e2eEnv is a SpoilerService wired to stub media tools and fake hosts, with two videos queued
+type e2eEnv struct {
+	service *backend.SpoilerService
+	events  *backend.MemoryEventSink
+	hosts   map[string]*fakeHost
+}
+
+func newE2EEnv(t *testing.T, template string, opts ...backend.Option) *e2eEnv {
+	t.Helper()
+	installFakeTools(t)
+	isolateUserDirs(t)
+
+	env := &e2eEnv{
+		events: backend.NewMemoryEventSink(),
+		hosts: map[string]*fakeHost{
+			"FP":  newFakeFastpic(t, 0),
+			"IB":  newFakeImgbox(t),
+			"HAM": newFakeHamster(t),
+		},
+	}
+
+	videoDir := t.TempDir()
+	for _, name := range []string{"Episode 01.mkv", "Episode 02.mkv"} {
+		if err := os.WriteFile(filepath.Join(videoDir, name), []byte("not really a video"), 0644); err != nil {
+			t.Fatalf("Failed to create video: %v", err)
+		}
+	}
+
+	baseURLs := make(map[string]string)
+	for code, host := range env.hosts {
+		baseURLs[code] = host.URL
+	}
+	opts = append([]backend.Option{
+		backend.WithEventSink(env.events),
+		backend.WithTemplate(template),
+		backend.WithHostBaseURLs(baseURLs),
+	}, opts...)
+	env.service = backend.NewSpoilerService(opts...)
+	t.Cleanup(env.service.ClearMovies)
+
+	settings := env.service.GetSettings()
+	settings.ScreenshotCount = 3
+	settings.HamsterEmail = "user@example.com"
+	settings.HamsterPassword = "secret"
+	env.service.UpdateSettings(settings)
+
+	if err := env.service.AddMovies([]string{videoDir}); err != nil {
+		t.Fatalf("AddMovies failed: %v", err)
+	}
+	if movies := env.service.GetState().Movies; len(movies) != 2 {
+		t.Fatalf("Got %d movies, want 2", len(movies))
+	}
+	return env
+}
+
+// updateSettings changes the settings of the service
+func (e *e2eEnv) updateSettings(update func(settings *backend.AppSettings)) {
+	settings := e.service.GetSettings()
+	update(&settings)
+	e.service.UpdateSettings(settings)
+}
+
+// process runs processing to the end and checks that every movie completed cleanly
+func (e *e2eEnv) process(t *testing.T) {
+	t.Helper()
+	if err := e.service.StartProcessing(); err != nil {
+		t.Fatalf("StartProcessing failed: %v", err)
+	}
+	e.wait(t)
+
+	for _, movie := range e.service.GetState().Movies {
+		if movie.ProcessingState != backend.StateCompleted || len(movie.Errors) > 0 {
+			t.Errorf("%s: state %s, error %q, errors %v", movie.FileName, movie.ProcessingState, movie.ProcessingError, movie.Errors)
+		}
+	}
+	if errs := e.events.Errors(); len(errs) > 0 {
+		t.Errorf("Unexpected error events: %v", errs)
+	}
+}
+
+// wait waits for a started processing run to finish
+func (e *e2eEnv) wait(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for e.service.GetState().Processing {
+		if time.Now().After(deadline) {
+			e.service.CancelProcessing()
+			t.Fatal("Processing did not finish in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkUploads checks that every host received the given number of uploads
+func (e *e2eEnv) checkUploads(t *testing.T, want int) {
+	t.Helper()
+	for code, host := range e.hosts {
+		if got := len(host.Uploads()); got != want {
+			t.Errorf("%s received %d uploads, want %d", code, got, want)
+		}
+	}
+}
+
+// TestGenerateEndToEnd runs a folder of videos through analysis, media generation and
+// uploads to all hosts without touching the network or real media tools
+func TestGenerateEndToEnd(t *testing.T) {
+	env := newE2EEnv(t, e2eTemplate)
+	env.process(t)
+
+	// A contact sheet and three screenshots for each of the two movies
+	env.checkUploads(t, 8)
+
+	result := env.service.GenerateResult()
+	for _, want := range []string{
+		`[spoiler="Episode 01.mkv | 10:00"]`,
+		`[spoiler="Episode 02.mkv | 10:00"]`,
+		"h264 1920x1080",
+		"https://i.fastpic.test/thumb/",
+		"https://thumbs.imgbox.test/",
+		"https://i.hamster.test/",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Result does not contain %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "%") {
+		t.Errorf("Result has unreplaced placeholders:\n%s", result)
+	}
+}
+
+func TestUploadCacheSkipsIdenticalImages(t *testing.T) {
+	env := newE2EEnv(t, e2eTemplate)
+	env.process(t)
+	first := env.service.GenerateResult()
+
+	// Regenerated media has the same content, so the links come from the cache
+	env.service.ResetMovieStatuses()
+	env.process(t)
+	env.checkUploads(t, 8)
+	if second := env.service.GenerateResult(); second != first {
+		t.Errorf("Result changed after reusing cached uploads:\n%s\nwant:\n%s", second, first)
+	}
+
+	if size := env.service.GetUploadCacheSize(); size != 24 {
+		t.Errorf("Cache has %d entries, want 24", size)
+	}
+	if removed, err := env.service.PruneUploadCache(30); err != nil || removed != 0 {
+		t.Errorf("PruneUploadCache(30) = %d, %v; want nothing removed", removed, err)
+	}
+	if removed, err := env.service.PruneUploadCache(0); err != nil || removed != 24 {
+		t.Errorf("PruneUploadCache(0) = %d, %v; want 24 removed", removed, err)
+	}
+
+	env.service.ResetMovieStatuses()
+	env.process(t)
+	env.checkUploads(t, 16)
+}
+
+func TestUploadCacheKeyedBySizeLimit(t *testing.T) {
+	env := newE2EEnv(t, e2eTemplate)
+	env.process(t)
+	env.checkUploads(t, 8)
+
+	// The stub screenshots are 43 bytes and the contact sheets less than 41, so only the
+	// screenshots need a re-encoded upload under the new imgbox limit
+	configService := backend.NewConfigService()
+	config := configService.GetConfig()
+	config.Hosts = map[string]backend.HostConfig{"IB": {MaxSizeMB: 41 / float64(1<<20)}}
+	if err := configService.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	env.service.ResetMovieStatuses()
+	env.process(t)
+
+	want := map[string]int{"FP": 8, "IB": 14, "HAM": 8}
+	for code, host := range env.hosts {
+		if got := len(host.Uploads()); got != want[code] {
+			t.Errorf("%s received %d uploads, want %d", code, got, want[code])
+		}
+	}
+	for _, file := range env.hosts["IB"].UploadedFiles()[8:] {
+		if file.Size > 41 {
+			t.Errorf("imgbox received %s of %d bytes, over the 41 byte limit", file.Filename, file.Size)
+		}
+	}
+}
+
+func TestRetryFailedUploadsOnlyMissingPieces(t *testing.T) {
+	ffmpegLog := withFFmpegLog(t)
+	env := newE2EEnv(t, e2eTemplate)
+
+	// imgbox refuses the second screenshot of the first episode as too large
+	const failing = "Episode 01_screenshot_2.jpg"
+	env.hosts["IB"].RejectUploads(func(file *multipart.FileHeader) int {
+		if file.Filename == failing {
+			return http.StatusRequestEntityTooLarge
+		}
+		return 0
+	})
+	if err := env.service.StartProcessing(); err != nil {
+		t.Fatalf("StartProcessing failed: %v", err)
+	}
+	env.wait(t)
+
+	before := env.service.GetState().Movies
+	if ib := before[0].Uploads["IB"].Screenshots; len(before[0].Errors) == 0 || len(ib) > 1 && ib[1].Direct != "" {
+		t.Fatalf("First episode has errors %v and imgbox screenshots %+v, want the second one missing",
+			before[0].Errors, before[0].Uploads["IB"].Screenshots)
+	}
+	want := map[string]int{"FP": 8, "IB": 7, "HAM": 8}
+	for code, host := range env.hosts {
+		if got := len(host.Uploads()); got != want[code] {
+			t.Fatalf("%s received %d uploads, want %d", code, got, want[code])
+		}
+	}
+	generated, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("Failed to read ffmpeg log: %v", err)
+	}
+
+	env.hosts["IB"].RejectUploads(nil)
+	if err := env.service.RetryFailed(); err != nil {
+		t.Fatalf("RetryFailed failed: %v", err)
+	}
+	env.wait(t)
+
+	want["IB"] = 8
+	for code, host := range env.hosts {
+		if got := len(host.Uploads()); got != want[code] {
+			t.Errorf("%s received %d uploads after the retry, want %d", code, got, want[code])
+		}
+	}
+	if uploads := env.hosts["IB"].Uploads(); uploads[len(uploads)-1] != failing {
+		t.Errorf("imgbox received %s on retry, want %s", uploads[len(uploads)-1], failing)
+	}
+	if regenerated, _ := os.ReadFile(ffmpegLog); string(regenerated) != string(generated) {
+		t.Errorf("Retry ran ffmpeg again:\n%s", strings.TrimPrefix(string(regenerated), string(generated)))
+	}
+
+	after := env.service.GetState().Movies
+	for i, movie := range after {
+		if movie.ProcessingState != backend.StateCompleted || len(movie.Errors) > 0 {
+			t.Errorf("%s: state %s, errors %v after the retry", movie.FileName, movie.ProcessingState, movie.Errors)
+		}
+		if !slices.Equal(movie.Media.Screenshots, before[i].Media.Screenshots) || movie.Media.ContactSheet != before[i].Media.ContactSheet {
+			t.Errorf("%s: media changed from %+v to %+v", movie.FileName, before[i].Media, movie.Media)
+		}
+		for code, set := range movie.Uploads {
+			old := before[i].Uploads[code]
+			if set.ContactSheet != old.ContactSheet {
+				t.Errorf("%s: %s contact sheet changed from %+v to %+v", movie.FileName, code, old.ContactSheet, set.ContactSheet)
+			}
+			for j, screenshot := range set.Screenshots {
+				if j < len(old.Screenshots) && old.Screenshots[j].Direct != "" && screenshot != old.Screenshots[j] {
+					t.Errorf("%s: %s screenshot %d changed from %+v to %+v", movie.FileName, code, j+1, old.Screenshots[j], screenshot)
+				}
+			}
+		}
+	}
+	if screenshot := after[0].Uploads["IB"].Screenshots[1]; screenshot.Direct == "" {
+		t.Errorf("Retried imgbox screenshot has no link: %+v", screenshot)
+	}
+}
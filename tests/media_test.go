@@ -0,0 +1,378 @@
+package img_uploaders
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"spoilr/backend"
+	"strings"
+	"testing"
+)
+
+// multiTrackFFprobeOutput has cover art, two audio tracks with the second one default, and
+// two subtitle tracks
+const multiTrackFFprobeOutput = `{
+  "streams": [
+    {"index": 0, "codec_type": "video", "codec_name": "hevc", "width": 1920, "height": 800, "r_frame_rate": "24000/1001", "disposition": {"default": 1}},
+    {"index": 1, "codec_type": "audio", "codec_name": "aac", "channels": 2, "channel_layout": "stereo", "sample_rate": "48000", "tags": {"language": "jpn", "BPS": "192000"}},
+    {"index": 2, "codec_type": "audio", "codec_name": "ac3", "channels": 6, "channel_layout": "5.1(side)", "sample_rate": "48000", "bit_rate": "448000", "disposition": {"default": 1}, "tags": {"language": "eng", "title": "Dub"}},
+    {"index": 3, "codec_type": "subtitle", "codec_name": "ass", "tags": {"LANGUAGE": "eng"}},
+    {"index": 4, "codec_type": "subtitle", "codec_name": "subrip", "disposition": {"forced": 1}, "tags": {"language": "rus", "title": "Signs"}},
+    {"index": 5, "codec_type": "video", "codec_name": "mjpeg", "width": 600, "height": 600, "disposition": {"attached_pic": 1}}
+  ],
+  "format": {"duration": "1440.000000", "size": "734003200", "bit_rate": "4077795"}
+}`
+
+func TestMultiTrackStreams(t *testing.T) {
+	withFakeFFprobe(t, multiTrackFFprobeOutput)
+
+	template := `%VIDEO_COUNT% %AUDIO_COUNT% %SUBTITLE_COUNT% | %AUDIO_CODEC% | %AUDIO_1_LANG% %AUDIO_1_BIT_RATE% | %AUDIO_2_LANG% %AUDIO_2_TITLE% %AUDIO_2_CHANNELS% | %SUBTITLE_1_LANG% %SUBTITLE_2_FLAGS% | %AUDIO_3_LANG%
+%AUDIO_SUMMARY%
+{{range .SubtitleStreams}}{{.Language}}:{{.Codec}};{{end}} {{range .AudioTracks}}{{.language}};{{end}}`
+	env := newE2EEnv(t, template)
+
+	if validation := env.service.ValidateTemplate(template); validation.Error != nil || len(validation.UnknownPlaceholders) > 0 {
+		t.Errorf("ValidateTemplate reported %+v, %+v", validation.Error, validation.UnknownPlaceholders)
+	}
+
+	movie := env.service.GetState().Movies[0]
+	preview, err := env.service.RenderTemplatePreview(template, "", movie.ID)
+	if err != nil || preview.Error != nil {
+		t.Fatalf("RenderTemplatePreview failed: %v %+v", err, preview.Error)
+	}
+	want := `1 2 2 | ac3 | jpn 192 kbps | eng Dub 5.1(side) | eng forced | −
+1. jpn / aac / stereo / 48.0 kHz / 192 kbps
+2. eng / ac3 / 5.1(side) / 48.0 kHz / 448 kbps / "Dub" / default
+eng:ass;rus:subrip; jpn;eng;`
+	if preview.Result != want {
+		t.Errorf("Result =\n%s\nwant:\n%s", preview.Result, want)
+	}
+}
+
+// hdrFFprobeOutput serves both the stream probe and the first frame probe: the Dolby Vision
+// record is stream side data, HDR10+ and the mastering display come with the frame
+const hdrFFprobeOutput = `{
+  "streams": [
+    {"index": 0, "codec_type": "video", "codec_name": "hevc", "width": 3840, "height": 2160, "pix_fmt": "yuv420p10le",
+     "color_range": "tv", "color_space": "bt2020nc", "color_transfer": "smpte2084", "color_primaries": "bt2020",
+     "side_data_list": [{"side_data_type": "DOVI configuration record", "dv_version_major": 1, "dv_profile": 8, "dv_level": 6, "dv_bl_signal_compatibility_id": 1}]},
+    {"index": 1, "codec_type": "audio", "codec_name": "eac3", "channels": 6, "sample_rate": "48000"}
+  ],
+  "frames": [
+    {"side_data_list": [
+      {"side_data_type": "Mastering display metadata", "red_x": "34000/50000", "red_y": "16000/50000", "green_x": "13250/50000", "green_y": "34500/50000",
+       "blue_x": "7500/50000", "blue_y": "3000/50000", "white_point_x": "15635/50000", "white_point_y": "16450/50000", "min_luminance": "50/10000", "max_luminance": "10000000/10000"},
+      {"side_data_type": "Content light level metadata", "max_content": 1000, "max_average": 400},
+      {"side_data_type": "HDR Dynamic Metadata SMPTE2094-40 (HDR10+)", "application_version": 1}
+    ]}
+  ],
+  "format": {"duration": "3600.000000", "size": "20000000000", "bit_rate": "44444444"}
+}`
+
+func TestHDRMetadata(t *testing.T) {
+	withFakeFFprobe(t, hdrFFprobeOutput)
+
+	template := "%VIDEO_HDR% | %VIDEO_BIT_DEPTH% | %VIDEO_MASTERING_DISPLAY% | %VIDEO_MAX_CLL% / %VIDEO_MAX_FALL% | {{.Info.Video.color_primaries}}"
+	env := newE2EEnv(t, template)
+
+	movie := env.service.GetState().Movies[0]
+	preview, err := env.service.RenderTemplatePreview(template, "", movie.ID)
+	if err != nil || preview.Error != nil {
+		t.Fatalf("RenderTemplatePreview failed: %v %+v", err, preview.Error)
+	}
+	want := "Dolby Vision Profile 8.1, HDR10+ | 10 bit | Display P3, min 0.0050 cd/m², max 1000 cd/m² | 1000 cd/m² / 400 cd/m² | bt2020"
+	if preview.Result != want {
+		t.Errorf("Result = %q, want %q", preview.Result, want)
+	}
+}
+
+// chaptersFFprobeOutput has three chapters, the last one untitled, and an empty chapter that
+// gets no screenshot
+const chaptersFFprobeOutput = `{
+  "streams": [
+    {"index": 0, "codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "r_frame_rate": "25/1"}
+  ],
+  "chapters": [
+    {"id": 0, "time_base": "1/1000000000", "start": 0, "start_time": "0.000000", "end": 90000000000, "end_time": "90.000000", "tags": {"title": "Opening"}},
+    {"id": 1, "time_base": "1/1000000000", "start": 90000000000, "start_time": "90.000000", "end": 510000000000, "end_time": "510.000000", "tags": {"title": "Part A"}},
+    {"id": 2, "time_base": "1/1000000000", "start": 510000000000, "start_time": "510.000000", "end": 510000000000, "end_time": "510.000000", "tags": {"title": "Marker"}},
+    {"id": 3, "time_base": "1/1000000000", "start": 510000000000, "start_time": "510.000000", "end": 600000000000, "end_time": "600.000000"}
+  ],
+  "format": {"duration": "600.000000", "size": "104857600", "bit_rate": "1398101"}
+}`
+
+func TestScreenshotPerChapter(t *testing.T) {
+	withFakeFFprobe(t, chaptersFFprobeOutput)
+
+	env := newE2EEnv(t, "%CHAPTER_COUNT% chapters\n%CHAPTERS%\n{{len .Uploads.IB.Screenshots}} screenshots")
+	env.updateSettings(func(settings *backend.AppSettings) {
+		settings.ScreenshotPerChapter = true
+	})
+	env.process(t)
+
+	// Three chapters with a length for each of the two movies
+	if uploads := env.hosts["IB"].Uploads(); len(uploads) != 6 {
+		t.Errorf("imgbox received %d uploads, want 6 screenshots", len(uploads))
+	}
+	movie := env.service.GetState().Movies[0]
+	if want := []float64{45, 300, 555}; !slices.Equal(movie.Media.ScreenshotTimestamps, want) {
+		t.Errorf("Screenshot timestamps = %v, want %v", movie.Media.ScreenshotTimestamps, want)
+	}
+
+	want := "4 chapters\n1. 0:00 Opening\n2. 1:30 Part A\n3. 8:30 Marker\n4. 8:30 Chapter 4\n3 screenshots\n"
+	if result := env.service.GenerateResult(); !strings.HasPrefix(result, want) {
+		t.Errorf("Result =\n%s\nwant it to start with:\n%s", result, want)
+	}
+}
+
+// frameMetadataOutput is what the metadata filter logs for three candidate frames: a black
+// frame, a sharp one with good contrast and one in the middle of a cut
+const frameMetadataOutput = `[Parsed_metadata_7 @ 0x1] frame:0    pts:0       pts_time:0
+[Parsed_metadata_7 @ 0x1] lavfi.scd.score=0.000
+[Parsed_metadata_7 @ 0x1] lavfi.blackframe.pblack=97
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YLOW=16
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YHIGH=20
+[Parsed_metadata_7 @ 0x1] lavfi.blur=0.5
+[Parsed_metadata_7 @ 0x1] frame:1    pts:2       pts_time:2
+[Parsed_metadata_7 @ 0x1] lavfi.scd.score=1.200
+[Parsed_metadata_7 @ 0x1] lavfi.blackframe.pblack=3
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YLOW=30
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YHIGH=200
+[Parsed_metadata_7 @ 0x1] lavfi.blur=2.1
+[Parsed_metadata_7 @ 0x1] frame:2    pts:4       pts_time:4
+[Parsed_metadata_7 @ 0x1] lavfi.scd.score=64.000
+[Parsed_metadata_7 @ 0x1] lavfi.blackframe.pblack=0
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YLOW=25
+[Parsed_metadata_7 @ 0x1] lavfi.signalstats.YHIGH=230
+[Parsed_metadata_7 @ 0x1] lavfi.blur=1.8
+`
+
+func TestSmartFrameSelection(t *testing.T) {
+	withFakeFFmpegMetadata(t, frameMetadataOutput)
+
+	env := newE2EEnv(t, "%SCREENSHOTS_IB%")
+	env.updateSettings(func(settings *backend.AppSettings) {
+		settings.SmartFrameSelection = true
+		settings.SmartFrameCandidates = 3
+		settings.SmartFrameWindowSeconds = 6
+	})
+	env.process(t)
+
+	// The 600 s movies get screenshots planned at 150, 300 and 450 s. Each window starts 3 s
+	// earlier and the second candidate, 2 s into it, wins.
+	movie := env.service.GetState().Movies[0]
+	if want := []float64{150, 300, 450}; !slices.Equal(movie.Media.ScreenshotTimestamps, want) {
+		t.Errorf("Planned timestamps = %v, want %v", movie.Media.ScreenshotTimestamps, want)
+	}
+	want := []float64{149, 299, 449}
+	if !slices.Equal(movie.Media.FrameTimestamps, want) || !movie.Media.SmartFrames {
+		t.Errorf("Frame timestamps = %v (smart %v), want %v", movie.Media.FrameTimestamps, movie.Media.SmartFrames, want)
+	}
+	for i, path := range movie.Media.Screenshots {
+		if data, _ := os.ReadFile(path); !strings.HasSuffix(string(data), fmt.Sprintf(" at %.2f", want[i])) {
+			t.Errorf("Screenshot %d = %q, want it taken at %.2f", i+1, data, want[i])
+		}
+	}
+}
+
+func TestMovieOverrides(t *testing.T) {
+	env := newE2EEnv(t, "{{len .Uploads.IB.Screenshots}} screenshots")
+	movies := env.service.GetState().Movies
+
+	for _, overrides := range []backend.MovieOverrides{
+		{Timestamps: "1:75"},
+		{Timestamps: "20:00"},
+		{ScreenshotCount: 21},
+		{StartPercent: 60, EndPercent: 40},
+	} {
+		if err := env.service.SetMovieOverrides(movies[0].ID, overrides); err == nil {
+			t.Errorf("SetMovieOverrides(%+v) succeeded, want an error", overrides)
+		}
+	}
+
+	if err := env.service.SetMovieOverrides(movies[0].ID, backend.MovieOverrides{Timestamps: "1:00, 150.5"}); err != nil {
+		t.Fatalf("SetMovieOverrides failed: %v", err)
+	}
+	overrides := backend.MovieOverrides{ScreenshotCount: 2, StartPercent: 10, EndPercent: 70}
+	if err := env.service.SetMovieOverrides(movies[1].ID, overrides); err != nil {
+		t.Fatalf("SetMovieOverrides failed: %v", err)
+	}
+	env.process(t)
+
+	// Explicit timestamps for the first movie, two screenshots spread over 60-420 s for the second
+	want := [][]float64{{60, 150.5}, {180, 300}}
+	for i, movie := range env.service.GetState().Movies {
+		if !slices.Equal(movie.Media.ScreenshotTimestamps, want[i]) {
+			t.Errorf("Movie %d screenshot timestamps = %v, want %v", i+1, movie.Media.ScreenshotTimestamps, want[i])
+		}
+	}
+	if uploads := env.hosts["IB"].Uploads(); len(uploads) != 4 {
+		t.Errorf("imgbox received %d uploads, want 4 screenshots", len(uploads))
+	}
+	if result := env.service.GenerateResult(); !strings.HasPrefix(result, "2 screenshots\n") {
+		t.Errorf("Result = %q, want 2 screenshots per movie", result)
+	}
+}
+
+func TestScreenshotFormatAndSizeLimit(t *testing.T) {
+	env := newE2EEnv(t, "%CONTACT_SHEET_IB%\n%SCREENSHOTS_IB%\n%SCREENSHOTS_FP%")
+
+	// The stub screenshots are 43 bytes; re-encoded from the image they lose the timestamp and fit
+	const limit = 41
+	configService := backend.NewConfigService()
+	config := configService.GetConfig()
+	config.Hosts = map[string]backend.HostConfig{"IB": {MaxSizeMB: limit / float64(1<<20)}}
+	if err := configService.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	env.updateSettings(func(settings *backend.AppSettings) {
+		settings.ScreenshotFormat = "png"
+	})
+	env.process(t)
+
+	movie := env.service.GetState().Movies[0]
+	for _, path := range movie.Media.Screenshots {
+		if filepath.Ext(path) != ".png" {
+			t.Errorf("Screenshot %s, want a .png file", path)
+		}
+	}
+	if fitted, _ := filepath.Glob(filepath.Join(filepath.Dir(movie.Media.Screenshots[0]), "*_ib.png")); len(fitted) > 0 {
+		t.Errorf("Re-encoded copies %v were not removed", fitted)
+	}
+	if n := len(movie.Uploads["FP"].Screenshots); n != 3 {
+		t.Errorf("fastpic has %d screenshot uploads, want 3", n)
+	}
+	for i, screenshot := range movie.Uploads["FP"].Screenshots {
+		if !strings.HasSuffix(screenshot.Direct, ".png") {
+			t.Errorf("fastpic screenshot %d direct link = %q, want a .png link", i+1, screenshot.Direct)
+		}
+	}
+
+	if uploads := env.hosts["IB"].Uploads(); len(uploads) != 8 {
+		t.Errorf("imgbox received %d uploads, want 2 contact sheets and 6 screenshots", len(uploads))
+	}
+	for code, host := range env.hosts {
+		for _, file := range host.UploadedFiles() {
+			want := "image/png"
+			if strings.Contains(file.Filename, "contact_sheet") {
+				want = "image/jpeg"
+			}
+			if contentType := file.Header.Get("Content-Type"); contentType != want {
+				t.Errorf("%s received %s as %s, want %s", code, file.Filename, contentType, want)
+			}
+			if code == "IB" && file.Size > limit {
+				t.Errorf("imgbox received %s of %d bytes, over the %d byte limit", file.Filename, file.Size, limit)
+			}
+			if code == "FP" && strings.Contains(file.Filename, "screenshot") && file.Size <= limit {
+				t.Errorf("fastpic received %s of %d bytes, want the original screenshot", file.Filename, file.Size)
+			}
+		}
+	}
+}
+
+func TestHDRTonemapping(t *testing.T) {
+	withFakeFFprobe(t, hdrFFprobeOutput)
+	ffmpegLog := withFFmpegLog(t)
+
+	env := newE2EEnv(t, "%SCREENSHOTS_IB%")
+	settings := env.service.GetSettings()
+	if !settings.TonemapHDR || settings.TonemapAlgorithm != "hable" {
+		t.Errorf("Tonemapping defaults to %v with %q, want it on with hable", settings.TonemapHDR, settings.TonemapAlgorithm)
+	}
+	settings.TonemapAlgorithm = "mobius"
+	settings.KeepUntonemapped = true
+	env.service.UpdateSettings(settings)
+	env.process(t)
+
+	// Every screenshot is taken twice, tonemapped and as is
+	data, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("Failed to read ffmpeg log: %v", err)
+	}
+	var tonemapped, untonemapped int
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.Contains(line, "zscale=tin=smpte2084:pin=bt2020:min=bt2020nc:t=linear") && strings.Contains(line, "tonemap=tonemap=mobius") {
+			tonemapped++
+		} else if !strings.Contains(line, " -vf ") {
+			untonemapped++
+		}
+	}
+	if tonemapped != 6 || untonemapped != 6 {
+		t.Errorf("ffmpeg took %d tonemapped and %d untonemapped screenshots, want 6 of each:\n%s", tonemapped, untonemapped, data)
+	}
+
+	movie := env.service.GetState().Movies[0]
+	if movie.Media.Tonemap != "mobius" || !movie.Media.Untonemapped {
+		t.Errorf("Media tonemap = %q, untonemapped %v, want mobius with copies", movie.Media.Tonemap, movie.Media.Untonemapped)
+	}
+	for _, path := range movie.Media.Screenshots {
+		copyPath := strings.TrimSuffix(path, ".jpg") + "_hdr.jpg"
+		if _, err := os.Stat(copyPath); err != nil {
+			t.Errorf("Untonemapped copy of %s is missing: %v", path, err)
+		}
+	}
+}
+
+// anamorphicFFprobeOutput is a PAL DVD stored at 720x576 and shown at 16:9
+const anamorphicFFprobeOutput = `{
+  "streams": [
+    {"index": 0, "codec_type": "video", "codec_name": "mpeg2video", "width": 720, "height": 576,
+     "sample_aspect_ratio": "64:45", "display_aspect_ratio": "16:9", "pix_fmt": "yuv420p"},
+    {"index": 1, "codec_type": "audio", "codec_name": "ac3", "channels": 2, "sample_rate": "48000"}
+  ],
+  "format": {"duration": "600.000000", "size": "4000000000", "bit_rate": "53333333"}
+}`
+
+// letterboxCropOutput is cropdetect finding bars above, below and beside the picture
+const letterboxCropOutput = `[Parsed_cropdetect_0 @ 0x1] x1:10 x2:709 y1:74 y2:501 w:698 h:426 x:12 y:76 pts:1 t:0.04 limit:0.094000 crop=698:426:12:76
+[Parsed_cropdetect_0 @ 0x1] x1:8 x2:711 y1:72 y2:503 w:704 h:432 x:8 y:72 pts:2 t:0.08 limit:0.094000 crop=704:432:8:72
+`
+
+func TestAnamorphicScreenshots(t *testing.T) {
+	withFakeFFprobe(t, anamorphicFFprobeOutput)
+	withFakeFFmpegMetadata(t, letterboxCropOutput)
+	ffmpegLog := withFFmpegLog(t)
+
+	template := "%DISPLAY_WIDTH%x%DISPLAY_HEIGHT% %DAR% | %WIDTH%x%HEIGHT%"
+	env := newE2EEnv(t, "%SCREENSHOTS_IB%")
+
+	movie := env.service.GetState().Movies[0]
+	preview, err := env.service.RenderTemplatePreview(template, "", movie.ID)
+	if err != nil || preview.Error != nil {
+		t.Fatalf("RenderTemplatePreview failed: %v %+v", err, preview.Error)
+	}
+	if want := "1024x576 16:9 | 720x576"; preview.Result != want {
+		t.Errorf("Result = %q, want %q", preview.Result, want)
+	}
+
+	settings := env.service.GetSettings()
+	if settings.AutoCrop {
+		t.Error("Auto-crop is on by default")
+	}
+	settings.AutoCrop = true
+	env.service.UpdateSettings(settings)
+	env.process(t)
+
+	// The bars are cropped off before the frame is stretched to 16:9
+	movie = env.service.GetState().Movies[0]
+	if movie.Media.Crop != "crop=704:432:8:72" {
+		t.Errorf("Media crop = %q, want crop=704:432:8:72", movie.Media.Crop)
+	}
+	data, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("Failed to read ffmpeg log: %v", err)
+	}
+	var detected, cropped int
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		switch {
+		case strings.Contains(line, "cropdetect="):
+			detected++
+		case strings.Contains(line, "-vf crop=704:432:8:72,scale=trunc(iw*1.422222/2)*2:ih,setsar=1 "):
+			cropped++
+		}
+	}
+	if detected != 6 || cropped != 6 {
+		t.Errorf("ffmpeg ran cropdetect %d times and took %d cropped screenshots, want 6 of each:\n%s", detected, cropped, data)
+	}
+}
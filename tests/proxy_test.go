@@ -0,0 +1,130 @@
+package img_uploaders
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"spoilr/backend"
+	"sync"
+	"testing"
+)
+
+// fakeProxy is an HTTP proxy that forwards plain requests and CONNECT tunnels and
+// remembers the target of each
+type fakeProxy struct {
+	*httptest.Server
+	mu      sync.Mutex
+	targets map[string]int
+}
+
+func newFakeProxy(t *testing.T) *fakeProxy {
+	t.Helper()
+	p := &fakeProxy{targets: make(map[string]int)}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeProxy) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.targets[r.Host]++
+	p.mu.Unlock()
+
+	if r.Method == http.MethodConnect {
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		go func() {
+			io.Copy(target, client)
+			target.Close()
+		}()
+		io.Copy(client, target)
+		client.Close()
+		return
+	}
+
+	r.RequestURI = ""
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// Requests returns how many requests went to the host
+func (p *fakeProxy) Requests(host string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targets[host]
+}
+
+func TestConnectionsThroughProxy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	proxy := newFakeProxy(t)
+	fastpic := newFakeFastpic(t, 0)
+	imgbox := newFakeImgbox(t)
+	hamster := newFakeHamster(t)
+
+	service := backend.NewSpoilerService(backend.WithHostBaseURLs(map[string]string{
+		"FP":  fastpic.URL,
+		"IB":  imgbox.URL,
+		"HAM": hamster.URL,
+	}))
+	settings := service.GetSettings()
+	settings.Proxy = proxy.URL
+	service.UpdateSettings(settings)
+
+	statuses := service.TestConnections()
+	if len(statuses) != 3 {
+		t.Fatalf("Got %d statuses, want 3", len(statuses))
+	}
+	for _, status := range statuses {
+		if !status.Reachable {
+			t.Errorf("%s not reachable: %s", status.Name, status.Error)
+		}
+	}
+
+	for name, host := range map[string]*fakeHost{"fastpic": fastpic, "imgbox": imgbox, "hamster": hamster} {
+		if proxy.Requests(host.Listener.Addr().String()) == 0 {
+			t.Errorf("%s was reached without the proxy", name)
+		}
+	}
+}
+
+func TestConnectionsReportUnreachableHost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	service := backend.NewSpoilerService(backend.WithHostBaseURLs(map[string]string{"FP": down.URL}))
+	for _, status := range service.TestConnections() {
+		if status.Code != "FP" {
+			continue
+		}
+		if status.Reachable || status.Error == "" {
+			t.Errorf("Closed host reported as %+v", status)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package img_uploaders
+
+import (
+	"context"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"spoilr/backend/img_uploaders"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadErrorClassification(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	tests := []struct {
+		status int
+		want   img_uploaders.ErrorKind
+	}{
+		{http.StatusUnauthorized, img_uploaders.ErrorFatal},
+		{http.StatusForbidden, img_uploaders.ErrorSessionExpired},
+		{419, img_uploaders.ErrorSessionExpired},
+		{http.StatusNotFound, img_uploaders.ErrorFatal},
+		{http.StatusRequestTimeout, img_uploaders.ErrorRetryable},
+		{http.StatusRequestEntityTooLarge, img_uploaders.ErrorFatal},
+		{http.StatusTooManyRequests, img_uploaders.ErrorRetryable},
+		{http.StatusInternalServerError, img_uploaders.ErrorRetryable},
+		{http.StatusBadGateway, img_uploaders.ErrorRetryable},
+		{http.StatusServiceUnavailable, img_uploaders.ErrorRetryable},
+	}
+
+	imgbox := newFakeImgbox(t)
+	uploader := img_uploaders.NewImgboxService(350, img_uploaders.WithBaseURL(imgbox.URL))
+	if err := uploader.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			imgbox.RejectUploads(func(*multipart.FileHeader) int { return tt.status })
+			_, err := uploader.Upload(context.Background(), imagePath, "image.png")
+			if err == nil {
+				t.Fatal("Upload succeeded")
+			}
+			if kind := img_uploaders.Classify(err); kind != tt.want {
+				t.Errorf("Classify(%v) = %s, want %s", err, kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectedLoginIsFatal(t *testing.T) {
+	hamster := newFakeHamster(t)
+	uploader := img_uploaders.NewHamsterService("", "secret", img_uploaders.WithBaseURL(hamster.URL))
+
+	err := uploader.Init(context.Background())
+	if err == nil {
+		t.Fatal("Init succeeded without a login")
+	}
+	if kind := img_uploaders.Classify(err); kind != img_uploaders.ErrorFatal {
+		t.Errorf("Classify(%v) = %s, want fatal", err, kind)
+	}
+}
+
+func TestNetworkErrorsAreRetryable(t *testing.T) {
+	failures := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"timeout", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}},
+		{"connection reset", func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			conn.(*net.TCPConn).SetLinger(0)
+			conn.Close()
+		}},
+	}
+
+	// imgbox goes through tls_client, fastpic through net/http
+	uploaders := []struct {
+		name string
+		new  func(opts ...img_uploaders.ServiceOption) img_uploaders.ImageUploader
+	}{
+		{"imgbox", func(opts ...img_uploaders.ServiceOption) img_uploaders.ImageUploader {
+			return img_uploaders.NewImgboxService(350, opts...)
+		}},
+		{"fastpic", func(opts ...img_uploaders.ServiceOption) img_uploaders.ImageUploader {
+			return img_uploaders.NewFastpicService("", 350, opts...)
+		}},
+	}
+
+	for _, uu := range uploaders {
+		for _, tt := range failures {
+			t.Run(uu.name+" "+tt.name, func(t *testing.T) {
+				server := httptest.NewServer(tt.handler)
+				t.Cleanup(server.Close)
+
+				uploader := uu.new(img_uploaders.WithBaseURL(server.URL), img_uploaders.WithTimeout(100*time.Millisecond))
+				err := uploader.Init(context.Background())
+				if err == nil {
+					t.Fatal("Init succeeded")
+				}
+				if kind := img_uploaders.Classify(err); kind != img_uploaders.ErrorRetryable {
+					t.Errorf("Classify(%v) = %s, want retryable", err, kind)
+				}
+			})
+		}
+	}
+}
+
+func TestCancelledUploadNotRetried(t *testing.T) {
+	imgbox := newFakeImgbox(t)
+	imgbox.RejectUploads(func(*multipart.FileHeader) int { return http.StatusServiceUnavailable })
+
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+	session := img_uploaders.NewSession(img_uploaders.NewImgboxService(350, img_uploaders.WithBaseURL(imgbox.URL)))
+	if err := session.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy := img_uploaders.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	if _, stats, err := session.Upload(ctx, policy, imagePath, "image.png"); err == nil || stats.Retries != 0 {
+		t.Errorf("Cancelled upload returned %v after %d retries, want an error without retries", err, stats.Retries)
+	}
+}
+
+func TestRetryBackoffLimits(t *testing.T) {
+	imgbox := newFakeImgbox(t)
+	imgbox.RejectUploads(func(*multipart.FileHeader) int { return http.StatusServiceUnavailable })
+
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	if err := createTestImage(imagePath); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+	session := img_uploaders.NewSession(img_uploaders.NewImgboxService(350, img_uploaders.WithBaseURL(imgbox.URL)))
+	if err := session.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	// The delays double from 20 ms and are capped at 30 ms, then lose up to half to jitter
+	policy := img_uploaders.RetryPolicy{MaxRetries: 3, BaseDelay: 20 * time.Millisecond, MaxDelay: 30 * time.Millisecond}
+	_, stats, err := session.Upload(context.Background(), policy, imagePath, "image.png")
+	if err == nil {
+		t.Fatal("Upload succeeded")
+	}
+	if stats.Retries != 3 || imgbox.Attempts() != 4 {
+		t.Errorf("Retries = %d with %d attempts, want 3 retries and 4 attempts", stats.Retries, imgbox.Attempts())
+	}
+	if minDelay, maxDelay := 40*time.Millisecond, 80*time.Millisecond; stats.Delay < minDelay || stats.Delay > maxDelay {
+		t.Errorf("Delay = %v, want between %v and %v", stats.Delay, minDelay, maxDelay)
+	}
+}
+
+// newRetryEnv is an end-to-end environment uploading screenshots to imgbox only, retrying
+// up to twice without waiting
+func newRetryEnv(t *testing.T) *e2eEnv {
+	t.Helper()
+	env := newE2EEnv(t, "%SCREENSHOTS_IB%")
+	settings := env.service.GetSettings()
+	settings.UploadRetries = 2
+	settings.UploadRetryBaseDelayMs = 1
+	settings.UploadRetryMaxDelayMs = 1
+	env.service.UpdateSettings(settings)
+	return env
+}
+
+func TestTransientUploadErrorRetried(t *testing.T) {
+	env := newRetryEnv(t)
+
+	// The first attempt of one screenshot fails
+	const failing = "Episode 01_screenshot_1.jpg"
+	failed := false
+	env.hosts["IB"].RejectUploads(func(file *multipart.FileHeader) int {
+		if file.Filename == failing && !failed {
+			failed = true
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	})
+	env.process(t)
+
+	if attempts, uploads := env.hosts["IB"].Attempts(), len(env.hosts["IB"].Uploads()); attempts != 7 || uploads != 6 {
+		t.Errorf("imgbox got %d attempts and %d uploads, want 7 and 6", attempts, uploads)
+	}
+	movies := env.service.GetState().Movies
+	if movies[0].UploadRetries != 1 || movies[0].UploadRetryDelayMs > 1 {
+		t.Errorf("First episode made %d retries in %d ms, want 1 within 1 ms", movies[0].UploadRetries, movies[0].UploadRetryDelayMs)
+	}
+	if movies[1].UploadRetries != 0 {
+		t.Errorf("Second episode made %d retries, want none", movies[1].UploadRetries)
+	}
+}
+
+func TestFatalUploadErrorNotRetried(t *testing.T) {
+	env := newRetryEnv(t)
+
+	const failing = "Episode 01_screenshot_1.jpg"
+	env.hosts["IB"].RejectUploads(func(file *multipart.FileHeader) int {
+		if file.Filename == failing {
+			return http.StatusUnauthorized
+		}
+		return 0
+	})
+	if err := env.service.StartProcessing(); err != nil {
+		t.Fatalf("StartProcessing failed: %v", err)
+	}
+	env.wait(t)
+
+	if attempts, uploads := env.hosts["IB"].Attempts(), len(env.hosts["IB"].Uploads()); attempts != 6 || uploads != 5 {
+		t.Errorf("imgbox got %d attempts and %d uploads, want 6 and 5", attempts, uploads)
+	}
+	movie := env.service.GetState().Movies[0]
+	if movie.UploadRetries != 0 || movie.UploadRetryDelayMs != 0 {
+		t.Errorf("First episode made %d retries in %d ms, want none", movie.UploadRetries, movie.UploadRetryDelayMs)
+	}
+	if len(movie.Errors) != 1 || !strings.Contains(movie.Errors[0], "401") {
+		t.Errorf("First episode errors = %v, want the 401 upload failure", movie.Errors)
+	}
+}
@@ -0,0 +1,98 @@
+package img_uploaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"spoilr/backend"
+	"testing"
+)
+
+// savedSession is a session file whose movies were interrupted while uploading, had
+// completed, and lost their source file
+const savedSession = `{
+  "version": 1,
+  "movies": [
+    {"id": "interrupted", "fileName": "Episode 01.mkv", "filePath": %q, "processingState": "uploading_screenshots",
+     "processingError": "stale", "uploads": {}},
+    {"id": "completed", "fileName": "Episode 02.mkv", "filePath": %q, "processingState": "completed",
+     "uploads": {"FP": {
+       "contactSheet": {"thumb": "https://i.fastpic.test/thumb/1.jpeg", "direct": "https://i.fastpic.test/big/1.jpg"},
+       "screenshots": [{"thumb": "https://i.fastpic.test/thumb/2.jpeg", "direct": "https://i.fastpic.test/big/2.jpg"}]
+     }}},
+    {"id": "missing", "fileName": "Episode 03.mkv", "filePath": %q, "processingState": "pending"}
+  ]
+}`
+
+func TestRestoreSession(t *testing.T) {
+	configDir := isolateUserDirs(t)
+
+	videoDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"Episode 01.mkv", "Episode 02.mkv", "Episode 03.mkv"} {
+		paths = append(paths, filepath.Join(videoDir, name))
+	}
+	for _, path := range paths[:2] {
+		if err := os.WriteFile(path, []byte("not really a video"), 0644); err != nil {
+			t.Fatalf("Failed to create video: %v", err)
+		}
+	}
+
+	sessionPath := filepath.Join(configDir, "spoilr", "spoilr.session")
+	if err := os.MkdirAll(filepath.Dir(sessionPath), 0700); err != nil {
+		t.Fatalf("Failed to create session directory: %v", err)
+	}
+	session := fmt.Sprintf(savedSession, paths[0], paths[1], paths[2])
+	if err := os.WriteFile(sessionPath, []byte(session), 0600); err != nil {
+		t.Fatalf("Failed to write session: %v", err)
+	}
+
+	service := backend.NewSpoilerService()
+	t.Cleanup(service.ClearMovies)
+
+	movies := service.GetState().Movies
+	if len(movies) != 3 {
+		t.Fatalf("Restored %d movies, want 3", len(movies))
+	}
+
+	tests := []struct {
+		state   backend.ProcessingState
+		err     string
+		missing bool
+	}{
+		{backend.StatePending, "", false},
+		{backend.StateCompleted, "", false},
+		{backend.StateError, "Source file not found", true},
+	}
+	for i, tt := range tests {
+		movie := movies[i]
+		if movie.ProcessingState != tt.state || movie.ProcessingError != tt.err || movie.FileMissing != tt.missing {
+			t.Errorf("%s: state %s, error %q, missing %v; want %s, %q, %v", movie.ID,
+				movie.ProcessingState, movie.ProcessingError, movie.FileMissing, tt.state, tt.err, tt.missing)
+		}
+	}
+
+	// Uploaded links survive a restore
+	fp := movies[1].Uploads["FP"]
+	if fp.ContactSheet.Thumb != "https://i.fastpic.test/thumb/1.jpeg" || len(fp.Screenshots) != 1 ||
+		fp.Screenshots[0].Direct != "https://i.fastpic.test/big/2.jpg" {
+		t.Errorf("fastpic uploads = %+v, want the saved links", fp)
+	}
+
+	// Any change saves the queue, which the next run restores as is
+	service.RemoveMovie("missing")
+	data, err := os.ReadFile(sessionPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved session: %v", err)
+	}
+	var saved backend.SessionData
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Version != 1 || len(saved.Movies) != 2 {
+		t.Fatalf("Saved session has version %d and %d movies (%v), want version 1 with 2 movies", saved.Version, len(saved.Movies), err)
+	}
+
+	restored := backend.NewSpoilerService().GetState().Movies
+	if len(restored) != 2 || restored[1].Uploads["FP"].ContactSheet.Thumb != fp.ContactSheet.Thumb {
+		t.Errorf("Second restore = %+v, want the two remaining movies unchanged", restored)
+	}
+}
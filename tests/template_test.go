@@ -0,0 +1,334 @@
+package img_uploaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"spoilr/backend"
+	"strings"
+	"testing"
+)
+
+// e2eGoTemplate mixes text/template actions with legacy placeholders
+const e2eGoTemplate = `[spoiler="{{.FileName}} | %DURATION%"]
+{{- if .AudioTracks}}
+Audio:{{range .AudioTracks}} {{.codec_name}} {{.channel_layout}}{{end}}
+{{- end}}
+{{- if .Info.Video.subtitle_codec}}
+Subtitles: never printed
+{{- end}}
+Size: {{size .Info.General.size}} / {{humanize .Info.General.bit_rate}}bps
+[{{pad 6 .VideoCodec}}]
+{{range .Uploads.IB.Screenshots}}{{.Direct}}
+{{end -}}
+{{join " " (list .Width .Height)}}
+[/spoiler]`
+
+func TestGoTemplateEndToEnd(t *testing.T) {
+	env := newE2EEnv(t, strings.Replace(e2eGoTemplate, `{{join " " (list .Width .Height)}}`, `{{.Width}}x{{.Height}}`, 1))
+	env.process(t)
+
+	// Only imgbox is used by the template
+	if uploads := env.hosts["IB"].Uploads(); len(uploads) != 6 {
+		t.Errorf("imgbox received %d uploads, want 6 screenshots", len(uploads))
+	}
+	if uploads := env.hosts["FP"].Uploads(); len(uploads) != 0 {
+		t.Errorf("fastpic received %d uploads, want none", len(uploads))
+	}
+
+	result := env.service.GenerateResult()
+	for _, want := range []string{
+		`[spoiler="Episode 01.mkv | 10:00"]`,
+		"\nAudio: aac stereo\n",
+		"Size: 100.0 MB / 5.2Mbps",
+		"[h264  ]",
+		"https://images.imgbox.test/slug",
+		"1920x1080",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Result does not contain %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Subtitles") || strings.Contains(result, "Template error") {
+		t.Errorf("Unexpected output:\n%s", result)
+	}
+}
+
+func TestTemplateErrorPosition(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	service := backend.NewSpoilerService()
+
+	tests := []struct {
+		name     string
+		template string
+		line     int
+		column   int
+	}{
+		{"unknown function", "File: %FILE_NAME%\nSize: %FILE_SIZE% {{nope .FileName}}", 2, 21},
+		{"unclosed action", "%FILE_NAME%\n\n  {{if .AudioCodec}", 3, 3},
+		{"missing end", "{{range .AudioTracks}}\n{{.codec_name}}", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.CheckTemplate(tt.template)
+			if err == nil {
+				t.Fatal("CheckTemplate accepted a broken template")
+			}
+			if err.Line != tt.line || err.Column != tt.column {
+				t.Errorf("Error at %d:%d, want %d:%d (%s)", err.Line, err.Column, tt.line, tt.column, err.Message)
+			}
+			if service.SetTemplate(tt.template) == nil {
+				t.Error("SetTemplate saved a broken template")
+			}
+		})
+	}
+
+	if err := service.CheckTemplate(e2eGoTemplate); err == nil || !strings.Contains(err.Message, `"list"`) {
+		t.Errorf("CheckTemplate = %v, want an undefined function error", err)
+	}
+	if err := service.CheckTemplate(e2eTemplate); err != nil {
+		t.Errorf("Legacy template rejected: %v", err)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	service := backend.NewSpoilerService()
+	settings := service.GetSettings()
+	settings.ScreenshotCount = 0
+	settings.HamsterEmail = ""
+	service.UpdateSettings(settings)
+
+	validation := service.ValidateTemplate(`[spoiler="%FILE_NAME%"]
+%SCREENSHOT_FP%
+%CONTACT_SHEET_HAM% %SCREENSHOTS_IB%
+[quote]{{range .Uploads.XX.Screenshots}}{{.Thumb}}{{end}}
+[/spoiler]
+[/b]`)
+
+	if validation.Error != nil {
+		t.Errorf("Unexpected error: %v", validation.Error)
+	}
+
+	var unknown []string
+	for _, issue := range validation.UnknownPlaceholders {
+		unknown = append(unknown, fmt.Sprintf("%s %d:%d %s", issue.Name, issue.Line, issue.Column, issue.Message))
+	}
+	wantUnknown := []string{
+		"%SCREENSHOT_FP% 2:1 unknown placeholder, did you mean %SCREENSHOTS_FP%?",
+		".Uploads.XX 4:25 unknown image host",
+	}
+	if !slices.Equal(unknown, wantUnknown) {
+		t.Errorf("Unknown placeholders = %q, want %q", unknown, wantUnknown)
+	}
+
+	empty := make(map[string]string)
+	for _, issue := range validation.EmptyPlaceholders {
+		empty[issue.Name] = issue.Message
+	}
+	if len(empty) != 2 || empty["%CONTACT_SHEET_HAM%"] != "Hamster email and password are not set" || empty["%SCREENSHOTS_IB%"] != "screenshot count is 0" {
+		t.Errorf("Empty placeholders = %v", empty)
+	}
+
+	var tags []string
+	for _, tag := range validation.UnbalancedTags {
+		tags = append(tags, fmt.Sprintf("%d:%d %s", tag.Line, tag.Column, tag.Message))
+	}
+	wantTags := []string{"4:1 [quote] closed by outer [/spoiler]", "6:1 [/b] without opening [b]"}
+	if !slices.Equal(tags, wantTags) {
+		t.Errorf("Unbalanced tags = %q, want %q", tags, wantTags)
+	}
+
+	// The typo still ends in _FP%, so fastpic uploads are triggered anyway
+	hosts := make(map[string]backend.TemplateHostUsage)
+	for _, host := range validation.Hosts {
+		hosts[host.Code] = host
+	}
+	for _, code := range []string{"FP", "IB", "HAM"} {
+		if host, ok := hosts[code]; !ok || !host.ContactSheet || !host.Screenshots {
+			t.Errorf("%s usage = %+v, want contact sheet and screenshots", code, host)
+		}
+	}
+
+	if clean := service.ValidateTemplate(e2eTemplate); len(clean.UnknownPlaceholders)+len(clean.UnbalancedTags) > 0 || clean.Error != nil {
+		t.Errorf("Issues in a valid template: %+v", clean)
+	}
+}
+
+func TestPlaceholderCatalog(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	service := backend.NewSpoilerService()
+
+	catalog := make(map[string]backend.PlaceholderInfo)
+	var names []string
+	for _, placeholder := range service.GetPlaceholderCatalog() {
+		if placeholder.Description == "" || placeholder.Category == "" || placeholder.Example == "" {
+			t.Errorf("Incomplete catalog entry %+v", placeholder)
+		}
+		catalog[placeholder.Name] = placeholder
+		names = append(names, placeholder.Name)
+	}
+
+	screenshots := catalog["%SCREENSHOTS_FP_BIG%"]
+	if screenshots.Host != "FP" || screenshots.Artifact != "screenshots" || !strings.Contains(screenshots.Example, "https://example.com/fp/screenshot-1.jpg") {
+		t.Errorf("%%SCREENSHOTS_FP_BIG%% = %+v", screenshots)
+	}
+	if codec := catalog["%Video@codec_name%"]; codec.Example != "h264" || codec.Host != "" {
+		t.Errorf("%%Video@codec_name%% = %+v", codec)
+	}
+	for _, name := range []string{"%FILE_NAME%", "%VIDEO_FPS%", "%AUDIO_CHANNELS%", "%CONTACT_SHEET_HAM_BIG%", "%General@size%"} {
+		if _, ok := catalog[name]; !ok {
+			t.Errorf("Catalog has no %s", name)
+		}
+	}
+
+	// Everything in the catalog is a placeholder the validator knows
+	if validation := service.ValidateTemplate(strings.Join(names, "\n")); len(validation.UnknownPlaceholders) > 0 {
+		t.Errorf("Catalog placeholders reported as unknown: %+v", validation.UnknownPlaceholders)
+	}
+}
+
+func TestRenderTemplatePreview(t *testing.T) {
+	env := newE2EEnv(t, e2eTemplate)
+	movie := env.service.GetState().Movies[0]
+
+	preview, err := env.service.RenderTemplatePreview(e2eTemplate, "", movie.ID)
+	if err != nil {
+		t.Fatalf("RenderTemplatePreview failed: %v", err)
+	}
+	if preview.Error != nil || preview.Sample {
+		t.Errorf("Preview error %v, sample %v", preview.Error, preview.Sample)
+	}
+	for _, want := range []string{
+		movie.FileName,
+		"https://example.com/fp/contact-sheet_thumb.jpg",
+		"https://example.com/ib/screenshot-3_thumb.jpg",
+		"[URL=https://example.com/ham/screenshot-1.html][IMG]https://example.com/ham/screenshot-1.jpg[/IMG][/URL]",
+	} {
+		if !strings.Contains(preview.Result, want) {
+			t.Errorf("Preview does not contain %q:\n%s", want, preview.Result)
+		}
+	}
+	if len(preview.Hosts) != 3 {
+		t.Errorf("Preview triggers %+v, want all three hosts", preview.Hosts)
+	}
+	env.checkUploads(t, 0)
+
+	sample, err := env.service.RenderTemplatePreview("{{.FileName}} {{len .Uploads.FP.Screenshots}}", "", "")
+	if err != nil || !sample.Sample || sample.Result != "Sample.Movie.2024.1080p.mkv 3" {
+		t.Errorf("Sample preview = %+v, %v", sample, err)
+	}
+	if broken, err := env.service.RenderTemplatePreview("{{if}}", "", ""); err != nil || broken.Error == nil {
+		t.Errorf("Broken template preview = %+v, %v", broken, err)
+	}
+	if _, err := env.service.RenderTemplatePreview(e2eTemplate, "", "no-such-movie"); err == nil {
+		t.Error("RenderTemplatePreview accepted an unknown movie")
+	}
+}
+
+func TestBatchHeaderAndFooter(t *testing.T) {
+	preset := backend.TemplatePreset{
+		Header:   "[b]{{.EpisodeCount}} episodes[/b] | %TOTAL_SIZE% | %TOTAL_DURATION% | %COMMON_VIDEO_CODEC% %COMMON_AUDIO_CODEC% | %FILE_NAME%",
+		Template: "%FILE_NAME% of %EPISODE_COUNT%",
+		Footer:   "{{range .Movies}}{{.FileName}};{{end}} %COMMON_RESOLUTION%",
+	}
+	env := newE2EEnv(t, "", backend.WithPreset(preset))
+	env.process(t)
+
+	// The stub videos are 18 bytes each
+	want := "[b]2 episodes[/b] | 36 B | 20:00 | h264 aac | −\n" +
+		"Episode 01.mkv of 2\n" +
+		"Episode 02.mkv of 2\n" +
+		"Episode 01.mkv;Episode 02.mkv; 1920x1080\n"
+	if result := env.service.GenerateResult(); result != want {
+		t.Errorf("GenerateResult() =\n%s\nwant:\n%s", result, want)
+	}
+
+	movie := env.service.GetState().Movies[1]
+	if result := env.service.GenerateResultForMovie(movie.ID); result != "Episode 02.mkv of 2" {
+		t.Errorf("GenerateResultForMovie() = %q, want the movie block only", result)
+	}
+
+	// A third episode without audio shares the video but not the audio codec, and a fourth
+	// with another codec and resolution leaves nothing in common
+	for _, tt := range []struct {
+		ffprobe, header, footer string
+	}{
+		{chaptersFFprobeOutput, "[b]3 episodes[/b] | 54 B | 30:00 | h264 mixed | −\n", "; 1920x1080\n"},
+		{multiTrackFFprobeOutput, "[b]4 episodes[/b] | 72 B | 54:00 | mixed mixed | −\n", "; mixed\n"},
+	} {
+		withFakeFFprobe(t, tt.ffprobe)
+		video := filepath.Join(t.TempDir(), fmt.Sprintf("Episode %02d.mkv", len(env.service.GetState().Movies)+1))
+		if err := os.WriteFile(video, []byte("not really a video"), 0644); err != nil {
+			t.Fatalf("Failed to create video: %v", err)
+		}
+		if err := env.service.AddMovies([]string{video}); err != nil {
+			t.Fatalf("AddMovies failed: %v", err)
+		}
+		env.process(t)
+
+		result := env.service.GenerateResult()
+		if !strings.HasPrefix(result, tt.header) || !strings.HasSuffix(result, tt.footer) {
+			t.Errorf("GenerateResult() =\n%s\nwant it to start with %q and end with %q", result, tt.header, tt.footer)
+		}
+	}
+}
+
+func TestMarkupFormats(t *testing.T) {
+	preset := backend.TemplatePreset{
+		Template: "%CONTACT_SHEET_FP%\n%SCREENSHOTS_IB_SPACED%\n%SCREENSHOTS_HAM_BIG%",
+		Markup:   backend.MarkupMarkdown,
+	}
+	env := newE2EEnv(t, "", backend.WithPreset(preset))
+	env.process(t)
+
+	result := env.service.GenerateResult()
+	for _, want := range []string{
+		"[![](https://i.fastpic.test/thumb/",
+		".jpg)](https://fastpic.test/view/",
+		"[![](https://thumbs.imgbox.test/slug",
+		"[![](https://i.hamster.test/",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Markdown result does not contain %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "[URL=") {
+		t.Errorf("Markdown result has BBCode:\n%s", result)
+	}
+
+	movie := env.service.GetState().Movies[0]
+	html, err := env.service.RenderTemplatePreview("{{.Uploads.HAM.ContactSheet.Big}}", backend.MarkupHTML, movie.ID)
+	if err != nil || !strings.HasPrefix(html.Result, `<a href="https://hamster.test/image/`) {
+		t.Errorf("HTML preview = %q, %v", html.Result, err)
+	}
+
+	jsonTemplate := `{"name": {{json .FileName}}, "sheet": %CONTACT_SHEET_FP_BIG%, "screenshots": %SCREENSHOTS_IB%}`
+	preview, err := env.service.RenderTemplatePreview(jsonTemplate, backend.MarkupJSON, movie.ID)
+	if err != nil {
+		t.Fatalf("RenderTemplatePreview failed: %v", err)
+	}
+	var decoded struct {
+		Name        string              `json:"name"`
+		Sheet       map[string]string   `json:"sheet"`
+		Screenshots []map[string]string `json:"screenshots"`
+	}
+	if err := json.Unmarshal([]byte(preview.Result), &decoded); err != nil {
+		t.Fatalf("JSON preview does not parse: %v\n%s", err, preview.Result)
+	}
+	if decoded.Name != movie.FileName || !strings.HasPrefix(decoded.Sheet["image"], "https://i.fastpic.test/big/") || len(decoded.Screenshots) != 3 {
+		t.Errorf("Unexpected JSON preview:\n%s", preview.Result)
+	}
+
+	if _, err := env.service.RenderTemplatePreview("", "rtf", ""); err == nil {
+		t.Error("RenderTemplatePreview accepted an unknown markup")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"spoilr/backend"
+	"sync/atomic"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// wailsEventSink forwards service events to the frontend. The service is created before
+// the application, so the app is attached once it exists.
+type wailsEventSink struct {
+	app atomic.Pointer[application.App]
+}
+
+func (w *wailsEventSink) State(state backend.AppState) {
+	if app := w.app.Load(); app != nil {
+		app.Event.Emit("state", state)
+	}
+}
+
+func (w *wailsEventSink) Error(message string) {
+	if app := w.app.Load(); app != nil {
+		app.Event.Emit("error", map[string]string{
+			"message": message,
+		})
+	}
+}
+
+func (w *wailsEventSink) Progress(progress backend.Progress) {
+	if app := w.app.Load(); app != nil {
+		app.Event.Emit("progress", progress)
+	}
+}
+
+// wailsDialogProvider shows native dialogs through the application
+type wailsDialogProvider struct {
+	events *wailsEventSink
+}
+
+func (w wailsDialogProvider) SelectDirectory(title string) (string, error) {
+	app := w.events.app.Load()
+	if app == nil {
+		return "", nil
+	}
+
+	return app.Dialog.OpenFile().
+		SetTitle(title).
+		CanChooseDirectories(true).
+		CanChooseFiles(false).
+		CanCreateDirectories(true).
+		PromptForSingleSelection()
+}